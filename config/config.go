@@ -1,7 +1,10 @@
 package config
 
 import (
+	"os"
 	"recyco/models"
+	"recyco/services"
+	"time"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
@@ -10,12 +13,49 @@ import (
 var DB *gorm.DB
 
 func ConnectDatabase() {
-	database, err := gorm.Open(mysql.Open("root:@tcp(127.0.0.1:3306)/recyco?charset=utf8mb4&parseTime=True&loc=Local"), &gorm.Config{})
+	database, err := OpenDatabase("root:@tcp(127.0.0.1:3306)/recyco?charset=utf8mb4&parseTime=True&loc=Local")
 
 	if err != nil {
 		panic("failed to connect database")
 	}
 
+	Migrate(database)
+	DB = database
+}
+
+// ConfigurePayments sets up the payment provider named by PAYMENT_PROVIDER.
+// Payments stay disabled while it is empty. "fake" is the development
+// provider, which settles charges without moving money and lets buyers
+// simulate its webhooks; its secret comes from PAYMENT_WEBHOOK_SECRET.
+func ConfigurePayments() {
+	switch os.Getenv("PAYMENT_PROVIDER") {
+	case "":
+		services.Payments = nil
+	case "fake":
+		secret := os.Getenv("PAYMENT_WEBHOOK_SECRET")
+		if secret == "" {
+			panic("failed to configure payments: PAYMENT_WEBHOOK_SECRET is not set")
+		}
+		services.Payments = services.NewFakePaymentProvider(secret)
+	default:
+		panic("failed to configure payments: unknown PAYMENT_PROVIDER " + os.Getenv("PAYMENT_PROVIDER"))
+	}
+}
+
+func OpenDatabase(dsn string) (*gorm.DB, error) {
+	return gorm.Open(mysql.Open(dsn), &gorm.Config{})
+}
+
+// Migrate brings the schema of database up to date, backfills data that
+// predates new columns and seeds the default marketplace rules.
+func Migrate(database *gorm.DB) {
+	hadItemStatus := database.Migrator().HasColumn(&models.MarketItems{}, "Status")
+	hadOrders := database.Migrator().HasTable(&models.Order{})
+	hadReservations := database.Migrator().HasColumn(&models.Order{}, "ReservedUntil")
+	hadHandoverPINs := database.Migrator().HasColumn(&models.Order{}, "HandoverPIN")
+	hadDeclaredWeights := database.Migrator().HasColumn(&models.Order{}, "DeclaredWeight")
+	hadReasonCodes := database.Migrator().HasColumn(&models.MarketItemTransactionActivities{}, "ReasonCode")
+
 	database.AutoMigrate(
 		&models.User{},
 		&models.Article{},
@@ -23,9 +63,295 @@ func ConnectDatabase() {
 		&models.MarketItems{},
 		&models.MarketItemTransactionActivities{},
 		&models.MarketItemPickupInformations{},
+		&models.Order{},
+		&models.Tariff{},
+		&models.Offer{},
+		&models.Notification{},
+		&models.Review{},
+		&models.DemandListing{},
+		&models.DemandResponse{},
+		&models.Auction{},
+		&models.Bid{},
+		&models.Contract{},
+		&models.PickupSlot{},
+		&models.RescheduleRequest{},
+		&models.LocationPing{},
+		&models.DeliveryProofPhoto{},
+		&models.Dispute{},
+		&models.DisputeMessage{},
+		&models.DisputeEvidencePhoto{},
+		&models.Invoice{},
+		&models.InvoiceSequence{},
+		&models.Payment{},
+		&models.PaymentRefund{},
+		&models.LedgerEntry{},
+		&models.Payout{},
+		&models.MarketScaleRule{},
+		&models.MarketRoleRule{},
 		&models.ForumPost{},
 		&models.ForumPostReply{},
 		&models.TreatmentLocation{},
+		&models.DataMigration{},
 	)
-	DB = database
+
+	if !hadItemStatus {
+		if err := backfillMarketItemStatus(database); err != nil {
+			panic("failed to backfill market item status")
+		}
+	}
+
+	if !hadOrders {
+		if err := backfillOrders(database); err != nil {
+			panic("failed to backfill orders")
+		}
+	}
+
+	if !hadDeclaredWeights {
+		if err := backfillDeclaredWeights(database); err != nil {
+			panic("failed to backfill declared order weights")
+		}
+	}
+
+	if !hadReservations {
+		if err := backfillReservations(database); err != nil {
+			panic("failed to backfill order reservations")
+		}
+	}
+
+	if !hadReasonCodes {
+		if err := backfillReasonCodes(database); err != nil {
+			panic("failed to backfill cancellation reason codes")
+		}
+	}
+
+	if !hadHandoverPINs {
+		if err := backfillHandoverPINs(database); err != nil {
+			panic("failed to backfill handover PINs")
+		}
+	}
+
+	if err := services.SeedMarketRules(database); err != nil {
+		panic("failed to seed marketplace rules")
+	}
+
+	for _, migration := range dataMigrations {
+		if err := applyDataMigration(database, migration.name, migration.apply); err != nil {
+			panic("failed to apply data migration " + migration.name)
+		}
+	}
+
+	if err := allowPendingCharges(database); err != nil {
+		panic("failed to allow payments without a charge")
+	}
+}
+
+// backfillMarketItemStatus derives the listing status of existing items from
+// their latest transaction activity. Before the status column existed, ordered
+// and finished items were hidden with a soft delete, so those are restored and
+// only items deleted by their seller stay soft-deleted (as WITHDRAWN).
+func backfillMarketItemStatus(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var items []models.MarketItems
+		if err := tx.Unscoped().Find(&items).Error; err != nil {
+			return err
+		}
+
+		for _, item := range items {
+			status := models.MarketItemStatusReady
+			var latest models.MarketItemTransactionActivities
+			if err := tx.Unscoped().Where("item_id = ?", item.ID).Order("created_at desc").First(&latest).Error; err == nil {
+				status = models.MarketItemStatusForTransaction(latest.Status)
+			}
+
+			updates := map[string]interface{}{"status": status}
+			if item.DeletedAt.Valid {
+				if status == models.MarketItemStatusReady {
+					updates["status"] = models.MarketItemStatusWithdrawn
+				} else {
+					updates["deleted_at"] = nil
+				}
+			}
+
+			if err := tx.Unscoped().Model(&models.MarketItems{}).Where("id = ?", item.ID).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// backfillOrders creates an order for every pickup information recorded before
+// orders existed and links its transaction activities to it. The buyer of a
+// cancelled legacy order was never stored, so it is left as the nil UUID.
+func backfillOrders(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var pickups []models.MarketItemPickupInformations
+		if err := tx.Unscoped().Find(&pickups).Error; err != nil {
+			return err
+		}
+
+		for _, pickup := range pickups {
+			var item models.MarketItems
+			if err := tx.Unscoped().Where("id = ?", pickup.ItemID).First(&item).Error; err != nil {
+				continue
+			}
+
+			var latest models.MarketItemTransactionActivities
+			if err := tx.Unscoped().Where("transaction_by_id = ?", pickup.ID).Order("created_at desc").First(&latest).Error; err != nil {
+				continue
+			}
+
+			order := models.Order{
+				ItemID:              item.ID,
+				SellerID:            item.PostedBy,
+				PickupInformationID: pickup.ID,
+				ItemPrice:           item.Price,
+				Currency:            item.Currency,
+				Status:              latest.Status,
+			}
+			if latest.Status != "CANCELLED" && item.OrderedBy != nil {
+				order.BuyerID = *item.OrderedBy
+			}
+
+			if err := tx.Create(&order).Error; err != nil {
+				return err
+			}
+
+			// Keep the original order date rather than the migration time.
+			if err := tx.Model(&models.Order{}).Where("id = ?", order.ID).UpdateColumn("created_at", pickup.CreatedAt).Error; err != nil {
+				return err
+			}
+
+			if err := tx.Unscoped().Model(&models.MarketItemTransactionActivities{}).
+				Where("transaction_by_id = ?", pickup.ID).
+				UpdateColumn("order_id", order.ID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// backfillDeclaredWeights records the weight and prices existing orders were
+// placed at, taken from their item and pickup information, so orders weighed
+// later can still be compared with what was declared. Contract orders were
+// always agreed per kg.
+func backfillDeclaredWeights(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var orders []models.Order
+		if err := tx.Unscoped().
+			Preload("MarketItem", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+			Preload("PickupInformation", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+			Find(&orders).Error; err != nil {
+			return err
+		}
+
+		for _, order := range orders {
+			if err := tx.Unscoped().Model(&models.Order{}).Where("id = ?", order.ID).
+				UpdateColumns(map[string]interface{}{
+					"PricedPerKg":           order.MarketItem.PricePerKg > 0 || order.ContractID != nil,
+					"DeclaredWeight":        order.MarketItem.Weight,
+					"DeclaredItemPrice":     order.ItemPrice,
+					"DeclaredServicePrice":  order.PickupInformation.ServicePrice,
+					"DeclaredDeliveryPrice": order.PickupInformation.DeliveryPrice,
+				}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// backfillReservations gives orders that were still waiting for the seller
+// when reservation deadlines were introduced a full window from now, so they
+// are not cancelled the moment the expiry job first runs.
+func backfillReservations(db *gorm.DB) error {
+	return db.Model(&models.Order{}).
+		Where("status = ? AND reserved_until IS NULL", services.TransactionStatusOnProcess).
+		UpdateColumn("reserved_until", time.Now().Add(services.ReservationWindow)).Error
+}
+
+// backfillReasonCodes gives cancellations recorded before reason codes
+// existed a code: RESERVATION_EXPIRED for those of the expiry job and OTHER,
+// with their free-text reason kept, for the rest.
+func backfillReasonCodes(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.MarketItemTransactionActivities{}).
+			Where("status = ? AND reason = ?", services.TransactionStatusCancelled, services.ReservationExpiredReason).
+			UpdateColumn("reason_code", services.CancelReasonReservationExpired).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&models.MarketItemTransactionActivities{}).
+			Where("status = ? AND (reason_code IS NULL OR reason_code = '')", services.TransactionStatusCancelled).
+			UpdateColumn("reason_code", services.CancelReasonOther).Error
+	})
+}
+
+// backfillHandoverPINs gives orders that are still open when handover PINs
+// were introduced a PIN, since they can no longer be completed without one.
+func backfillHandoverPINs(db *gorm.DB) error {
+	var orders []models.Order
+	if err := db.Where("status IN ? AND (handover_pin IS NULL OR handover_pin = '')",
+		[]string{services.TransactionStatusOnProcess, services.TransactionStatusOnDeliver}).
+		Find(&orders).Error; err != nil {
+		return err
+	}
+
+	for i := range orders {
+		if err := services.ResetHandoverPIN(db, &orders[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dataMigrations are one-off data fixes that Migrate applies in order after
+// seeding, each once per database. They are recorded by name, so a name must
+// never change or be reused.
+var dataMigrations = []struct {
+	name  string
+	apply func(db *gorm.DB) error
+}{
+	{"small-scale-buying", backfillSmallScaleBuying},
+}
+
+// applyDataMigration runs apply within a transaction unless name is already
+// recorded as applied, and records it. Claiming the name first means
+// concurrent starts apply it only once.
+func applyDataMigration(db *gorm.DB, name string, apply func(db *gorm.DB) error) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(&models.DataMigration{Name: name, AppliedAt: time.Now()}).Error
+		if services.IsDuplicateKey(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return apply(tx)
+	})
+}
+
+// backfillSmallScaleBuying lets C_SMALL buy SMALL items in rules seeded before
+// small-scale orders existed, when the default still forbade it. Admins can
+// revoke it again afterwards, so it is applied once as a data migration.
+func backfillSmallScaleBuying(db *gorm.DB) error {
+	return db.Model(&models.MarketRoleRule{}).
+		Where("role = ? AND item_scale = ?", "C_SMALL", "SMALL").
+		UpdateColumn("can_buy", true).Error
+}
+
+// allowPendingCharges lets charge_id be NULL where it was created NOT NULL.
+// Payments are now stored before the provider is asked for their charge, and
+// AutoMigrate never drops a NOT NULL constraint by itself.
+func allowPendingCharges(db *gorm.DB) error {
+	columns, err := db.Migrator().ColumnTypes(&models.Payment{})
+	if err != nil {
+		return err
+	}
+	for _, column := range columns {
+		if nullable, ok := column.Nullable(); ok && !nullable && column.Name() == "charge_id" {
+			return db.Migrator().AlterColumn(&models.Payment{}, "ChargeID")
+		}
+	}
+	return nil
 }
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"recyco/models"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	testDatabaseOnce sync.Once
+	testDatabase     *gorm.DB
+	testDatabaseErr  error
+)
+
+// openTestDatabase opens and migrates the MySQL database named by
+// RECYCO_TEST_DSN, and skips the test when no database is configured.
+func openTestDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv("RECYCO_TEST_DSN")
+	if dsn == "" {
+		t.Skip("RECYCO_TEST_DSN is not set")
+	}
+
+	testDatabaseOnce.Do(func() {
+		testDatabase, testDatabaseErr = OpenDatabase(dsn)
+		if testDatabaseErr == nil {
+			Migrate(testDatabase)
+		}
+	})
+	if testDatabaseErr != nil {
+		t.Fatalf("connect test database: %v", testDatabaseErr)
+	}
+	return testDatabase
+}
+
+func TestDataMigrationIsAppliedOnce(t *testing.T) {
+	db := openTestDatabase(t)
+
+	name := "test-" + uuid.NewString()
+	t.Cleanup(func() { db.Delete(&models.DataMigration{}, "name = ?", name) })
+
+	// A migration that fails is not recorded and runs again next time.
+	failed := errors.New("failed")
+	if err := applyDataMigration(db, name, func(*gorm.DB) error { return failed }); !errors.Is(err, failed) {
+		t.Fatalf("apply failing migration: %v, want %v", err, failed)
+	}
+
+	applied := 0
+	for i := 0; i < 2; i++ {
+		if err := applyDataMigration(db, name, func(*gorm.DB) error { applied++; return nil }); err != nil {
+			t.Fatalf("apply migration: %v", err)
+		}
+	}
+	if applied != 1 {
+		t.Fatalf("migration applied %d times, want once", applied)
+	}
+}
+
+func TestBackfillSmallScaleBuying(t *testing.T) {
+	db := openTestDatabase(t)
+
+	var rule models.MarketRoleRule
+	err := db.Where("role = ? AND item_scale = ?", "C_SMALL", "SMALL").First(&rule).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		rule = models.MarketRoleRule{Role: "C_SMALL", ItemScale: "SMALL"}
+		err = db.Create(&rule).Error
+		t.Cleanup(func() { db.Unscoped().Delete(&models.MarketRoleRule{}, "id = ?", rule.ID) })
+	} else {
+		id, canBuy := rule.ID, rule.CanBuy
+		t.Cleanup(func() { db.Model(&models.MarketRoleRule{}).Where("id = ?", id).UpdateColumn("can_buy", canBuy) })
+	}
+	if err != nil {
+		t.Fatalf("find C_SMALL SMALL rule: %v", err)
+	}
+
+	// Rules seeded before small-scale orders kept C_SMALL from buying.
+	db.Model(&models.MarketRoleRule{}).Where("id = ?", rule.ID).UpdateColumn("can_buy", false)
+	if err := backfillSmallScaleBuying(db); err != nil {
+		t.Fatalf("backfill: %v", err)
+	}
+
+	db.Where("id = ?", rule.ID).First(&rule)
+	if !rule.CanBuy {
+		t.Fatal("C_SMALL still cannot buy SMALL items after the backfill")
+	}
+}
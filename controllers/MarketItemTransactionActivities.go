@@ -1,29 +1,60 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"recyco/config"
 	"recyco/models"
+	"recyco/services"
 	"recyco/utils"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"gorm.io/gorm"
 )
 
 type MarketItemPickupInput struct {
-	ItemID                    string `form:"item_id" binding:"required"`
-	RecipientName             string `form:"recipient_name" binding:"required"`
-	RecipientPhone            string `form:"recipient_phone" binding:"required"`
-	Description               string `form:"description"`
-	PickupLocationAddress     string `form:"pickup_location_address" binding:"required"`
-	PickupLocationDescription string `form:"pickup_location_description"`
-	Status                    string `form:"status"`
+	ItemID                    string   `form:"item_id" binding:"required"`
+	RecipientName             string   `form:"recipient_name" binding:"required"`
+	RecipientPhone            string   `form:"recipient_phone" binding:"required"`
+	Description               string   `form:"description"`
+	PickupLocationAddress     string   `form:"pickup_location_address" binding:"required"`
+	PickupLocationDescription string   `form:"pickup_location_description"`
+	PickupLat                 *float64 `form:"pickup_lat"`
+	PickupLon                 *float64 `form:"pickup_lon"`
+	SlotID                    string   `form:"slot_id"`
+	Status                    string   `form:"status"`
 }
 
-type MarketItemTransactionStatusUpdateInput struct {
-	Status string `form:"status" binding:"required"`
+type MarketItemQuoteInput struct {
+	ItemID    string   `form:"item_id" binding:"required"`
+	PickupLat *float64 `form:"pickup_lat"`
+	PickupLon *float64 `form:"pickup_lon"`
+}
+
+// respondTransitionError maps errors from services.OpenOrder and
+// services.RecordTransactionStatus to API responses.
+func respondTransitionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidTransactionStatus):
+		utils.RespondFailed(c, http.StatusBadRequest, "Invalid status value", nil)
+	case errors.Is(err, services.ErrTransitionReason):
+		utils.RespondFailed(c, http.StatusBadRequest, "A reason is required for this status", nil)
+	case errors.Is(err, services.ErrTransitionForbidden):
+		utils.RespondFailed(c, http.StatusForbidden, "You are not allowed to set this status", nil)
+	case errors.Is(err, services.ErrIllegalTransition):
+		utils.RespondFailed(c, http.StatusConflict, "Status transition is not allowed", nil)
+	case errors.Is(err, services.ErrTransactionConflict):
+		utils.RespondFailed(c, http.StatusConflict, "Market item has already been ordered or changed", nil)
+	case errors.Is(err, services.ErrHandoverRequired):
+		utils.RespondFailed(c, http.StatusBadRequest, "The buyer's handover PIN is required to complete the order", nil)
+	case errors.Is(err, services.ErrCancellationReason):
+		utils.RespondFailed(c, http.StatusBadRequest, "A valid reason_code is required to cancel an order", nil)
+	case errors.Is(err, services.ErrSlotUnavailable):
+		utils.RespondFailed(c, http.StatusConflict, "Pickup slot is not available", nil)
+	default:
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to create transaction activity", nil)
+	}
 }
 
 func CreateMarketItemPickupInformation(c *gin.Context) {
@@ -41,25 +72,17 @@ func CreateMarketItemPickupInformation(c *gin.Context) {
 	}
 
 	userRole, exists := c.Get("userRole")
-	if !exists || userRole != "C_LARGE" {
-		utils.RespondFailed(c, http.StatusForbidden, "You do not have permission to create pickup information", nil)
+	if !exists {
+		utils.RespondFailed(c, http.StatusInternalServerError, "User role not found in context", nil)
 		return
 	}
 
 	if input.Status == "" {
-		input.Status = "ON_PROCESS"
+		input.Status = services.TransactionStatusOnProcess
 	}
 
-	validStatuses := []string{"ON_PROCESS", "ON_DELIVER", "FINISHED", "CANCELLED"}
-	isValidStatus := false
-	for _, status := range validStatuses {
-		if input.Status == status {
-			isValidStatus = true
-			break
-		}
-	}
-	if !isValidStatus {
-		utils.RespondFailed(c, http.StatusBadRequest, "Invalid status value", nil)
+	if err := services.ValidateTransactionTransition("", input.Status, services.PartyBuyer, ""); err != nil {
+		respondTransitionError(c, err)
 		return
 	}
 
@@ -75,13 +98,37 @@ func CreateMarketItemPickupInformation(c *gin.Context) {
 		return
 	}
 
-	var servicePrice int
-	if marketItem.Weight <= 50 {
-		servicePrice = 1000
-	} else {
-		servicePrice = (int(marketItem.Weight/50) + 1) * 1000
+	rules, err := services.LoadMarketRules(config.DB)
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to load marketplace rules", nil)
+		return
+	}
+
+	if !rules.CanBuy(userRole.(string), marketItem.ItemScale) {
+		utils.RespondFailed(c, http.StatusForbidden, "You do not have permission to create pickup information", nil)
+		return
+	}
+
+	if marketItem.ItemScale == "SMALL" {
+		utils.RespondFailed(c, http.StatusBadRequest, "SMALL scale items are ordered through /market_transactions/small", nil)
+		return
+	}
+
+	if marketItem.Status != models.MarketItemStatusReady {
+		utils.RespondFailed(c, http.StatusConflict, "Market item is not available for order", nil)
+		return
+	}
+
+	if marketItem.ListingType == models.ListingTypeAuction {
+		utils.RespondFailed(c, http.StatusConflict, "Market item is being auctioned", nil)
+		return
+	}
+
+	slotID, err := parseOptionalSlotID(input.SlotID)
+	if err != nil {
+		utils.RespondFailed(c, http.StatusBadRequest, "Invalid slot_id", nil)
+		return
 	}
-	deliveryPrice := marketItem.Weight * 1500
 
 	tx := config.DB.Begin()
 	if tx.Error != nil {
@@ -90,51 +137,25 @@ func CreateMarketItemPickupInformation(c *gin.Context) {
 	}
 
 	pickupInformation := models.MarketItemPickupInformations{
-		ID:                        uuid.New(),
-		ItemID:                    itemID,
 		RecipientName:             input.RecipientName,
 		RecipientPhone:            input.RecipientPhone,
 		Description:               input.Description,
 		PickupLocationAddress:     input.PickupLocationAddress,
 		PickupLocationDescription: input.PickupLocationDescription,
-		ServicePrice:              float64(servicePrice),
-		DeliveryPrice:             deliveryPrice,
-		CreatedAt:                 time.Now(),
-		UpdatedAt:                 time.Now(),
-	}
-
-	if err := tx.Create(&pickupInformation).Error; err != nil {
-		tx.Rollback()
-		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to create pickup information", nil)
-		return
+		PickupLat:                 input.PickupLat,
+		PickupLon:                 input.PickupLon,
 	}
 
-	transactionActivity := models.MarketItemTransactionActivities{
-		ID:              uuid.New(),
-		ItemID:          itemID,
-		Status:          input.Status,
-		TransactionByID: pickupInformation.ID,
-		CreatedAt:       time.Now(),
-		UpdatedAt:       time.Now(),
+	order, transactionActivity, err := services.PlaceOrder(tx, marketItem, userID.(uuid.UUID), &pickupInformation, marketItem.Price, models.FulfillmentDelivery)
+	if err == nil && slotID != uuid.Nil {
+		err = services.BookOrderSlot(tx, &order, slotID)
 	}
-
-	if err := tx.Create(&transactionActivity).Error; err != nil {
+	if err != nil {
 		tx.Rollback()
-		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to create transaction activity", nil)
+		respondTransitionError(c, err)
 		return
 	}
 
-	if input.Status == "ON_PROCESS" {
-		if err := tx.Model(&marketItem).Updates(map[string]interface{}{
-			"DeletedAt": time.Now(),
-			"OrderedBy": userID,
-		}).Error; err != nil {
-			tx.Rollback()
-			utils.RespondFailed(c, http.StatusInternalServerError, "Failed to update market item", nil)
-			return
-		}
-	}
-
 	if err := tx.Commit().Error; err != nil {
 		tx.Rollback()
 		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to commit transaction", nil)
@@ -142,6 +163,7 @@ func CreateMarketItemPickupInformation(c *gin.Context) {
 	}
 
 	utils.RespondSuccess(c, "Pickup information and transaction activity created successfully", gin.H{
+		"order_id": order.ID,
 		"pickup_information": gin.H{
 			"id":                          pickupInformation.ID,
 			"item_id":                     pickupInformation.ItemID,
@@ -152,11 +174,14 @@ func CreateMarketItemPickupInformation(c *gin.Context) {
 			"pickup_location_description": pickupInformation.PickupLocationDescription,
 			"service_price":               pickupInformation.ServicePrice,
 			"delivery_price":              pickupInformation.DeliveryPrice,
+			"distance_km":                 pickupInformation.DistanceKm,
+			"tariff_id":                   pickupInformation.TariffID,
 			"created_at":                  pickupInformation.CreatedAt,
 			"updated_at":                  pickupInformation.UpdatedAt,
 		},
 		"transaction_activity": gin.H{
 			"id":             transactionActivity.ID,
+			"order_id":       transactionActivity.OrderID,
 			"item_id":        transactionActivity.ItemID,
 			"status":         transactionActivity.Status,
 			"created_at":     transactionActivity.CreatedAt,
@@ -166,6 +191,63 @@ func CreateMarketItemPickupInformation(c *gin.Context) {
 	})
 }
 
+// QuoteMarketItemPickup previews the fees an order of the item would be
+// charged right now, without reserving it. Only items that can be ordered are
+// quoted.
+func QuoteMarketItemPickup(c *gin.Context) {
+	var input MarketItemQuoteInput
+	if err := c.ShouldBind(&input); err != nil {
+		utils.RespondFailed(c, http.StatusBadRequest, "Input tidak valid", nil)
+		return
+	}
+
+	var marketItem models.MarketItems
+	if err := config.DB.Where("id = ?", input.ItemID).First(&marketItem).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Market item not found", nil)
+		return
+	}
+
+	rules, err := services.LoadMarketRules(config.DB)
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to load marketplace rules", nil)
+		return
+	}
+
+	userRole, _ := c.Get("userRole")
+	if !rules.CanBuy(userRole.(string), marketItem.ItemScale) {
+		utils.RespondFailed(c, http.StatusForbidden, "You do not have permission to order this market item", nil)
+		return
+	}
+
+	if marketItem.Status != models.MarketItemStatusReady {
+		utils.RespondFailed(c, http.StatusConflict, "Market item is not available for order", nil)
+		return
+	}
+
+	if marketItem.ListingType == models.ListingTypeAuction {
+		utils.RespondFailed(c, http.StatusConflict, "Market item is being auctioned", nil)
+		return
+	}
+
+	quote, err := services.QuoteFees(config.DB, marketItem, input.PickupLat, input.PickupLon)
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to calculate fees", nil)
+		return
+	}
+
+	utils.RespondSuccess(c, "Quote calculated successfully", gin.H{
+		"item_id":        marketItem.ID,
+		"item_price":     marketItem.Price,
+		"service_price":  quote.ServicePrice,
+		"delivery_price": quote.DeliveryPrice,
+		"total_price":    marketItem.Price + quote.ServicePrice + quote.DeliveryPrice,
+		"currency":       marketItem.Currency,
+		"distance_km":    quote.DistanceKm,
+		"tariff_id":      quote.TariffID,
+		"tariff_name":    quote.TariffName,
+	})
+}
+
 func GetMarketItemTransactions(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
@@ -173,65 +255,45 @@ func GetMarketItemTransactions(c *gin.Context) {
 		return
 	}
 
-	var latestTransactions []models.MarketItemTransactionActivities
-	subQuery := config.DB.Unscoped().Table("market_item_transaction_activities").
-		Select("MAX(created_at)").
-		Group("item_id")
-
-	if err := config.DB.Unscoped().Preload("MarketItem.PostedByUser").
-		Preload("MarketItem.OrderedByUser").
-		Where("created_at IN (?)", subQuery).
-		Find(&latestTransactions).Error; err != nil {
+	var orders []models.Order
+	if err := preloadOrder(config.DB).
+		Where("buyer_id = ? OR seller_id = ?", userID, userID).
+		Order("created_at desc").
+		Find(&orders).Error; err != nil {
 		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to fetch market transactions", nil)
 		return
 	}
 
 	var responseItems []map[string]interface{}
-	for _, transaction := range latestTransactions {
-		marketItem := transaction.MarketItem
-		if marketItem.PostedBy != userID && (marketItem.OrderedBy == nil || *marketItem.OrderedBy != userID) {
-			continue
-		}
-
-		postedBy := map[string]interface{}{
-			"id":           marketItem.PostedByUser.ID,
-			"phone_number": marketItem.PostedByUser.PhoneNumber,
-			"name":         marketItem.PostedByUser.Name,
-			"role":         marketItem.PostedByUser.Role,
-		}
-
-		var orderedBy map[string]interface{}
-		if marketItem.OrderedBy != nil {
-			orderedBy = map[string]interface{}{
-				"id":           marketItem.OrderedByUser.ID,
-				"phone_number": marketItem.OrderedByUser.PhoneNumber,
-				"name":         marketItem.OrderedByUser.Name,
-				"role":         marketItem.OrderedByUser.Role,
-			}
-		} else {
-			orderedBy = nil
-		}
+	for _, order := range orders {
+		marketItem := order.MarketItem
 
 		item := map[string]interface{}{
-			"id":            marketItem.ID,
-			"name":          marketItem.Name,
-			"price":         marketItem.Price,
-			"weight":        marketItem.Weight,
-			"description":   marketItem.Description,
-			"thumbnail_url": marketItem.ThumbnailUrl,
-			"posted_by":     postedBy,
-			"posted_at":     marketItem.CreatedAt.Format(time.RFC3339),
-			"ordered_by":    orderedBy,
+			"id":                 marketItem.ID,
+			"name":               marketItem.Name,
+			"price":              marketItem.Price,
+			"currency":           marketItem.Currency,
+			"weight":             marketItem.Weight,
+			"weight_unit":        marketItem.WeightUnit,
+			"display_weight":     models.WeightInUnit(marketItem.Weight, marketItem.WeightUnit),
+			"quantity":           marketItem.Quantity,
+			"quantity_unit":      marketItem.QuantityUnit,
+			"weight_is_estimate": marketItem.WeightIsEstimate,
+			"description":        marketItem.Description,
+			"thumbnail_url":      marketItem.ThumbnailUrl,
+			"posted_by":          orderUserResponse(order.Seller),
+			"posted_at":          marketItem.CreatedAt.Format(time.RFC3339),
+			"ordered_by":         orderUserResponse(order.Buyer),
 		}
 
 		responseItem := map[string]interface{}{
-			"id":   transaction.ID,
+			"id":   order.ID,
 			"item": item,
 			"last_status": map[string]interface{}{
-				"status":     transaction.Status,
-				"created_at": transaction.CreatedAt.Format(time.RFC3339),
+				"status":     order.Status,
+				"created_at": order.UpdatedAt.Format(time.RFC3339),
 			},
-			"transaction_by": transaction.TransactionByID,
+			"transaction_by": order.PickupInformationID,
 		}
 
 		responseItems = append(responseItems, responseItem)
@@ -250,17 +312,11 @@ func GetMarketItemTransactions(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// GetMarketItemPickupInformationByID is keyed by market item ID and shows the
+// most recent order of that item; GetOrderByID covers any specific order.
 func GetMarketItemPickupInformationByID(c *gin.Context) {
-	itemID := c.Param("id")
-	var marketItem models.MarketItems
-
-	if err := config.DB.Unscoped().Preload("PostedByUser").Preload("OrderedByUser").Where("id = ?", itemID).First(&marketItem).Error; err != nil {
-		utils.RespondFailed(c, http.StatusNotFound, "Market item not found", nil)
-		return
-	}
-
-	var pickupInfo models.MarketItemPickupInformations
-	if err := config.DB.Preload("MarketItem").Where("item_id = ?", marketItem.ID).First(&pickupInfo).Error; err != nil {
+	order, err := findLatestOrderForItem(c.Param("id"))
+	if err != nil {
 		utils.RespondFailed(c, http.StatusNotFound, "Pickup information not found", nil)
 		return
 	}
@@ -271,64 +327,46 @@ func GetMarketItemPickupInformationByID(c *gin.Context) {
 		return
 	}
 
-	if marketItem.PostedBy != userID && (marketItem.OrderedBy == nil || *marketItem.OrderedBy != userID) {
+	if !isOrderParty(order, userID) {
 		utils.RespondFailed(c, http.StatusForbidden, "You do not have permission to access this resource", nil)
 		return
 	}
 
-	var transactionActivities []models.MarketItemTransactionActivities
-	if err := config.DB.Unscoped().Where("item_id = ?", marketItem.ID).Order("created_at desc").Find(&transactionActivities).Error; err != nil {
-		transactionActivities = []models.MarketItemTransactionActivities{}
-	}
-
-	postedBy := map[string]interface{}{
-		"id":           marketItem.PostedByUser.ID,
-		"phone_number": marketItem.PostedByUser.PhoneNumber,
-		"name":         marketItem.PostedByUser.Name,
-		"role":         marketItem.PostedByUser.Role,
-	}
-
-	orderedBy := map[string]interface{}{}
-	if marketItem.OrderedBy != nil {
-		orderedBy = map[string]interface{}{
-			"id":           marketItem.OrderedByUser.ID,
-			"phone_number": marketItem.OrderedByUser.PhoneNumber,
-			"name":         marketItem.OrderedByUser.Name,
-			"role":         marketItem.OrderedByUser.Role,
-		}
-	}
-
+	marketItem := order.MarketItem
 	itemDetail := map[string]interface{}{
-		"id":            marketItem.ID,
-		"name":          marketItem.Name,
-		"price":         marketItem.Price,
-		"weight":        marketItem.Weight,
-		"description":   marketItem.Description,
-		"thumbnail_url": marketItem.ThumbnailUrl,
-		"posted_by":     postedBy,
-		"ordered_by":    orderedBy,
-		"posted_at":     marketItem.CreatedAt.Format(time.RFC3339),
-	}
-
-	var allStatus []map[string]interface{}
-	for _, activity := range transactionActivities {
-		if marketItem.OrderedBy != nil && activity.TransactionByID == pickupInfo.ID {
-			allStatus = append(allStatus, map[string]interface{}{
-				"status":     activity.Status,
-				"created_at": activity.CreatedAt.Format(time.RFC3339),
-			})
-		}
-	}
-
-	if allStatus == nil {
-		allStatus = []map[string]interface{}{}
+		"id":                 marketItem.ID,
+		"name":               marketItem.Name,
+		"price":              marketItem.Price,
+		"currency":           marketItem.Currency,
+		"weight":             marketItem.Weight,
+		"weight_unit":        marketItem.WeightUnit,
+		"display_weight":     models.WeightInUnit(marketItem.Weight, marketItem.WeightUnit),
+		"quantity":           marketItem.Quantity,
+		"quantity_unit":      marketItem.QuantityUnit,
+		"weight_is_estimate": marketItem.WeightIsEstimate,
+		"description":        marketItem.Description,
+		"thumbnail_url":      marketItem.ThumbnailUrl,
+		"posted_by":          orderUserResponse(order.Seller),
+		"ordered_by":         orderUserResponse(order.Buyer),
+		"posted_at":          marketItem.CreatedAt.Format(time.RFC3339),
+	}
+
+	allStatus := []map[string]interface{}{}
+	for i := len(order.Activities) - 1; i >= 0; i-- {
+		activity := order.Activities[i]
+		allStatus = append(allStatus, map[string]interface{}{
+			"status":     activity.Status,
+			"reason":     activity.Reason,
+			"created_at": activity.CreatedAt.Format(time.RFC3339),
+		})
 	}
 
 	response := map[string]interface{}{
 		"success": true,
 		"message": "Pickup information fetched successfully",
 		"data": map[string]interface{}{
-			"id":         pickupInfo.ID,
+			"id":         order.PickupInformationID,
+			"order_id":   order.ID,
 			"item":       itemDetail,
 			"all_status": allStatus,
 		},
@@ -337,102 +375,26 @@ func GetMarketItemPickupInformationByID(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// UpdateMarketItemTransactionStatus is keyed by market item ID and changes the
+// most recent order of that item; UpdateOrderStatus covers any specific order.
 func UpdateMarketItemTransactionStatus(c *gin.Context) {
-	var input MarketItemTransactionStatusUpdateInput
+	var input OrderStatusUpdateInput
 	if err := c.ShouldBind(&input); err != nil {
 		utils.RespondFailed(c, http.StatusBadRequest, "Input tidak valid", nil)
 		return
 	}
 
-	itemID := c.Param("id")
 	var marketItem models.MarketItems
-
-	if err := config.DB.Unscoped().Where("id = ?", itemID).First(&marketItem).Error; err != nil {
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&marketItem).Error; err != nil {
 		utils.RespondFailed(c, http.StatusNotFound, "Market item not found", nil)
 		return
 	}
 
-	userID, exists := c.Get("userID")
-	if !exists {
-		utils.RespondFailed(c, http.StatusUnauthorized, "Unauthorized", nil)
-		return
-	}
-
-	if marketItem.PostedBy != userID {
-		utils.RespondFailed(c, http.StatusForbidden, "You do not have permission to access this resource", nil)
-		return
-	}
-
-	var pickupInfo models.MarketItemPickupInformations
-	if err := config.DB.Where("item_id = ?", marketItem.ID).First(&pickupInfo).Error; err != nil {
+	order, err := findLatestOrderForItem(marketItem.ID)
+	if err != nil {
 		utils.RespondFailed(c, http.StatusNotFound, "Pickup information not found", nil)
 		return
 	}
 
-	validStatuses := []string{"ON_PROCESS", "ON_DELIVER", "FINISHED", "CANCELLED"}
-	isValidStatus := false
-	for _, status := range validStatuses {
-		if input.Status == status {
-			isValidStatus = true
-			break
-		}
-	}
-	if !isValidStatus {
-		utils.RespondFailed(c, http.StatusBadRequest, "Invalid status value", nil)
-		return
-	}
-
-	var existingTransaction models.MarketItemTransactionActivities
-	if err := config.DB.Where("item_id = ? AND status = ? AND transaction_by_id = ?", marketItem.ID, input.Status, pickupInfo.ID).First(&existingTransaction).Error; err == nil {
-		utils.RespondFailed(c, http.StatusBadRequest, "Duplicate status for the same item and transaction", nil)
-		return
-	}
-
-	tx := config.DB.Begin()
-	if tx.Error != nil {
-		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to start transaction", nil)
-		return
-	}
-
-	transaction := models.MarketItemTransactionActivities{
-		ID:              uuid.New(),
-		ItemID:          marketItem.ID,
-		Status:          input.Status,
-		TransactionByID: pickupInfo.ID,
-		CreatedAt:       time.Now(),
-		UpdatedAt:       time.Now(),
-	}
-
-	if err := tx.Create(&transaction).Error; err != nil {
-		tx.Rollback()
-		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to create new transaction activity", nil)
-		return
-	}
-
-	if input.Status == "CANCELLED" {
-		marketItem.OrderedBy = nil
-		marketItem.DeletedAt = gorm.DeletedAt{}
-		if err := tx.Save(&marketItem).Error; err != nil {
-			tx.Rollback()
-			utils.RespondFailed(c, http.StatusInternalServerError, "Failed to update market item", nil)
-			return
-		}
-	}
-
-	if err := tx.Commit().Error; err != nil {
-		tx.Rollback()
-		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to commit transaction", nil)
-		return
-	}
-
-	response := map[string]interface{}{
-		"id":             transaction.ID,
-		"item_id":        transaction.ItemID,
-		"status":         transaction.Status,
-		"created_at":     transaction.CreatedAt,
-		"updated_at":     transaction.UpdatedAt,
-		"transaction_by": transaction.TransactionByID,
-	}
-
-	utils.RespondSuccess(c, "Transaction status updated successfully", response)
+	updateOrderStatus(c, order, input)
 }
@@ -0,0 +1,63 @@
+package controllers_test
+
+import (
+	"net/http"
+	"net/url"
+	"recyco/config"
+	"recyco/models"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentOrdersReserveItemOnce(t *testing.T) {
+	router := setupDatabase(t)
+
+	seller, _ := createUser(t, "P_LARGE")
+	item := createItem(t, seller, "LARGE")
+
+	const buyers = 10
+	tokens := make([]string, buyers)
+	for i := range tokens {
+		_, tokens[i] = createUser(t, "C_LARGE")
+	}
+
+	form := url.Values{
+		"item_id":                 {item.ID.String()},
+		"recipient_name":          {"Buyer"},
+		"recipient_phone":         {"08123456789"},
+		"pickup_location_address": {"Jl. Merdeka 1"},
+	}
+
+	codes := make([]int, buyers)
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := range tokens {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			codes[i] = send(router, http.MethodPost, "/market_transactions/", tokens[i], form).Code
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	counts := map[int]int{}
+	for _, code := range codes {
+		counts[code]++
+	}
+	if counts[http.StatusOK] != 1 || counts[http.StatusConflict] != buyers-1 {
+		t.Fatalf("responses = %v, want one %d and %d %d", counts, http.StatusOK, buyers-1, http.StatusConflict)
+	}
+
+	var orders int64
+	config.DB.Model(&models.Order{}).Where("item_id = ?", item.ID).Count(&orders)
+	if orders != 1 {
+		t.Fatalf("orders = %d, want 1", orders)
+	}
+
+	config.DB.Where("id = ?", item.ID).First(&item)
+	if item.Status != models.MarketItemStatusReserved {
+		t.Fatalf("item status = %s, want %s", item.Status, models.MarketItemStatusReserved)
+	}
+}
@@ -0,0 +1,369 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"recyco/config"
+	"recyco/models"
+	"recyco/services"
+	"recyco/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultAuctionExtensionMinutes = 5
+	maxAuctionDuration             = 30 * 24 * time.Hour
+)
+
+type AuctionInput struct {
+	ItemID           string       `form:"item_id" binding:"required"`
+	StartingPrice    models.Money `form:"starting_price" binding:"required"`
+	ReservePrice     models.Money `form:"reserve_price"`
+	MinIncrement     models.Money `form:"min_increment" binding:"required"`
+	EndsAt           string       `form:"ends_at" binding:"required"`
+	ExtensionMinutes int          `form:"extension_minutes"`
+}
+
+type BidInput struct {
+	Amount                    models.Money `form:"amount" binding:"required"`
+	RecipientName             string       `form:"recipient_name" binding:"required"`
+	RecipientPhone            string       `form:"recipient_phone" binding:"required"`
+	PickupLocationAddress     string       `form:"pickup_location_address" binding:"required"`
+	PickupLocationDescription string       `form:"pickup_location_description"`
+	PickupLat                 *float64     `form:"pickup_lat"`
+	PickupLon                 *float64     `form:"pickup_lon"`
+}
+
+// auctionResponse hides the reserve price from everyone but the seller;
+// bidders only learn whether it has been met.
+func auctionResponse(auction models.Auction, userID interface{}) map[string]interface{} {
+	response := map[string]interface{}{
+		"id": auction.ID,
+		"item": map[string]interface{}{
+			"id":             auction.MarketItem.ID,
+			"name":           auction.MarketItem.Name,
+			"weight":         auction.MarketItem.Weight,
+			"weight_unit":    auction.MarketItem.WeightUnit,
+			"display_weight": models.WeightInUnit(auction.MarketItem.Weight, auction.MarketItem.WeightUnit),
+			"category":       auction.MarketItem.Category,
+			"description":    auction.MarketItem.Description,
+			"thumbnail_url":  auction.MarketItem.ThumbnailUrl,
+		},
+		"seller_id":         auction.SellerID,
+		"starting_price":    auction.StartingPrice,
+		"min_increment":     auction.MinIncrement,
+		"current_price":     auction.CurrentPrice,
+		"minimum_bid":       services.MinimumBid(auction),
+		"currency":          auction.Currency,
+		"reserve_met":       auction.BidCount > 0 && auction.CurrentPrice >= auction.ReservePrice,
+		"bid_count":         auction.BidCount,
+		"highest_bidder_id": auction.HighestBidderID,
+		"ends_at":           auction.EndsAt.Format(time.RFC3339),
+		"extension_seconds": auction.ExtensionSeconds,
+		"status":            auction.Status,
+		"order_id":          auction.OrderID,
+		"created_at":        auction.CreatedAt.Format(time.RFC3339),
+	}
+	if auction.SellerID == userID {
+		response["reserve_price"] = auction.ReservePrice
+	}
+	return response
+}
+
+func bidResponse(bid models.Bid) map[string]interface{} {
+	return map[string]interface{}{
+		"id":       bid.ID,
+		"sequence": bid.Sequence,
+		"bidder": map[string]interface{}{
+			"id":   bid.Bidder.ID,
+			"name": bid.Bidder.Name,
+		},
+		"amount":     bid.Amount,
+		"created_at": bid.CreatedAt.Format(time.RFC3339Nano),
+	}
+}
+
+func preloadAuction(db *gorm.DB) *gorm.DB {
+	return db.Preload("MarketItem", func(db *gorm.DB) *gorm.DB { return db.Unscoped() })
+}
+
+// findViewableAuction loads the auction in the URL if the user may view its
+// item's scale or is its seller. It writes the error response itself.
+func findViewableAuction(c *gin.Context) (models.Auction, bool) {
+	var auction models.Auction
+	if err := preloadAuction(config.DB).Where("id = ?", c.Param("id")).First(&auction).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Auction not found", nil)
+		return auction, false
+	}
+
+	userID, _ := c.Get("userID")
+	if auction.SellerID == userID {
+		return auction, true
+	}
+
+	rules, err := services.LoadMarketRules(config.DB)
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to load marketplace rules", nil)
+		return auction, false
+	}
+
+	userRole, _ := c.Get("userRole")
+	if role, ok := userRole.(string); !ok || !rules.CanView(role, auction.MarketItem.ItemScale) {
+		utils.RespondFailed(c, http.StatusForbidden, "You do not have permission to access this resource", nil)
+		return auction, false
+	}
+
+	return auction, true
+}
+
+// CreateAuction puts a seller's READY large-scale item up for auction. The
+// item can no longer be ordered directly until the auction ends.
+func CreateAuction(c *gin.Context) {
+	var input AuctionInput
+	if err := c.ShouldBind(&input); err != nil {
+		utils.RespondFailed(c, http.StatusBadRequest, "Input tidak valid", nil)
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.RespondFailed(c, http.StatusInternalServerError, "User ID not found in context", nil)
+		return
+	}
+
+	if input.StartingPrice <= 0 || input.MinIncrement <= 0 || input.ReservePrice < 0 {
+		utils.RespondFailed(c, http.StatusBadRequest, "starting_price and min_increment must be greater than 0", nil)
+		return
+	}
+
+	endsAt, err := time.Parse(time.RFC3339, input.EndsAt)
+	if err != nil || !endsAt.After(time.Now()) || endsAt.Sub(time.Now()) > maxAuctionDuration {
+		utils.RespondFailed(c, http.StatusBadRequest, "ends_at must be a future RFC3339 time within 30 days", nil)
+		return
+	}
+
+	if input.ExtensionMinutes == 0 {
+		input.ExtensionMinutes = defaultAuctionExtensionMinutes
+	}
+	if input.ExtensionMinutes < 0 || input.ExtensionMinutes > 60 {
+		utils.RespondFailed(c, http.StatusBadRequest, "extension_minutes must be between 1 and 60", nil)
+		return
+	}
+
+	var marketItem models.MarketItems
+	if err := config.DB.Where("id = ?", input.ItemID).First(&marketItem).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Market item not found", nil)
+		return
+	}
+
+	if marketItem.PostedBy != userID {
+		utils.RespondFailed(c, http.StatusForbidden, "You can only auction your own market items", nil)
+		return
+	}
+
+	if marketItem.ItemScale != "LARGE" {
+		utils.RespondFailed(c, http.StatusBadRequest, "Only LARGE scale items can be auctioned", nil)
+		return
+	}
+
+	auction := models.Auction{
+		ItemID:           marketItem.ID,
+		SellerID:         marketItem.PostedBy,
+		StartingPrice:    input.StartingPrice,
+		ReservePrice:     input.ReservePrice,
+		MinIncrement:     input.MinIncrement,
+		Currency:         marketItem.Currency,
+		EndsAt:           endsAt,
+		ExtensionSeconds: input.ExtensionMinutes * 60,
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		return services.StartAuction(tx, &auction)
+	})
+	if errors.Is(err, services.ErrTransactionConflict) {
+		utils.RespondFailed(c, http.StatusConflict, "Market item is not available for auction", nil)
+		return
+	}
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to create auction", nil)
+		return
+	}
+
+	auction.MarketItem = marketItem
+	utils.RespondSuccess(c, "Auction created successfully", auctionResponse(auction, userID))
+}
+
+func GetAuctions(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	userRole, exists := c.Get("userRole")
+	if !exists {
+		utils.RespondFailed(c, http.StatusInternalServerError, "User role not found in context", nil)
+		return
+	}
+
+	rules, err := services.LoadMarketRules(config.DB)
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to load marketplace rules", nil)
+		return
+	}
+
+	status := c.DefaultQuery("status", models.AuctionStatusOpen)
+
+	var auctions []models.Auction
+	if err := preloadAuction(config.DB).
+		Joins("JOIN market_items ON market_items.id = auctions.item_id").
+		Where("auctions.status = ?", status).
+		Where("market_items.item_scale IN ? OR auctions.seller_id = ?", rules.ViewableScales(userRole.(string)), userID).
+		Order("auctions.ends_at asc").
+		Find(&auctions).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to fetch auctions", nil)
+		return
+	}
+
+	responseItems := []map[string]interface{}{}
+	for _, auction := range auctions {
+		responseItems = append(responseItems, auctionResponse(auction, userID))
+	}
+
+	utils.RespondSuccess(c, "Auctions fetched successfully", responseItems)
+}
+
+func GetAuctionByID(c *gin.Context) {
+	auction, ok := findViewableAuction(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	utils.RespondSuccess(c, "Auction fetched successfully", auctionResponse(auction, userID))
+}
+
+func GetAuctionBids(c *gin.Context) {
+	auction, ok := findViewableAuction(c)
+	if !ok {
+		return
+	}
+
+	var bids []models.Bid
+	if err := config.DB.Preload("Bidder").Where("auction_id = ?", auction.ID).Order("sequence desc").Find(&bids).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to fetch bids", nil)
+		return
+	}
+
+	responseItems := []map[string]interface{}{}
+	for _, bid := range bids {
+		responseItems = append(responseItems, bidResponse(bid))
+	}
+
+	utils.RespondSuccess(c, "Bids fetched successfully", responseItems)
+}
+
+// PlaceAuctionBid places a bid for the signed-in buyer. A bid that lost a race
+// against a concurrent one is answered with 409 and the new minimum, so the
+// client can bid again knowingly rather than being silently reordered.
+func PlaceAuctionBid(c *gin.Context) {
+	var input BidInput
+	if err := c.ShouldBind(&input); err != nil {
+		utils.RespondFailed(c, http.StatusBadRequest, "Input tidak valid", nil)
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.RespondFailed(c, http.StatusInternalServerError, "User ID not found in context", nil)
+		return
+	}
+
+	auction, ok := findViewableAuction(c)
+	if !ok {
+		return
+	}
+
+	if auction.SellerID == userID {
+		utils.RespondFailed(c, http.StatusBadRequest, "You cannot bid on your own auction", nil)
+		return
+	}
+
+	rules, err := services.LoadMarketRules(config.DB)
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to load marketplace rules", nil)
+		return
+	}
+
+	userRole, _ := c.Get("userRole")
+	if !rules.CanBuy(userRole.(string), auction.MarketItem.ItemScale) {
+		utils.RespondFailed(c, http.StatusForbidden, "You do not have permission to bid on this auction", nil)
+		return
+	}
+
+	bid := models.Bid{
+		BidderID:                  userID.(uuid.UUID),
+		Amount:                    input.Amount,
+		RecipientName:             input.RecipientName,
+		RecipientPhone:            input.RecipientPhone,
+		PickupLocationAddress:     input.PickupLocationAddress,
+		PickupLocationDescription: input.PickupLocationDescription,
+		PickupLat:                 input.PickupLat,
+		PickupLon:                 input.PickupLon,
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		return services.PlaceBid(tx, &auction, &bid, time.Now())
+	})
+	switch {
+	case errors.Is(err, services.ErrAuctionClosed):
+		utils.RespondFailed(c, http.StatusConflict, "Auction is not open for bids", nil)
+		return
+	case errors.Is(err, services.ErrBidTooLow):
+		utils.RespondFailed(c, http.StatusBadRequest, "Bid must be at least "+services.MinimumBid(auction).String(), nil)
+		return
+	case errors.Is(err, services.ErrBidOutpaced):
+		var latest models.Auction
+		config.DB.Where("id = ?", auction.ID).First(&latest)
+		utils.RespondFailed(c, http.StatusConflict, "Another bid was placed first; the minimum bid is now "+services.MinimumBid(latest).String(), nil)
+		return
+	case err != nil:
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to place bid", nil)
+		return
+	}
+
+	utils.RespondSuccess(c, "Bid placed successfully", gin.H{
+		"bid":     bidResponse(bid),
+		"auction": auctionResponse(auction, userID),
+	})
+}
+
+// CancelAuction lets the seller withdraw an auction before anyone has bid.
+func CancelAuction(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var auction models.Auction
+	if err := preloadAuction(config.DB).Where("id = ?", c.Param("id")).First(&auction).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Auction not found", nil)
+		return
+	}
+
+	if auction.SellerID != userID {
+		utils.RespondFailed(c, http.StatusForbidden, "You do not have permission to cancel this auction", nil)
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		return services.CancelAuction(tx, auction)
+	})
+	if errors.Is(err, services.ErrTransactionConflict) {
+		utils.RespondFailed(c, http.StatusConflict, "Only open auctions without bids can be cancelled", nil)
+		return
+	}
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to cancel auction", nil)
+		return
+	}
+
+	auction.Status = models.AuctionStatusCancelled
+	utils.RespondSuccess(c, "Auction cancelled successfully", auctionResponse(auction, userID))
+}
@@ -0,0 +1,163 @@
+package controllers_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"recyco/config"
+	"recyco/models"
+	"recyco/services"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// startAuction puts a new LARGE item of a new seller up for auction through
+// the API and returns the auction.
+func startAuction(t *testing.T) models.Auction {
+	t.Helper()
+	router := setupDatabase(t)
+
+	seller, sellerToken := createUser(t, "P_LARGE")
+	item := createItem(t, seller, "LARGE")
+	recorder := send(router, http.MethodPost, "/auctions/", sellerToken, url.Values{
+		"item_id":        {item.ID.String()},
+		"starting_price": {"10000"},
+		"min_increment":  {"100"},
+		"ends_at":        {time.Now().Add(time.Hour).Format(time.RFC3339)},
+	})
+	assertStatus(t, recorder, http.StatusOK)
+
+	var body struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode auction: %v", err)
+	}
+
+	var auction models.Auction
+	if err := config.DB.Where("id = ?", body.Data.ID).First(&auction).Error; err != nil {
+		t.Fatalf("find auction: %v", err)
+	}
+	return auction
+}
+
+func bidForm(amount int64) url.Values {
+	return url.Values{
+		"amount":                  {strconv.FormatInt(amount, 10)},
+		"recipient_name":          {"Bidder"},
+		"recipient_phone":         {"08123456789"},
+		"pickup_location_address": {"Jl. Merdeka 1"},
+	}
+}
+
+func TestConcurrentBidsAreStrictlyOrdered(t *testing.T) {
+	router := setupDatabase(t)
+	auction := startAuction(t)
+
+	const bidders = 8
+	tokens := make([]string, bidders)
+	for i := range tokens {
+		_, tokens[i] = createUser(t, "C_LARGE")
+	}
+
+	codes := make([]int, bidders)
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := range tokens {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			codes[i] = send(router, http.MethodPost, "/auctions/"+auction.ID.String()+"/bids", tokens[i], bidForm(20000+int64(i)*1000)).Code
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	placed := 0
+	for _, code := range codes {
+		switch code {
+		case http.StatusOK:
+			placed++
+		case http.StatusConflict, http.StatusBadRequest:
+		default:
+			t.Fatalf("responses = %v, want only %d, %d and %d", codes, http.StatusOK, http.StatusConflict, http.StatusBadRequest)
+		}
+	}
+	if placed == 0 {
+		t.Fatalf("responses = %v, want at least one bid placed", codes)
+	}
+
+	var bids []models.Bid
+	config.DB.Where("auction_id = ?", auction.ID).Order("sequence asc").Find(&bids)
+	if len(bids) != placed {
+		t.Fatalf("bids = %d, want %d", len(bids), placed)
+	}
+	for i, bid := range bids {
+		if bid.Sequence != i+1 {
+			t.Fatalf("bid %d has sequence %d", i+1, bid.Sequence)
+		}
+		if i > 0 && bid.Amount < bids[i-1].Amount+auction.MinIncrement {
+			t.Fatalf("bid %d of %s does not raise bid %d of %s", bid.Sequence, bid.Amount, bids[i-1].Sequence, bids[i-1].Amount)
+		}
+	}
+
+	last := bids[len(bids)-1]
+	config.DB.Where("id = ?", auction.ID).First(&auction)
+	if auction.BidCount != placed || auction.CurrentPrice != last.Amount || auction.HighestBidID == nil || *auction.HighestBidID != last.ID {
+		t.Fatalf("auction leads with bid %v at %s after %d bids, want bid %s at %s after %d",
+			auction.HighestBidID, auction.CurrentPrice, auction.BidCount, last.ID, last.Amount, placed)
+	}
+}
+
+func TestAuctionItemIsOnlyReservedByItsAuction(t *testing.T) {
+	router := setupDatabase(t)
+	auction := startAuction(t)
+
+	bidder, bidderToken := createUser(t, "C_LARGE")
+	assertStatus(t, send(router, http.MethodPost, "/auctions/"+auction.ID.String()+"/bids", bidderToken, bidForm(20000)), http.StatusOK)
+
+	var item models.MarketItems
+	config.DB.Where("id = ?", auction.ItemID).First(&item)
+
+	// A fixed-price order working from a stale read of the item must not
+	// take it while it is auctioned.
+	buyer, _ := createUser(t, "C_LARGE")
+	item.ListingType = models.ListingTypeFixed
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		_, _, err := services.PlaceOrder(tx, item, buyer.ID, &models.MarketItemPickupInformations{
+			RecipientName:         "Buyer",
+			RecipientPhone:        "08123456789",
+			PickupLocationAddress: "Jl. Merdeka 1",
+		}, item.Price, models.FulfillmentDelivery)
+		return err
+	})
+	if !errors.Is(err, services.ErrTransactionConflict) {
+		t.Fatalf("fixed-price order of an auction item: %v, want %v", err, services.ErrTransactionConflict)
+	}
+
+	config.DB.Where("id = ?", auction.ID).First(&auction)
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		return services.CloseAuction(tx, auction, auction.EndsAt.Add(time.Second))
+	})
+	if err != nil {
+		t.Fatalf("close auction: %v", err)
+	}
+
+	config.DB.Where("id = ?", auction.ItemID).First(&item)
+	if item.Status != models.MarketItemStatusReserved || item.ListingType != models.ListingTypeFixed {
+		t.Fatalf("item is %s %s, want %s %s", item.Status, item.ListingType, models.MarketItemStatusReserved, models.ListingTypeFixed)
+	}
+
+	var order models.Order
+	if err := config.DB.Where("item_id = ? AND buyer_id = ?", item.ID, bidder.ID).First(&order).Error; err != nil {
+		t.Fatalf("find order of the winning bidder: %v", err)
+	}
+}
@@ -0,0 +1,399 @@
+package controllers
+
+import (
+	"net/http"
+	"recyco/config"
+	"recyco/models"
+	"recyco/services"
+	"recyco/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type ContractInput struct {
+	BuyerID         string       `form:"buyer_id" binding:"required"`
+	Title           string       `form:"title" binding:"required"`
+	Category        string       `form:"category" binding:"required"`
+	Description     string       `form:"description"`
+	EstimatedWeight float64      `form:"estimated_weight" binding:"required"`
+	WeightUnit      string       `form:"weight_unit"`
+	PricePerKg      models.Money `form:"price_per_kg" binding:"required"`
+	Frequency       string       `form:"frequency" binding:"required"`
+	Weekday         int          `form:"weekday"`
+	DayOfMonth      int          `form:"day_of_month"`
+	StartsOn        string       `form:"starts_on" binding:"required"`
+	EndsOn          string       `form:"ends_on"`
+	Lat             *float64     `form:"lat"`
+	Lon             *float64     `form:"lon"`
+}
+
+type ContractAcceptInput struct {
+	RecipientName             string   `form:"recipient_name" binding:"required"`
+	RecipientPhone            string   `form:"recipient_phone" binding:"required"`
+	PickupLocationAddress     string   `form:"pickup_location_address" binding:"required"`
+	PickupLocationDescription string   `form:"pickup_location_description"`
+	PickupLat                 *float64 `form:"pickup_lat"`
+	PickupLon                 *float64 `form:"pickup_lon"`
+}
+
+type ContractTerminateInput struct {
+	Reason string `form:"reason" binding:"required"`
+}
+
+func contractResponse(contract models.Contract) map[string]interface{} {
+	return map[string]interface{}{
+		"id":                          contract.ID,
+		"seller":                      orderUserResponse(contract.Seller),
+		"buyer":                       orderUserResponse(contract.Buyer),
+		"title":                       contract.Title,
+		"category":                    contract.Category,
+		"description":                 contract.Description,
+		"estimated_weight":            contract.EstimatedWeight,
+		"weight_unit":                 contract.WeightUnit,
+		"display_weight":              models.WeightInUnit(contract.EstimatedWeight, contract.WeightUnit),
+		"price_per_kg":                contract.PricePerKg,
+		"estimated_price":             contract.PricePerKg.MulQuantity(contract.EstimatedWeight),
+		"currency":                    contract.Currency,
+		"frequency":                   contract.Frequency,
+		"weekday":                     contract.Weekday,
+		"day_of_month":                contract.DayOfMonth,
+		"starts_on":                   contract.StartsOn.Format("2006-01-02"),
+		"ends_on":                     contract.EndsOn,
+		"next_run_at":                 contract.NextRunAt,
+		"recipient_name":              contract.RecipientName,
+		"recipient_phone":             contract.RecipientPhone,
+		"pickup_location_address":     contract.PickupLocationAddress,
+		"pickup_location_description": contract.PickupLocationDescription,
+		"status":                      contract.Status,
+		"terminated_by":               contract.TerminatedBy,
+		"termination_reason":          contract.TerminationReason,
+		"created_at":                  contract.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// findContract loads the contract in the URL for one of its parties. It
+// writes the error response itself.
+func findContract(c *gin.Context) (models.Contract, bool) {
+	var contract models.Contract
+	if err := config.DB.Preload("Seller").Preload("Buyer").Where("id = ?", c.Param("id")).First(&contract).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Contract not found", nil)
+		return contract, false
+	}
+
+	userID, _ := c.Get("userID")
+	userRole, _ := c.Get("userRole")
+	if contract.SellerID != userID && contract.BuyerID != userID && userRole != "ADMIN" {
+		utils.RespondFailed(c, http.StatusForbidden, "You do not have permission to access this resource", nil)
+		return contract, false
+	}
+
+	return contract, true
+}
+
+// moveContract changes the status of contract from one of from, writing the
+// error response itself when another request changed it first.
+func moveContract(c *gin.Context, contract *models.Contract, from []string, updates map[string]interface{}) bool {
+	updates["UpdatedAt"] = time.Now()
+	result := config.DB.Model(&models.Contract{}).
+		Where("id = ? AND status IN ?", contract.ID, from).
+		Updates(updates)
+	if result.Error != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to update contract", nil)
+		return false
+	}
+	if result.RowsAffected == 0 {
+		utils.RespondFailed(c, http.StatusConflict, "Contract is "+contract.Status, nil)
+		return false
+	}
+
+	config.DB.Preload("Seller").Preload("Buyer").Where("id = ?", contract.ID).First(contract)
+	return true
+}
+
+// CreateContract lets a large producer propose a recurring sale to a
+// recycler. It takes effect once the buyer accepts it. The estimated weight
+// decides the scale of the items each run posts, which the seller must be
+// allowed to post and the buyer to buy.
+func CreateContract(c *gin.Context) {
+	var input ContractInput
+	if err := c.ShouldBind(&input); err != nil {
+		utils.RespondFailed(c, http.StatusBadRequest, "Input tidak valid", nil)
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.RespondFailed(c, http.StatusInternalServerError, "User ID not found in context", nil)
+		return
+	}
+
+	var buyer models.User
+	if err := config.DB.Where("id = ? AND role = ?", input.BuyerID, "C_LARGE").First(&buyer).Error; err != nil {
+		utils.RespondFailed(c, http.StatusBadRequest, "buyer_id must be a C_LARGE user", nil)
+		return
+	}
+
+	if input.WeightUnit == "" {
+		input.WeightUnit = models.WeightUnitKilogram
+	}
+	weight, err := models.NormalizeWeight(input.EstimatedWeight, input.WeightUnit)
+	if err != nil || weight <= 0 {
+		utils.RespondFailed(c, http.StatusBadRequest, "estimated_weight must be greater than 0 and unit one of g, kg, ton", nil)
+		return
+	}
+
+	rules, err := services.LoadMarketRules(config.DB)
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to load marketplace rules", nil)
+		return
+	}
+
+	userRole, _ := c.Get("userRole")
+	itemScale := rules.ScaleForWeight(input.Category, weight)
+	if !rules.CanPost(userRole.(string), itemScale) {
+		utils.RespondFailed(c, http.StatusBadRequest, scaleNotAllowedMessage(rules, input.Category, itemScale), nil)
+		return
+	}
+	if !rules.CanBuy(buyer.Role, itemScale) {
+		utils.RespondFailed(c, http.StatusBadRequest, "The buyer is not allowed to buy "+itemScale+" scale items", nil)
+		return
+	}
+
+	if input.PricePerKg <= 0 {
+		utils.RespondFailed(c, http.StatusBadRequest, "price_per_kg must be greater than 0", nil)
+		return
+	}
+
+	switch input.Frequency {
+	case models.ContractFrequencyWeekly, models.ContractFrequencyBiweekly:
+		if input.Weekday < 0 || input.Weekday > 6 {
+			utils.RespondFailed(c, http.StatusBadRequest, "weekday must be between 0 (Sunday) and 6 (Saturday)", nil)
+			return
+		}
+		input.DayOfMonth = 0
+	case models.ContractFrequencyMonthly:
+		if input.DayOfMonth < 1 || input.DayOfMonth > 28 {
+			utils.RespondFailed(c, http.StatusBadRequest, "day_of_month must be between 1 and 28", nil)
+			return
+		}
+		input.Weekday = 0
+	default:
+		utils.RespondFailed(c, http.StatusBadRequest, "frequency must be WEEKLY, BIWEEKLY or MONTHLY", nil)
+		return
+	}
+
+	startsOn, err := time.ParseInLocation("2006-01-02", input.StartsOn, time.Local)
+	if err != nil {
+		utils.RespondFailed(c, http.StatusBadRequest, "starts_on must be a YYYY-MM-DD date", nil)
+		return
+	}
+
+	contract := models.Contract{
+		SellerID:        userID.(uuid.UUID),
+		BuyerID:         buyer.ID,
+		Title:           input.Title,
+		Category:        input.Category,
+		Description:     input.Description,
+		EstimatedWeight: weight,
+		WeightUnit:      input.WeightUnit,
+		PricePerKg:      input.PricePerKg,
+		Frequency:       input.Frequency,
+		Weekday:         input.Weekday,
+		DayOfMonth:      input.DayOfMonth,
+		StartsOn:        startsOn,
+		Lat:             input.Lat,
+		Lon:             input.Lon,
+	}
+
+	if input.EndsOn != "" {
+		endsOn, err := time.ParseInLocation("2006-01-02", input.EndsOn, time.Local)
+		if err != nil || endsOn.Before(startsOn) {
+			utils.RespondFailed(c, http.StatusBadRequest, "ends_on must be a YYYY-MM-DD date after starts_on", nil)
+			return
+		}
+		endOfDay := endsOn.AddDate(0, 0, 1).Add(-time.Second)
+		contract.EndsOn = &endOfDay
+	}
+
+	if services.NextContractRun(contract, time.Now()) == nil {
+		utils.RespondFailed(c, http.StatusBadRequest, "The schedule has no run in the future", nil)
+		return
+	}
+
+	if err := config.DB.Create(&contract).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to create contract", nil)
+		return
+	}
+
+	config.DB.Preload("Seller").Preload("Buyer").Where("id = ?", contract.ID).First(&contract)
+	utils.RespondSuccess(c, "Contract proposed successfully", contractResponse(contract))
+}
+
+func GetContracts(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.RespondFailed(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	query := config.DB.Preload("Seller").Preload("Buyer").Where("seller_id = ? OR buyer_id = ?", userID, userID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var contracts []models.Contract
+	if err := query.Order("created_at desc").Find(&contracts).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to fetch contracts", nil)
+		return
+	}
+
+	responseItems := []map[string]interface{}{}
+	for _, contract := range contracts {
+		responseItems = append(responseItems, contractResponse(contract))
+	}
+
+	utils.RespondSuccess(c, "Contracts fetched successfully", responseItems)
+}
+
+func GetContractByID(c *gin.Context) {
+	contract, ok := findContract(c)
+	if !ok {
+		return
+	}
+
+	utils.RespondSuccess(c, "Contract fetched successfully", contractResponse(contract))
+}
+
+// GetContractOrders lists the orders a contract has generated, newest first.
+func GetContractOrders(c *gin.Context) {
+	contract, ok := findContract(c)
+	if !ok {
+		return
+	}
+
+	var orders []models.Order
+	if err := preloadOrder(config.DB).Where("contract_id = ?", contract.ID).Order("created_at desc").Find(&orders).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to fetch orders", nil)
+		return
+	}
+
+	responseItems := []map[string]interface{}{}
+	for _, order := range orders {
+		responseItems = append(responseItems, orderResponse(order))
+	}
+
+	utils.RespondSuccess(c, "Contract orders fetched successfully", responseItems)
+}
+
+// AcceptContract activates a proposed contract with the buyer's handover
+// details and schedules its first run.
+func AcceptContract(c *gin.Context) {
+	var input ContractAcceptInput
+	if err := c.ShouldBind(&input); err != nil {
+		utils.RespondFailed(c, http.StatusBadRequest, "Input tidak valid", nil)
+		return
+	}
+
+	contract, ok := findContract(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	if contract.BuyerID != userID {
+		utils.RespondFailed(c, http.StatusForbidden, "Only the buyer can accept this contract", nil)
+		return
+	}
+
+	next := services.NextContractRun(contract, time.Now())
+	if next == nil {
+		utils.RespondFailed(c, http.StatusConflict, "The schedule has no run in the future", nil)
+		return
+	}
+
+	if !moveContract(c, &contract, []string{models.ContractStatusProposed}, map[string]interface{}{
+		"Status":                    models.ContractStatusActive,
+		"NextRunAt":                 next,
+		"RecipientName":             input.RecipientName,
+		"RecipientPhone":            input.RecipientPhone,
+		"PickupLocationAddress":     input.PickupLocationAddress,
+		"PickupLocationDescription": input.PickupLocationDescription,
+		"PickupLat":                 input.PickupLat,
+		"PickupLon":                 input.PickupLon,
+	}) {
+		return
+	}
+
+	utils.RespondSuccess(c, "Contract accepted successfully", contractResponse(contract))
+}
+
+func PauseContract(c *gin.Context) {
+	contract, ok := findContract(c)
+	if !ok {
+		return
+	}
+
+	if !moveContract(c, &contract, []string{models.ContractStatusActive}, map[string]interface{}{
+		"Status":    models.ContractStatusPaused,
+		"NextRunAt": nil,
+	}) {
+		return
+	}
+
+	utils.RespondSuccess(c, "Contract paused successfully", contractResponse(contract))
+}
+
+// ResumeContract reactivates a paused contract from its next scheduled day;
+// runs that fell within the pause are skipped.
+func ResumeContract(c *gin.Context) {
+	contract, ok := findContract(c)
+	if !ok {
+		return
+	}
+
+	next := services.NextContractRun(contract, time.Now())
+	updates := map[string]interface{}{"Status": models.ContractStatusActive, "NextRunAt": next}
+	if next == nil {
+		updates["Status"] = models.ContractStatusEnded
+	}
+
+	if !moveContract(c, &contract, []string{models.ContractStatusPaused}, updates) {
+		return
+	}
+
+	utils.RespondSuccess(c, "Contract resumed successfully", contractResponse(contract))
+}
+
+// TerminateContract ends a contract for good, or declines it while it is
+// still proposed. Orders it already generated are not affected.
+func TerminateContract(c *gin.Context) {
+	var input ContractTerminateInput
+	if err := c.ShouldBind(&input); err != nil {
+		utils.RespondFailed(c, http.StatusBadRequest, "A reason is required to terminate a contract", nil)
+		return
+	}
+
+	contract, ok := findContract(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	if contract.SellerID != userID && contract.BuyerID != userID {
+		utils.RespondFailed(c, http.StatusForbidden, "Only the parties can terminate this contract", nil)
+		return
+	}
+
+	if !moveContract(c, &contract, []string{models.ContractStatusProposed, models.ContractStatusActive, models.ContractStatusPaused}, map[string]interface{}{
+		"Status":            models.ContractStatusTerminated,
+		"NextRunAt":         nil,
+		"TerminatedBy":      userID,
+		"TerminationReason": input.Reason,
+	}) {
+		return
+	}
+
+	utils.RespondSuccess(c, "Contract terminated successfully", contractResponse(contract))
+}
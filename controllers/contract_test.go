@@ -0,0 +1,88 @@
+package controllers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"recyco/config"
+	"recyco/models"
+	"recyco/services"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func contractForm(buyer models.User, weight string) url.Values {
+	return url.Values{
+		"buyer_id":         {buyer.ID.String()},
+		"title":            {"Weekly cardboard"},
+		"category":         {"paper"},
+		"estimated_weight": {weight},
+		"price_per_kg":     {"2000"},
+		"frequency":        {models.ContractFrequencyWeekly},
+		"weekday":          {"1"},
+		"starts_on":        {time.Now().AddDate(0, 0, 1).Format("2006-01-02")},
+	}
+}
+
+func TestContractScaleFollowsRules(t *testing.T) {
+	router := setupDatabase(t)
+
+	_, sellerToken := createUser(t, "P_LARGE")
+	buyer, _ := createUser(t, "C_LARGE")
+
+	// 1 kg would post SMALL items, which neither side may trade.
+	assertStatus(t, send(router, http.MethodPost, "/contracts/", sellerToken, contractForm(buyer, "1")), http.StatusBadRequest)
+	assertStatus(t, send(router, http.MethodPost, "/contracts/", sellerToken, contractForm(buyer, "100")), http.StatusOK)
+}
+
+func TestCancelledContractOrderWithdrawsItem(t *testing.T) {
+	router := setupDatabase(t)
+
+	_, sellerToken := createUser(t, "P_LARGE")
+	buyer, buyerToken := createUser(t, "C_LARGE")
+
+	recorder := send(router, http.MethodPost, "/contracts/", sellerToken, contractForm(buyer, "100"))
+	assertStatus(t, recorder, http.StatusOK)
+	var body struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode contract: %v", err)
+	}
+	assertStatus(t, send(router, http.MethodPost, "/contracts/"+body.Data.ID+"/accept", buyerToken, url.Values{
+		"recipient_name":          {"Buyer"},
+		"recipient_phone":         {"08123456789"},
+		"pickup_location_address": {"Jl. Merdeka 1"},
+	}), http.StatusOK)
+
+	var contract models.Contract
+	config.DB.Where("id = ?", body.Data.ID).First(&contract)
+	var order models.Order
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		order, err = services.RunContract(tx, contract, *contract.NextRunAt)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("run contract: %v", err)
+	}
+
+	var item models.MarketItems
+	config.DB.Where("id = ?", order.ItemID).First(&item)
+	if item.ItemScale != "LARGE" {
+		t.Fatalf("contract item is %s, want LARGE", item.ItemScale)
+	}
+
+	assertStatus(t, send(router, http.MethodPost, "/orders/"+order.ID.String()+"/cancel", buyerToken, url.Values{
+		"reason_code": {services.CancelReasonChangedMind},
+	}), http.StatusOK)
+
+	config.DB.Unscoped().Where("id = ?", order.ItemID).First(&item)
+	if item.Status != models.MarketItemStatusWithdrawn || !item.DeletedAt.Valid {
+		t.Fatalf("contract item is %s after the cancellation, want it withdrawn", item.Status)
+	}
+}
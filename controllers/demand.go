@@ -0,0 +1,702 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"recyco/config"
+	"recyco/models"
+	"recyco/services"
+	"recyco/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// weightTolerance absorbs the gram rounding of normalized weights when
+// comparing a response against what is left of a demand.
+const weightTolerance = 0.0005
+
+type DemandListingInput struct {
+	Title       string       `form:"title" binding:"required"`
+	Category    string       `form:"category" binding:"required"`
+	Description string       `form:"description"`
+	Weight      float64      `form:"weight" binding:"required"`
+	WeightUnit  string       `form:"weight_unit"`
+	PricePerKg  models.Money `form:"price_per_kg" binding:"required"`
+	Fulfillment string       `form:"fulfillment"`
+	AreaAddress string       `form:"area_address" binding:"required"`
+	Lat         *float64     `form:"lat"`
+	Lon         *float64     `form:"lon"`
+	ValidUntil  string       `form:"valid_until" binding:"required"`
+}
+
+type DemandResponseInput struct {
+	Weight     float64      `form:"weight" binding:"required"`
+	WeightUnit string       `form:"weight_unit"`
+	PricePerKg models.Money `form:"price_per_kg"`
+	Message    string       `form:"message"`
+	Lat        *float64     `form:"lat"`
+	Lon        *float64     `form:"lon"`
+}
+
+func demandListingResponse(demand models.DemandListing, reputation services.Reputation) map[string]interface{} {
+	return map[string]interface{}{
+		"id":                       demand.ID,
+		"title":                    demand.Title,
+		"category":                 demand.Category,
+		"description":              demand.Description,
+		"weight":                   demand.Weight,
+		"weight_unit":              demand.WeightUnit,
+		"display_weight":           models.WeightInUnit(demand.Weight, demand.WeightUnit),
+		"fulfilled_weight":         demand.FulfilledWeight,
+		"display_fulfilled_weight": models.WeightInUnit(demand.FulfilledWeight, demand.WeightUnit),
+		"price_per_kg":             demand.PricePerKg,
+		"currency":                 demand.Currency,
+		"scale":                    demand.ItemScale,
+		"fulfillment":              demand.Fulfillment,
+		"area_address":             demand.AreaAddress,
+		"lat":                      demand.Lat,
+		"lon":                      demand.Lon,
+		"valid_until":              demand.ValidUntil.Format(time.RFC3339),
+		"status":                   demand.Status,
+		"posted_by":                postedByResponse(demand.PostedByUser, reputation),
+		"created_at":               demand.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func demandResponseResponse(response models.DemandResponse) map[string]interface{} {
+	return map[string]interface{}{
+		"id":             response.ID,
+		"demand_id":      response.DemandID,
+		"responder":      orderUserResponse(response.Responder),
+		"weight":         response.Weight,
+		"weight_unit":    response.WeightUnit,
+		"display_weight": models.WeightInUnit(response.Weight, response.WeightUnit),
+		"price_per_kg":   response.PricePerKg,
+		"total_price":    response.PricePerKg.MulQuantity(response.Weight),
+		"message":        response.Message,
+		"lat":            response.Lat,
+		"lon":            response.Lon,
+		"thumbnail_url":  response.ThumbnailUrl,
+		"status":         response.Status,
+		"item_id":        response.ItemID,
+		"order_id":       response.OrderID,
+		"created_at":     response.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func demandPosters(demands []models.DemandListing) []uuid.UUID {
+	seen := map[uuid.UUID]bool{}
+	posters := []uuid.UUID{}
+	for _, demand := range demands {
+		if !seen[demand.PostedBy] {
+			seen[demand.PostedBy] = true
+			posters = append(posters, demand.PostedBy)
+		}
+	}
+	return posters
+}
+
+func respondDemandListings(c *gin.Context, demands []models.DemandListing) {
+	reputations, err := services.LoadReputations(config.DB, demandPosters(demands))
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to load reputation", nil)
+		return
+	}
+
+	responseItems := []map[string]interface{}{}
+	for _, demand := range demands {
+		responseItems = append(responseItems, demandListingResponse(demand, reputations[demand.PostedBy]))
+	}
+
+	utils.RespondSuccess(c, "Demand listings fetched successfully", responseItems)
+}
+
+func CreateDemandListing(c *gin.Context) {
+	var input DemandListingInput
+	if err := c.ShouldBind(&input); err != nil {
+		utils.RespondFailed(c, http.StatusBadRequest, "Input tidak valid", nil)
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.RespondFailed(c, http.StatusInternalServerError, "User ID not found in context", nil)
+		return
+	}
+
+	userRole, exists := c.Get("userRole")
+	if !exists {
+		utils.RespondFailed(c, http.StatusInternalServerError, "User role not found in context", nil)
+		return
+	}
+
+	if input.WeightUnit == "" {
+		input.WeightUnit = models.WeightUnitKilogram
+	}
+	weight, err := models.NormalizeWeight(input.Weight, input.WeightUnit)
+	if err != nil || weight <= 0 {
+		utils.RespondFailed(c, http.StatusBadRequest, "Weight must be greater than 0 and unit one of g, kg, ton", nil)
+		return
+	}
+
+	if input.PricePerKg <= 0 {
+		utils.RespondFailed(c, http.StatusBadRequest, "price_per_kg must be greater than 0", nil)
+		return
+	}
+
+	validUntil, err := time.Parse(time.RFC3339, input.ValidUntil)
+	if err != nil || !validUntil.After(time.Now()) {
+		utils.RespondFailed(c, http.StatusBadRequest, "valid_until must be a future RFC3339 time", nil)
+		return
+	}
+
+	rules, err := services.LoadMarketRules(config.DB)
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to load marketplace rules", nil)
+		return
+	}
+
+	itemScale := rules.ScaleForWeight(input.Category, weight)
+	if !rules.CanBuy(userRole.(string), itemScale) {
+		utils.RespondFailed(c, http.StatusForbidden, "You do not have permission to buy "+itemScale+" scale items", nil)
+		return
+	}
+
+	if itemScale == "LARGE" {
+		input.Fulfillment = models.FulfillmentDelivery
+	} else if input.Fulfillment != models.FulfillmentSelfPickup && input.Fulfillment != models.FulfillmentDropOff {
+		utils.RespondFailed(c, http.StatusBadRequest, "Fulfillment must be SELF_PICKUP or DROP_OFF", nil)
+		return
+	}
+
+	demand := models.DemandListing{
+		Title:       input.Title,
+		Category:    input.Category,
+		Description: input.Description,
+		Weight:      weight,
+		WeightUnit:  input.WeightUnit,
+		PricePerKg:  input.PricePerKg,
+		ItemScale:   itemScale,
+		Fulfillment: input.Fulfillment,
+		AreaAddress: input.AreaAddress,
+		Lat:         input.Lat,
+		Lon:         input.Lon,
+		ValidUntil:  validUntil,
+		PostedBy:    userID.(uuid.UUID),
+	}
+
+	if err := config.DB.Create(&demand).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to create demand listing", nil)
+		return
+	}
+
+	config.DB.Where("id = ?", demand.PostedBy).First(&demand.PostedByUser)
+	utils.RespondSuccess(c, "Demand listing created successfully", demandListingResponse(demand, services.Reputation{}))
+}
+
+// GetDemandListings lets producers browse open demand listings of the scales
+// they are allowed to supply.
+func GetDemandListings(c *gin.Context) {
+	userRole, exists := c.Get("userRole")
+	if !exists {
+		utils.RespondFailed(c, http.StatusInternalServerError, "User role not found in context", nil)
+		return
+	}
+
+	rules, err := services.LoadMarketRules(config.DB)
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to load marketplace rules", nil)
+		return
+	}
+
+	query := config.DB.Preload("PostedByUser").
+		Where("status = ? AND valid_until > ?", models.DemandStatusOpen, time.Now())
+
+	if userRole != "ADMIN" {
+		query = query.Where("item_scale IN ?", rules.PostableScales(userRole.(string)))
+	}
+	if scale := c.Query("scale"); scale != "" {
+		query = query.Where("item_scale = ?", scale)
+	}
+	if category := c.Query("category"); category != "" {
+		query = query.Where("category = ?", category)
+	}
+
+	var demands []models.DemandListing
+	if err := query.Order("valid_until asc").Find(&demands).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to fetch demand listings", nil)
+		return
+	}
+
+	respondDemandListings(c, demands)
+}
+
+func GetUserDemandListings(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.RespondFailed(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	query := config.DB.Preload("PostedByUser").Where("posted_by = ?", userID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var demands []models.DemandListing
+	if err := query.Order("created_at desc").Find(&demands).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to fetch demand listings", nil)
+		return
+	}
+
+	respondDemandListings(c, demands)
+}
+
+// findVisibleDemand loads the demand listing in the URL if the user posted it,
+// may supply its scale, or is an admin. It writes the error response itself.
+func findVisibleDemand(c *gin.Context) (models.DemandListing, bool) {
+	var demand models.DemandListing
+	if err := config.DB.Preload("PostedByUser").Where("id = ?", c.Param("id")).First(&demand).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Demand listing not found", nil)
+		return demand, false
+	}
+
+	userID, _ := c.Get("userID")
+	userRole, _ := c.Get("userRole")
+	if demand.PostedBy == userID || userRole == "ADMIN" {
+		return demand, true
+	}
+
+	rules, err := services.LoadMarketRules(config.DB)
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to load marketplace rules", nil)
+		return demand, false
+	}
+	if role, ok := userRole.(string); !ok || !rules.CanPost(role, demand.ItemScale) {
+		utils.RespondFailed(c, http.StatusForbidden, "You do not have permission to access this resource", nil)
+		return demand, false
+	}
+
+	return demand, true
+}
+
+// demandResponseScale is the scale of the item a response of weight to
+// demand would become. It writes the error response itself when the
+// responder may not post items of that scale or the buyer may not buy them.
+func demandResponseScale(c *gin.Context, demand models.DemandListing, responderRole string, weight float64) (string, bool) {
+	rules, err := services.LoadMarketRules(config.DB)
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to load marketplace rules", nil)
+		return "", false
+	}
+
+	scale := rules.ScaleForWeight(demand.Category, weight)
+	if !rules.CanPost(responderRole, scale) {
+		utils.RespondFailed(c, http.StatusBadRequest, "The producer is not allowed to post "+scale+" scale items", nil)
+		return scale, false
+	}
+	if !rules.CanBuy(demand.PostedByUser.Role, scale) {
+		utils.RespondFailed(c, http.StatusBadRequest, "The buyer is not allowed to buy "+scale+" scale items", nil)
+		return scale, false
+	}
+	return scale, true
+}
+
+func GetDemandListingByID(c *gin.Context) {
+	demand, ok := findVisibleDemand(c)
+	if !ok {
+		return
+	}
+
+	reputations, err := services.LoadReputations(config.DB, []uuid.UUID{demand.PostedBy})
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to load reputation", nil)
+		return
+	}
+
+	utils.RespondSuccess(c, "Demand listing fetched successfully", demandListingResponse(demand, reputations[demand.PostedBy]))
+}
+
+// CloseDemandListing stops a demand listing from taking more responses and
+// rejects the ones still pending.
+func CloseDemandListing(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var demand models.DemandListing
+	if err := config.DB.Preload("PostedByUser").Where("id = ?", c.Param("id")).First(&demand).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Demand listing not found", nil)
+		return
+	}
+
+	if demand.PostedBy != userID {
+		utils.RespondFailed(c, http.StatusForbidden, "You do not have permission to close this demand listing", nil)
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.DemandListing{}).
+			Where("id = ? AND status = ?", demand.ID, models.DemandStatusOpen).
+			Updates(map[string]interface{}{"Status": models.DemandStatusClosed, "UpdatedAt": time.Now()})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return services.ErrTransactionConflict
+		}
+
+		return rejectPendingDemandResponses(tx, demand.ID)
+	})
+	if errors.Is(err, services.ErrTransactionConflict) {
+		utils.RespondFailed(c, http.StatusConflict, "Demand listing is already "+demand.Status, nil)
+		return
+	}
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to close demand listing", nil)
+		return
+	}
+
+	demand.Status = models.DemandStatusClosed
+	utils.RespondSuccess(c, "Demand listing closed successfully", demandListingResponse(demand, services.Reputation{}))
+}
+
+func rejectPendingDemandResponses(tx *gorm.DB, demandID uuid.UUID) error {
+	return tx.Model(&models.DemandResponse{}).
+		Where("demand_id = ? AND status = ?", demandID, models.DemandResponseStatusPending).
+		Updates(map[string]interface{}{"Status": models.DemandResponseStatusRejected, "UpdatedAt": time.Now()}).Error
+}
+
+// CreateDemandResponse lets a producer offer to supply part or all of what is
+// left of a demand listing. The price defaults to the one the buyer asked.
+func CreateDemandResponse(c *gin.Context) {
+	var input DemandResponseInput
+	if err := c.ShouldBind(&input); err != nil {
+		utils.RespondFailed(c, http.StatusBadRequest, "Input tidak valid", nil)
+		return
+	}
+
+	userID, _ := c.Get("userID")
+
+	demand, ok := findVisibleDemand(c)
+	if !ok {
+		return
+	}
+
+	if demand.PostedBy == userID {
+		utils.RespondFailed(c, http.StatusBadRequest, "You cannot respond to your own demand listing", nil)
+		return
+	}
+
+	userRole, _ := c.Get("userRole")
+	if userRole == "ADMIN" {
+		utils.RespondFailed(c, http.StatusForbidden, "You do not have permission to respond to this demand listing", nil)
+		return
+	}
+
+	if demand.Status != models.DemandStatusOpen || !demand.ValidUntil.After(time.Now()) {
+		utils.RespondFailed(c, http.StatusConflict, "Demand listing is no longer open", nil)
+		return
+	}
+
+	if input.WeightUnit == "" {
+		input.WeightUnit = models.WeightUnitKilogram
+	}
+	weight, err := models.NormalizeWeight(input.Weight, input.WeightUnit)
+	if err != nil || weight <= 0 {
+		utils.RespondFailed(c, http.StatusBadRequest, "Weight must be greater than 0 and unit one of g, kg, ton", nil)
+		return
+	}
+
+	if weight > demand.Weight-demand.FulfilledWeight+weightTolerance {
+		utils.RespondFailed(c, http.StatusBadRequest, "Weight exceeds what is left of the demand listing", nil)
+		return
+	}
+
+	if _, ok := demandResponseScale(c, demand, userRole.(string), weight); !ok {
+		return
+	}
+
+	if input.PricePerKg == 0 {
+		input.PricePerKg = demand.PricePerKg
+	}
+	if input.PricePerKg < 0 {
+		utils.RespondFailed(c, http.StatusBadRequest, "price_per_kg must be greater than 0", nil)
+		return
+	}
+
+	var pending int64
+	if err := config.DB.Model(&models.DemandResponse{}).
+		Where("demand_id = ? AND responder_id = ? AND status = ?", demand.ID, userID, models.DemandResponseStatusPending).
+		Count(&pending).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to create response", nil)
+		return
+	}
+	if pending > 0 {
+		utils.RespondFailed(c, http.StatusConflict, "You already have a pending response to this demand listing", nil)
+		return
+	}
+
+	response := models.DemandResponse{
+		DemandID:    demand.ID,
+		ResponderID: userID.(uuid.UUID),
+		Weight:      weight,
+		WeightUnit:  input.WeightUnit,
+		PricePerKg:  input.PricePerKg,
+		Message:     input.Message,
+		Lat:         input.Lat,
+		Lon:         input.Lon,
+	}
+
+	if file, err := c.FormFile("thumbnail"); err == nil {
+		filename := uuid.New().String() + filepath.Ext(file.Filename)
+		if err := c.SaveUploadedFile(file, "uploads/markets/"+filename); err != nil {
+			utils.RespondFailed(c, http.StatusInternalServerError, "Failed to save file", nil)
+			return
+		}
+		response.ThumbnailUrl = "/uploads/markets/" + filename
+	}
+
+	if err := config.DB.Create(&response).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to create response", nil)
+		return
+	}
+
+	config.DB.Where("id = ?", response.ResponderID).First(&response.Responder)
+	utils.RespondSuccess(c, "Response created successfully", demandResponseResponse(response))
+}
+
+// GetDemandResponses lists every response to the buyer who posted the demand
+// listing, and only their own responses to producers.
+func GetDemandResponses(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	demand, ok := findVisibleDemand(c)
+	if !ok {
+		return
+	}
+
+	query := config.DB.Preload("Responder").Where("demand_id = ?", demand.ID)
+	if demand.PostedBy != userID {
+		query = query.Where("responder_id = ?", userID)
+	}
+
+	var responses []models.DemandResponse
+	if err := query.Order("created_at asc").Find(&responses).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to fetch responses", nil)
+		return
+	}
+
+	responseItems := []map[string]interface{}{}
+	for _, response := range responses {
+		responseItems = append(responseItems, demandResponseResponse(response))
+	}
+
+	utils.RespondSuccess(c, "Responses fetched successfully", responseItems)
+}
+
+func findPendingDemandResponse(c *gin.Context) (models.DemandListing, models.DemandResponse, bool) {
+	var demand models.DemandListing
+	var response models.DemandResponse
+
+	if err := config.DB.Preload("PostedByUser").Where("id = ?", c.Param("id")).First(&demand).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Demand listing not found", nil)
+		return demand, response, false
+	}
+
+	if err := config.DB.Preload("Responder").
+		Where("id = ? AND demand_id = ?", c.Param("responseId"), demand.ID).
+		First(&response).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Response not found", nil)
+		return demand, response, false
+	}
+
+	if response.Status != models.DemandResponseStatusPending {
+		utils.RespondFailed(c, http.StatusConflict, "Response is already "+response.Status, nil)
+		return demand, response, false
+	}
+
+	return demand, response, true
+}
+
+func moveDemandResponse(tx *gorm.DB, response models.DemandResponse, status string) error {
+	result := tx.Model(&models.DemandResponse{}).
+		Where("id = ? AND status = ?", response.ID, models.DemandResponseStatusPending).
+		Updates(map[string]interface{}{"Status": status, "UpdatedAt": time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return services.ErrTransactionConflict
+	}
+	return nil
+}
+
+// AcceptDemandResponse turns a producer's response into a market item posted
+// by the producer and an order of it by the buyer, at the responded price and
+// with the demand listing's area as the handover location. The item's scale
+// follows from the response's weight and must still be one the producer may
+// post and the buyer may buy. The accepted weight is counted against the
+// demand listing, which becomes FULFILLED once the wanted weight is reached;
+// cancelling the order gives it back.
+func AcceptDemandResponse(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	demand, response, ok := findPendingDemandResponse(c)
+	if !ok {
+		return
+	}
+
+	if demand.PostedBy != userID {
+		utils.RespondFailed(c, http.StatusForbidden, "Only the buyer who posted the demand listing can accept responses", nil)
+		return
+	}
+
+	itemScale, ok := demandResponseScale(c, demand, response.Responder.Role, response.Weight)
+	if !ok {
+		return
+	}
+
+	var order models.Order
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := moveDemandResponse(tx, response, models.DemandResponseStatusAccepted); err != nil {
+			return err
+		}
+
+		result := tx.Model(&models.DemandListing{}).
+			Where("id = ? AND status = ? AND valid_until > ? AND fulfilled_weight + ? <= weight + ?",
+				demand.ID, models.DemandStatusOpen, time.Now(), response.Weight, weightTolerance).
+			Updates(map[string]interface{}{
+				"FulfilledWeight": gorm.Expr("fulfilled_weight + ?", response.Weight),
+				"UpdatedAt":       time.Now(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return services.ErrTransactionConflict
+		}
+
+		// The weight fulfilled so far is compared in the database, after the
+		// increment, since demand was read before the transaction began.
+		result = tx.Model(&models.DemandListing{}).
+			Where("id = ? AND status = ? AND fulfilled_weight >= weight - ?", demand.ID, models.DemandStatusOpen, weightTolerance).
+			Update("status", models.DemandStatusFulfilled)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			if err := rejectPendingDemandResponses(tx, demand.ID); err != nil {
+				return err
+			}
+		}
+
+		marketItem := models.MarketItems{
+			Name:         demand.Title,
+			Price:        response.PricePerKg.MulQuantity(response.Weight),
+			PricePerKg:   response.PricePerKg,
+			Currency:     demand.Currency,
+			Weight:       response.Weight,
+			WeightUnit:   response.WeightUnit,
+			ItemScale:    itemScale,
+			Status:       models.MarketItemStatusReady,
+			Category:     demand.Category,
+			Description:  response.Message,
+			Lat:          response.Lat,
+			Lon:          response.Lon,
+			ThumbnailUrl: response.ThumbnailUrl,
+			PostedBy:     response.ResponderID,
+		}
+		if err := tx.Create(&marketItem).Error; err != nil {
+			return err
+		}
+
+		pickupInformation := models.MarketItemPickupInformations{
+			RecipientName:         demand.PostedByUser.Name,
+			RecipientPhone:        demand.PostedByUser.PhoneNumber,
+			Description:           "Demand listing: " + demand.Title,
+			PickupLocationAddress: demand.AreaAddress,
+			PickupLat:             demand.Lat,
+			PickupLon:             demand.Lon,
+		}
+
+		var err error
+		order, _, err = services.PlaceOrder(tx, marketItem, demand.PostedBy, &pickupInformation, marketItem.Price, demand.Fulfillment)
+		if err != nil {
+			return err
+		}
+
+		response.ItemID = &marketItem.ID
+		response.OrderID = &order.ID
+		return tx.Model(&models.DemandResponse{}).Where("id = ?", response.ID).
+			Updates(map[string]interface{}{"ItemID": marketItem.ID, "OrderID": order.ID}).Error
+	})
+	if errors.Is(err, services.ErrTransactionConflict) {
+		utils.RespondFailed(c, http.StatusConflict, "Demand listing was changed by another request or cannot take this weight", nil)
+		return
+	}
+	if err != nil {
+		respondTransitionError(c, err)
+		return
+	}
+
+	if err := preloadOrder(config.DB).Where("id = ?", order.ID).First(&order).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to fetch order", nil)
+		return
+	}
+
+	response.Status = models.DemandResponseStatusAccepted
+	utils.RespondSuccess(c, "Response accepted successfully", gin.H{
+		"response": demandResponseResponse(response),
+		"order":    orderResponse(order),
+	})
+}
+
+func RejectDemandResponse(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	demand, response, ok := findPendingDemandResponse(c)
+	if !ok {
+		return
+	}
+
+	if demand.PostedBy != userID {
+		utils.RespondFailed(c, http.StatusForbidden, "Only the buyer who posted the demand listing can reject responses", nil)
+		return
+	}
+
+	finishDemandResponse(c, response, models.DemandResponseStatusRejected, "Response rejected successfully")
+}
+
+func WithdrawDemandResponse(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	_, response, ok := findPendingDemandResponse(c)
+	if !ok {
+		return
+	}
+
+	if response.ResponderID != userID {
+		utils.RespondFailed(c, http.StatusForbidden, "Only the producer who responded can withdraw the response", nil)
+		return
+	}
+
+	finishDemandResponse(c, response, models.DemandResponseStatusWithdrawn, "Response withdrawn successfully")
+}
+
+func finishDemandResponse(c *gin.Context, response models.DemandResponse, status string, message string) {
+	err := moveDemandResponse(config.DB, response, status)
+	if errors.Is(err, services.ErrTransactionConflict) {
+		utils.RespondFailed(c, http.StatusConflict, "Response was changed by another request", nil)
+		return
+	}
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to update response", nil)
+		return
+	}
+
+	response.Status = status
+	utils.RespondSuccess(c, message, demandResponseResponse(response))
+}
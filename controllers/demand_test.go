@@ -0,0 +1,127 @@
+package controllers_test
+
+import (
+	"net/http"
+	"net/url"
+	"recyco/config"
+	"recyco/models"
+	"recyco/services"
+	"sync"
+	"testing"
+	"time"
+)
+
+// createDemand stores an open LARGE demand listing of buyer for 100 kg.
+func createDemand(t *testing.T, buyer models.User) models.DemandListing {
+	t.Helper()
+
+	demand := models.DemandListing{
+		Title:       "Cardboard",
+		Category:    "paper",
+		Weight:      100,
+		PricePerKg:  models.NewMoney(2000),
+		ItemScale:   "LARGE",
+		AreaAddress: "Jl. Merdeka 1",
+		ValidUntil:  time.Now().Add(24 * time.Hour),
+		PostedBy:    buyer.ID,
+	}
+	if err := config.DB.Create(&demand).Error; err != nil {
+		t.Fatalf("create demand: %v", err)
+	}
+	return demand
+}
+
+func TestConcurrentAcceptsFulfillDemand(t *testing.T) {
+	router := setupDatabase(t)
+
+	buyer, buyerToken := createUser(t, "C_LARGE")
+	demand := createDemand(t, buyer)
+
+	// Two halves accepted at once together fulfil the demand, even though
+	// each request read it while it was still empty.
+	responses := make([]models.DemandResponse, 2)
+	for i := range responses {
+		producer, _ := createUser(t, "P_LARGE")
+		responses[i] = models.DemandResponse{
+			DemandID:    demand.ID,
+			ResponderID: producer.ID,
+			Weight:      50,
+			PricePerKg:  demand.PricePerKg,
+		}
+		if err := config.DB.Create(&responses[i]).Error; err != nil {
+			t.Fatalf("create response: %v", err)
+		}
+	}
+
+	codes := make([]int, len(responses))
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i, response := range responses {
+		wg.Add(1)
+		go func(i int, path string) {
+			defer wg.Done()
+			<-start
+			codes[i] = send(router, http.MethodPost, path, buyerToken, nil).Code
+		}(i, "/demands/"+demand.ID.String()+"/responses/"+response.ID.String()+"/accept")
+	}
+	close(start)
+	wg.Wait()
+
+	for _, code := range codes {
+		if code != http.StatusOK {
+			t.Fatalf("responses = %v, want both %d", codes, http.StatusOK)
+		}
+	}
+
+	config.DB.Where("id = ?", demand.ID).First(&demand)
+	if demand.Status != models.DemandStatusFulfilled || demand.FulfilledWeight != 100 {
+		t.Fatalf("demand is %s with %v kg, want %s with 100 kg", demand.Status, demand.FulfilledWeight, models.DemandStatusFulfilled)
+	}
+}
+
+func TestDemandResponseScaleFollowsRules(t *testing.T) {
+	router := setupDatabase(t)
+
+	buyer, _ := createUser(t, "C_LARGE")
+	demand := createDemand(t, buyer)
+	_, producerToken := createUser(t, "P_LARGE")
+
+	// 5 kg makes a SMALL item, which neither side may trade.
+	path := "/demands/" + demand.ID.String() + "/responses"
+	assertStatus(t, send(router, http.MethodPost, path, producerToken, url.Values{"weight": {"5"}}), http.StatusBadRequest)
+	assertStatus(t, send(router, http.MethodPost, path, producerToken, url.Values{"weight": {"50"}}), http.StatusOK)
+}
+
+func TestCancelledDemandOrderReopensDemand(t *testing.T) {
+	router := setupDatabase(t)
+
+	buyer, buyerToken := createUser(t, "C_LARGE")
+	demand := createDemand(t, buyer)
+	producer, _ := createUser(t, "P_LARGE")
+	response := models.DemandResponse{
+		DemandID:    demand.ID,
+		ResponderID: producer.ID,
+		Weight:      demand.Weight,
+		PricePerKg:  demand.PricePerKg,
+	}
+	if err := config.DB.Create(&response).Error; err != nil {
+		t.Fatalf("create response: %v", err)
+	}
+
+	assertStatus(t, send(router, http.MethodPost, "/demands/"+demand.ID.String()+"/responses/"+response.ID.String()+"/accept", buyerToken, nil), http.StatusOK)
+	config.DB.Where("id = ?", response.ID).First(&response)
+	assertStatus(t, send(router, http.MethodPost, "/orders/"+response.OrderID.String()+"/cancel", buyerToken, url.Values{
+		"reason_code": {services.CancelReasonChangedMind},
+	}), http.StatusOK)
+
+	config.DB.Where("id = ?", demand.ID).First(&demand)
+	if demand.Status != models.DemandStatusOpen || demand.FulfilledWeight != 0 {
+		t.Fatalf("demand is %s with %v kg after the cancellation, want %s with 0 kg", demand.Status, demand.FulfilledWeight, models.DemandStatusOpen)
+	}
+
+	var item models.MarketItems
+	config.DB.Unscoped().Where("id = ?", response.ItemID).First(&item)
+	if item.Status != models.MarketItemStatusWithdrawn || !item.DeletedAt.Valid {
+		t.Fatalf("item made for the buyer is %s, want it withdrawn", item.Status)
+	}
+}
@@ -0,0 +1,355 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"recyco/config"
+	"recyco/models"
+	"recyco/services"
+	"recyco/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const maxEvidencePhotos = 5
+
+type DisputeInput struct {
+	Reason      string `form:"reason" binding:"required"`
+	Description string `form:"description" binding:"required"`
+}
+
+type DisputeMessageInput struct {
+	Body string `form:"body" binding:"required"`
+}
+
+type DisputeResolutionInput struct {
+	Resolution   string       `form:"resolution" binding:"required"`
+	RefundAmount models.Money `form:"refund_amount"`
+	Note         string       `form:"note"`
+}
+
+func preloadDispute(db *gorm.DB) *gorm.DB {
+	return db.Preload("OpenedBy").
+		Preload("Photos", func(db *gorm.DB) *gorm.DB { return db.Where("message_id IS NULL") }).
+		Preload("Messages", func(db *gorm.DB) *gorm.DB { return db.Order("created_at asc") }).
+		Preload("Messages.Author").
+		Preload("Messages.Photos")
+}
+
+func evidencePhotoUrls(photos []models.DisputeEvidencePhoto) []string {
+	urls := []string{}
+	for _, photo := range photos {
+		urls = append(urls, photo.Url)
+	}
+	return urls
+}
+
+func disputeMessageResponse(message models.DisputeMessage) map[string]interface{} {
+	return map[string]interface{}{
+		"id":           message.ID,
+		"author":       actorResponse(&message.Author),
+		"author_party": message.AuthorParty,
+		"body":         message.Body,
+		"photos":       evidencePhotoUrls(message.Photos),
+		"created_at":   message.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func disputeResponse(dispute models.Dispute) map[string]interface{} {
+	messages := []map[string]interface{}{}
+	for _, message := range dispute.Messages {
+		messages = append(messages, disputeMessageResponse(message))
+	}
+
+	return map[string]interface{}{
+		"id":              dispute.ID,
+		"order_id":        dispute.OrderID,
+		"opened_by":       actorResponse(&dispute.OpenedBy),
+		"opened_by_party": dispute.OpenedByParty,
+		"reason":          dispute.Reason,
+		"description":     dispute.Description,
+		"photos":          evidencePhotoUrls(dispute.Photos),
+		"status":          dispute.Status,
+		"resolution":      dispute.Resolution,
+		"refund_amount":   dispute.RefundAmount,
+		"resolution_note": dispute.ResolutionNote,
+		"at_fault_id":     dispute.AtFaultID,
+		"resolved_by_id":  dispute.ResolvedByID,
+		"resolved_at":     dispute.ResolvedAt,
+		"messages":        messages,
+		"created_at":      dispute.CreatedAt.Format(time.RFC3339),
+		"updated_at":      dispute.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// saveEvidencePhotos stores up to five photos files of the request under
+// uploads/disputes and writes the error response itself when that fails.
+func saveEvidencePhotos(c *gin.Context) ([]string, bool) {
+	urls := []string{}
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		return urls, true
+	}
+	files := form.File["photos"]
+	if len(files) > maxEvidencePhotos {
+		utils.RespondFailed(c, http.StatusBadRequest, "At most 5 photos can be attached", nil)
+		return urls, false
+	}
+
+	for _, file := range files {
+		filename := uuid.New().String() + filepath.Ext(file.Filename)
+		if err := c.SaveUploadedFile(file, "uploads/disputes/"+filename); err != nil {
+			removeUploads(urls)
+			utils.RespondFailed(c, http.StatusInternalServerError, "Failed to save file", nil)
+			return urls, false
+		}
+		urls = append(urls, "/uploads/disputes/"+filename)
+	}
+	return urls, true
+}
+
+// findDispute loads the dispute of the request with its order and checks that
+// the user is one of the order's parties or an admin.
+func findDispute(c *gin.Context) (models.Dispute, models.Order, bool) {
+	var dispute models.Dispute
+	var order models.Order
+
+	if err := preloadDispute(config.DB).Where("id = ?", c.Param("id")).First(&dispute).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Dispute not found", nil)
+		return dispute, order, false
+	}
+
+	if err := config.DB.Where("id = ?", dispute.OrderID).First(&order).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Order not found", nil)
+		return dispute, order, false
+	}
+
+	userID, _ := c.Get("userID")
+	userRole, _ := c.Get("userRole")
+	if !isOrderParty(order, userID) && userRole != "ADMIN" {
+		utils.RespondFailed(c, http.StatusForbidden, "You do not have permission to access this resource", nil)
+		return dispute, order, false
+	}
+
+	return dispute, order, true
+}
+
+func respondDisputeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrDisputeNotAllowed):
+		utils.RespondFailed(c, http.StatusConflict, "Only orders on delivery or finished within the last 14 days can be disputed", nil)
+	case errors.Is(err, services.ErrDisputeAlreadyOpen):
+		utils.RespondFailed(c, http.StatusConflict, "This order already has an open dispute", nil)
+	case errors.Is(err, services.ErrDisputeClosed):
+		utils.RespondFailed(c, http.StatusConflict, "Dispute is already resolved", nil)
+	case errors.Is(err, services.ErrDisputeRefund):
+		utils.RespondFailed(c, http.StatusBadRequest, "Refund amount must be greater than 0 and within the order total", nil)
+	case errors.Is(err, services.ErrDisputeResolution):
+		utils.RespondFailed(c, http.StatusBadRequest, "Resolution must be PARTIAL_REFUND, CANCELLED or UPHELD", nil)
+	case errors.Is(err, services.ErrDisputeNoCounterparty):
+		utils.RespondFailed(c, http.StatusConflict, "The other party of this order is unknown", nil)
+	default:
+		respondTransitionError(c, err)
+	}
+}
+
+// OpenOrderDispute lets the buyer or the seller of an order complain about it
+// with a reason, a description and evidence photos.
+func OpenOrderDispute(c *gin.Context) {
+	var input DisputeInput
+	if err := c.ShouldBind(&input); err != nil {
+		utils.RespondFailed(c, http.StatusBadRequest, "Input tidak valid", nil)
+		return
+	}
+
+	input.Reason = strings.ToUpper(input.Reason)
+	if !models.IsValidDisputeReason(input.Reason) {
+		utils.RespondFailed(c, http.StatusBadRequest, "Reason must be one of "+strings.Join(models.DisputeReasons, ", "), nil)
+		return
+	}
+
+	userID, _ := c.Get("userID")
+
+	var order models.Order
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&order).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Order not found", nil)
+		return
+	}
+
+	party := orderParty(order, userID)
+	if party == "" {
+		utils.RespondFailed(c, http.StatusForbidden, "You do not have permission to access this resource", nil)
+		return
+	}
+
+	photoURLs, ok := saveEvidencePhotos(c)
+	if !ok {
+		return
+	}
+
+	dispute := models.Dispute{
+		OpenedByID:    userID.(uuid.UUID),
+		OpenedByParty: party,
+		Reason:        input.Reason,
+		Description:   input.Description,
+	}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		return services.OpenDispute(tx, order, &dispute, photoURLs, time.Now())
+	})
+	if err != nil {
+		removeUploads(photoURLs)
+		respondDisputeError(c, err)
+		return
+	}
+
+	preloadDispute(config.DB).Where("id = ?", dispute.ID).First(&dispute)
+	utils.RespondSuccess(c, "Dispute opened successfully", disputeResponse(dispute))
+}
+
+// GetOrderDisputes lists the disputes of an order, oldest first.
+func GetOrderDisputes(c *gin.Context) {
+	var order models.Order
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&order).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Order not found", nil)
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	userRole, _ := c.Get("userRole")
+	if !isOrderParty(order, userID) && userRole != "ADMIN" {
+		utils.RespondFailed(c, http.StatusForbidden, "You do not have permission to access this resource", nil)
+		return
+	}
+
+	var disputes []models.Dispute
+	if err := preloadDispute(config.DB).Where("order_id = ?", order.ID).Order("created_at asc").Find(&disputes).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to fetch disputes", nil)
+		return
+	}
+
+	responseItems := []map[string]interface{}{}
+	for _, dispute := range disputes {
+		responseItems = append(responseItems, disputeResponse(dispute))
+	}
+
+	utils.RespondSuccess(c, "Disputes fetched successfully", responseItems)
+}
+
+// GetDisputes lists disputes newest first: all of them for admins, otherwise
+// those on the user's own orders. ?status= filters by OPEN or RESOLVED.
+func GetDisputes(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	userRole, _ := c.Get("userRole")
+
+	query := preloadDispute(config.DB)
+	if userRole != "ADMIN" {
+		query = query.Where("order_id IN (?)",
+			config.DB.Model(&models.Order{}).Select("id").Where("buyer_id = ? OR seller_id = ?", userID, userID))
+	}
+
+	if status := c.Query("status"); status != "" {
+		if status != models.DisputeStatusOpen && status != models.DisputeStatusResolved {
+			utils.RespondFailed(c, http.StatusBadRequest, "Invalid status value", nil)
+			return
+		}
+		query = query.Where("status = ?", status)
+	}
+
+	var disputes []models.Dispute
+	if err := query.Order("created_at desc").Find(&disputes).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to fetch disputes", nil)
+		return
+	}
+
+	responseItems := []map[string]interface{}{}
+	for _, dispute := range disputes {
+		responseItems = append(responseItems, disputeResponse(dispute))
+	}
+
+	utils.RespondSuccess(c, "Disputes fetched successfully", responseItems)
+}
+
+func GetDisputeByID(c *gin.Context) {
+	dispute, _, ok := findDispute(c)
+	if !ok {
+		return
+	}
+
+	utils.RespondSuccess(c, "Dispute fetched successfully", disputeResponse(dispute))
+}
+
+// CreateDisputeMessage adds a message, optionally with photos, to the thread
+// of an open dispute. Admins write as ADMIN, the order parties as themselves.
+func CreateDisputeMessage(c *gin.Context) {
+	var input DisputeMessageInput
+	if err := c.ShouldBind(&input); err != nil {
+		utils.RespondFailed(c, http.StatusBadRequest, "Input tidak valid", nil)
+		return
+	}
+
+	dispute, order, ok := findDispute(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	party := orderParty(order, userID)
+	if party == "" {
+		party = services.PartyAdmin
+	}
+
+	photoURLs, ok := saveEvidencePhotos(c)
+	if !ok {
+		return
+	}
+
+	message := models.DisputeMessage{
+		AuthorID:    userID.(uuid.UUID),
+		AuthorParty: party,
+		Body:        input.Body,
+	}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		return services.AddDisputeMessage(tx, dispute, order, &message, photoURLs)
+	})
+	if err != nil {
+		removeUploads(photoURLs)
+		respondDisputeError(c, err)
+		return
+	}
+
+	config.DB.Where("id = ?", message.AuthorID).First(&message.Author)
+	utils.RespondSuccess(c, "Message sent successfully", disputeMessageResponse(message))
+}
+
+// ResolveOrderDispute lets an admin settle an open dispute with a partial
+// refund, by cancelling the order or by upholding it.
+func ResolveOrderDispute(c *gin.Context) {
+	var input DisputeResolutionInput
+	if err := c.ShouldBind(&input); err != nil {
+		utils.RespondFailed(c, http.StatusBadRequest, "Input tidak valid", nil)
+		return
+	}
+
+	dispute, order, ok := findDispute(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		return services.ResolveDispute(tx, &dispute, &order, strings.ToUpper(input.Resolution), input.RefundAmount, input.Note, userID.(uuid.UUID), time.Now())
+	})
+	if err != nil {
+		respondDisputeError(c, err)
+		return
+	}
+
+	sendOrderRefunds(order.ID)
+	utils.RespondSuccess(c, "Dispute resolved successfully", disputeResponse(dispute))
+}
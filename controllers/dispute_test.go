@@ -0,0 +1,46 @@
+package controllers_test
+
+import (
+	"net/http"
+	"net/url"
+	"recyco/config"
+	"recyco/models"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentDisputesOpenOnce(t *testing.T) {
+	router := setupDatabase(t)
+	order, sellerToken := deliverOrder(t, router)
+
+	codes := make([]int, 5)
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			codes[i] = send(router, http.MethodPost, "/orders/"+order.ID.String()+"/disputes", sellerToken, url.Values{
+				"reason":      {models.DisputeReasonNonPayment},
+				"description": {"The buyer has not paid"},
+			}).Code
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	counts := map[int]int{}
+	for _, code := range codes {
+		counts[code]++
+	}
+	if counts[http.StatusOK] != 1 || counts[http.StatusConflict] != len(codes)-1 {
+		t.Fatalf("responses = %v, want one %d and the rest %d", codes, http.StatusOK, http.StatusConflict)
+	}
+
+	var open int64
+	config.DB.Model(&models.Dispute{}).Where("order_id = ? AND status = ?", order.ID, models.DisputeStatusOpen).Count(&open)
+	if open != 1 {
+		t.Fatalf("order has %d open disputes, want 1", open)
+	}
+}
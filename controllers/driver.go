@@ -0,0 +1,168 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"recyco/config"
+	"recyco/models"
+	"recyco/services"
+	"recyco/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type DriverAssignmentInput struct {
+	DriverID string `form:"driver_id" binding:"required"`
+}
+
+// AssignOrderDriver lets the seller of a delivery order, or an admin, assign
+// it to a driver before it is picked up.
+func AssignOrderDriver(c *gin.Context) {
+	var input DriverAssignmentInput
+	if err := c.ShouldBind(&input); err != nil {
+		utils.RespondFailed(c, http.StatusBadRequest, "Input tidak valid", nil)
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	userRole, _ := c.Get("userRole")
+
+	var order models.Order
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&order).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Order not found", nil)
+		return
+	}
+
+	if order.SellerID != userID && userRole != "ADMIN" {
+		utils.RespondFailed(c, http.StatusForbidden, "You do not have permission to access this resource", nil)
+		return
+	}
+
+	if order.Fulfillment != models.FulfillmentDelivery {
+		utils.RespondFailed(c, http.StatusBadRequest, "Only DELIVERY orders can be assigned to a driver", nil)
+		return
+	}
+
+	var driver models.User
+	if err := config.DB.Where("id = ? AND role = ?", input.DriverID, "DRIVER").First(&driver).Error; err != nil {
+		utils.RespondFailed(c, http.StatusBadRequest, "driver_id must be a DRIVER user", nil)
+		return
+	}
+
+	tx := config.DB.Begin()
+	if tx.Error != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to start transaction", nil)
+		return
+	}
+
+	if err := services.AssignDriver(tx, &order, driver.ID); err != nil {
+		tx.Rollback()
+		if errors.Is(err, services.ErrTransactionConflict) {
+			utils.RespondFailed(c, http.StatusConflict, "Only orders waiting for pickup can be assigned", nil)
+			return
+		}
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to assign driver", nil)
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to commit transaction", nil)
+		return
+	}
+
+	if err := preloadOrder(config.DB).Where("id = ?", order.ID).First(&order).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to fetch order", nil)
+		return
+	}
+
+	utils.RespondSuccess(c, "Driver assigned successfully", orderResponse(order))
+}
+
+// GetDriverJobs lists the orders assigned to the signed-in driver, optionally
+// filtered by ?status.
+func GetDriverJobs(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.RespondFailed(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	query := preloadOrder(config.DB).Where("driver_id = ?", userID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var orders []models.Order
+	if err := query.Order("created_at desc").Find(&orders).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to fetch jobs", nil)
+		return
+	}
+
+	responseItems := []map[string]interface{}{}
+	for _, order := range orders {
+		responseItems = append(responseItems, orderResponse(order))
+	}
+
+	utils.RespondSuccess(c, "Jobs fetched successfully", responseItems)
+}
+
+func findDriverJob(c *gin.Context) (models.Order, bool) {
+	userID, _ := c.Get("userID")
+
+	var order models.Order
+	if err := preloadOrder(config.DB).Where("id = ?", c.Param("id")).First(&order).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Job not found", nil)
+		return order, false
+	}
+
+	if !isOrderDriver(order, userID) {
+		utils.RespondFailed(c, http.StatusForbidden, "This job is not assigned to you", nil)
+		return order, false
+	}
+
+	return order, true
+}
+
+func GetDriverJobByID(c *gin.Context) {
+	order, ok := findDriverJob(c)
+	if !ok {
+		return
+	}
+
+	utils.RespondSuccess(c, "Job fetched successfully", orderResponse(order))
+}
+
+func MarkDriverJobPickedUp(c *gin.Context) {
+	moveDriverJob(c, services.TransactionStatusOnDeliver, "Job marked as picked up")
+}
+
+func MarkDriverJobDelivered(c *gin.Context) {
+	moveDriverJob(c, services.TransactionStatusFinished, "Job marked as delivered")
+}
+
+func moveDriverJob(c *gin.Context, status string, message string) {
+	order, ok := findDriverJob(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("userID")
+
+	if status == services.TransactionStatusFinished {
+		_, ok = completeOrder(c, &order, services.PartyDriver, userID.(uuid.UUID))
+	} else {
+		_, ok = recordOrderStatus(c, &order, status, services.PartyDriver, userID.(uuid.UUID), "")
+	}
+	if !ok {
+		return
+	}
+
+	if err := preloadOrder(config.DB).Where("id = ?", order.ID).First(&order).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to fetch order", nil)
+		return
+	}
+
+	utils.RespondSuccess(c, message, orderResponse(order))
+}
@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"recyco/config"
+	"recyco/models"
+	"recyco/services"
+	"recyco/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const maxProofPhotos = 5
+
+type HandoverInput struct {
+	PIN                string  `form:"pin"`
+	HandoverWeight     float64 `form:"handover_weight"`
+	HandoverWeightUnit string  `form:"handover_weight_unit"`
+}
+
+// bindHandover reads the handover evidence sent with a completion request:
+// the buyer's PIN, which is checked here, the optionally measured weight and
+// up to five proof_photos files. It writes the error response itself.
+func bindHandover(c *gin.Context, order models.Order) (services.Handover, bool) {
+	var handover services.Handover
+
+	var input HandoverInput
+	if err := c.ShouldBind(&input); err != nil {
+		utils.RespondFailed(c, http.StatusBadRequest, "Input tidak valid", nil)
+		return handover, false
+	}
+
+	if order.Status != services.TransactionStatusOnDeliver {
+		utils.RespondFailed(c, http.StatusConflict, "Status transition is not allowed", nil)
+		return handover, false
+	}
+
+	if input.PIN == "" {
+		utils.RespondFailed(c, http.StatusBadRequest, "The buyer's handover PIN is required to complete the order", nil)
+		return handover, false
+	}
+
+	if input.HandoverWeight < 0 {
+		utils.RespondFailed(c, http.StatusBadRequest, "handover_weight must not be negative", nil)
+		return handover, false
+	}
+	if input.HandoverWeight > 0 {
+		if input.HandoverWeightUnit == "" {
+			input.HandoverWeightUnit = models.WeightUnitKilogram
+		}
+		weight, err := models.NormalizeWeight(input.HandoverWeight, input.HandoverWeightUnit)
+		if err != nil {
+			utils.RespondFailed(c, http.StatusBadRequest, err.Error(), nil)
+			return handover, false
+		}
+		handover.Weight = &weight
+	}
+
+	var files []*multipart.FileHeader
+	if form, err := c.MultipartForm(); err == nil {
+		files = form.File["proof_photos"]
+	}
+	if len(files) > maxProofPhotos {
+		utils.RespondFailed(c, http.StatusBadRequest, "At most 5 proof photos can be attached", nil)
+		return handover, false
+	}
+
+	switch err := services.VerifyHandoverPIN(config.DB, order, input.PIN); {
+	case errors.Is(err, services.ErrHandoverPINWrong):
+		utils.RespondFailed(c, http.StatusForbidden, "Handover PIN is incorrect", nil)
+		return handover, false
+	case errors.Is(err, services.ErrHandoverPINLocked):
+		utils.RespondFailed(c, http.StatusLocked, "Too many incorrect PINs; the buyer has to issue a new one", nil)
+		return handover, false
+	case err != nil:
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to verify handover PIN", nil)
+		return handover, false
+	}
+
+	for _, file := range files {
+		filename := uuid.New().String() + filepath.Ext(file.Filename)
+		if err := c.SaveUploadedFile(file, "uploads/proofs/"+filename); err != nil {
+			removeUploads(handover.PhotoURLs)
+			utils.RespondFailed(c, http.StatusInternalServerError, "Failed to save file", nil)
+			return handover, false
+		}
+		handover.PhotoURLs = append(handover.PhotoURLs, "/uploads/proofs/"+filename)
+	}
+
+	return handover, true
+}
+
+// removeUploads deletes files saved for a request that failed.
+func removeUploads(urls []string) {
+	for _, url := range urls {
+		os.Remove("." + url)
+	}
+}
+
+// completeOrder finishes order as party with the evidence of the request and
+// writes the error response itself when that fails.
+func completeOrder(c *gin.Context, order *models.Order, party string, actorID uuid.UUID) (models.MarketItemTransactionActivities, bool) {
+	return completeOrderWith(c, order, party, actorID, nil)
+}
+
+// completeOrderWith is completeOrder that also runs apply, when it is not
+// nil, in the transaction that finishes the order.
+func completeOrderWith(c *gin.Context, order *models.Order, party string, actorID uuid.UUID, apply func(tx *gorm.DB) error) (models.MarketItemTransactionActivities, bool) {
+	handover, ok := bindHandover(c, *order)
+	if !ok {
+		return models.MarketItemTransactionActivities{}, false
+	}
+
+	tx := config.DB.Begin()
+	if tx.Error != nil {
+		removeUploads(handover.PhotoURLs)
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to start transaction", nil)
+		return models.MarketItemTransactionActivities{}, false
+	}
+
+	activity, err := services.CompleteOrder(tx, order, party, actorID, handover)
+	if err == nil && apply != nil {
+		err = apply(tx)
+	}
+	if err != nil {
+		tx.Rollback()
+		removeUploads(handover.PhotoURLs)
+		respondTransitionError(c, err)
+		return activity, false
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		removeUploads(handover.PhotoURLs)
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to commit transaction", nil)
+		return activity, false
+	}
+
+	sendOrderRefunds(order.ID)
+	return activity, true
+}
+
+// GetOrderHandoverPIN shows the buyer the PIN they give the delivering party
+// at handover.
+func GetOrderHandoverPIN(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var order models.Order
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&order).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Order not found", nil)
+		return
+	}
+
+	if order.BuyerID != userID {
+		utils.RespondFailed(c, http.StatusForbidden, "Only the buyer can see the handover PIN", nil)
+		return
+	}
+
+	utils.RespondSuccess(c, "Handover PIN fetched successfully", gin.H{
+		"order_id":           order.ID,
+		"pin":                order.HandoverPIN,
+		"remaining_attempts": services.HandoverPINMaxAttempts - order.HandoverPINAttempts,
+	})
+}
+
+// ResetOrderHandoverPIN issues the buyer a new PIN, for example after it
+// leaked or was locked by wrong attempts.
+func ResetOrderHandoverPIN(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var order models.Order
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&order).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Order not found", nil)
+		return
+	}
+
+	if order.BuyerID != userID {
+		utils.RespondFailed(c, http.StatusForbidden, "Only the buyer can reset the handover PIN", nil)
+		return
+	}
+
+	if order.Status != services.TransactionStatusOnProcess && order.Status != services.TransactionStatusOnDeliver {
+		utils.RespondFailed(c, http.StatusConflict, "Order is already "+order.Status, nil)
+		return
+	}
+
+	if err := services.ResetHandoverPIN(config.DB, &order); err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to reset handover PIN", nil)
+		return
+	}
+
+	utils.RespondSuccess(c, "Handover PIN reset successfully", gin.H{
+		"order_id":           order.ID,
+		"pin":                order.HandoverPIN,
+		"remaining_attempts": services.HandoverPINMaxAttempts,
+	})
+}
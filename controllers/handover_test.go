@@ -0,0 +1,124 @@
+package controllers_test
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"recyco/config"
+	"recyco/models"
+	"recyco/services"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// deliverOrder has a new buyer order a new LARGE item and its seller start
+// the delivery. It returns the order and the seller's token.
+func deliverOrder(t *testing.T, router *gin.Engine) (models.Order, string) {
+	t.Helper()
+
+	seller, sellerToken := createUser(t, "P_LARGE")
+	_, buyerToken := createUser(t, "C_LARGE")
+	item := createItem(t, seller, "LARGE")
+
+	assertStatus(t, send(router, http.MethodPost, "/market_transactions/", buyerToken, url.Values{
+		"item_id":                 {item.ID.String()},
+		"recipient_name":          {"Buyer"},
+		"recipient_phone":         {"08123456789"},
+		"pickup_location_address": {"Jl. Merdeka 1"},
+	}), http.StatusOK)
+
+	var order models.Order
+	if err := config.DB.Where("item_id = ?", item.ID).First(&order).Error; err != nil {
+		t.Fatalf("find order: %v", err)
+	}
+	assertStatus(t, send(router, http.MethodPut, "/orders/"+order.ID.String()+"/status", sellerToken, url.Values{
+		"status": {services.TransactionStatusOnDeliver},
+	}), http.StatusOK)
+
+	config.DB.Where("id = ?", order.ID).First(&order)
+	return order, sellerToken
+}
+
+func TestConcurrentWrongPINsStopAtLimit(t *testing.T) {
+	router := setupDatabase(t)
+	order, sellerToken := deliverOrder(t, router)
+
+	wrong := "000000"
+	if order.HandoverPIN == wrong {
+		wrong = "111111"
+	}
+
+	const guesses = 2 * services.HandoverPINMaxAttempts
+	codes := make([]int, guesses)
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			codes[i] = send(router, http.MethodPut, "/orders/"+order.ID.String()+"/status", sellerToken, url.Values{
+				"status": {services.TransactionStatusFinished},
+				"pin":    {wrong},
+			}).Code
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	counts := map[int]int{}
+	for _, code := range codes {
+		counts[code]++
+	}
+	if counts[http.StatusForbidden] != services.HandoverPINMaxAttempts || counts[http.StatusLocked] != guesses-services.HandoverPINMaxAttempts {
+		t.Fatalf("responses = %v, want %d %d and %d %d", counts,
+			services.HandoverPINMaxAttempts, http.StatusForbidden, guesses-services.HandoverPINMaxAttempts, http.StatusLocked)
+	}
+
+	// Once locked, even the right PIN is refused.
+	assertStatus(t, send(router, http.MethodPut, "/orders/"+order.ID.String()+"/status", sellerToken, url.Values{
+		"status": {services.TransactionStatusFinished},
+		"pin":    {order.HandoverPIN},
+	}), http.StatusLocked)
+}
+
+func TestRightPINClearsWrongAttempts(t *testing.T) {
+	router := setupDatabase(t)
+	order, _ := deliverOrder(t, router)
+
+	if err := services.VerifyHandoverPIN(config.DB, order, "x"); !errors.Is(err, services.ErrHandoverPINWrong) {
+		t.Fatalf("wrong PIN: %v, want %v", err, services.ErrHandoverPINWrong)
+	}
+	if err := services.VerifyHandoverPIN(config.DB, order, order.HandoverPIN); err != nil {
+		t.Fatalf("right PIN: %v", err)
+	}
+
+	config.DB.Where("id = ?", order.ID).First(&order)
+	if order.HandoverPINAttempts != 0 {
+		t.Fatalf("attempts = %d after the right PIN, want 0", order.HandoverPINAttempts)
+	}
+}
+
+func TestFinishingFromItemUpdateSavesEditsWithOrder(t *testing.T) {
+	router := setupDatabase(t)
+	order, sellerToken := deliverOrder(t, router)
+
+	assertStatus(t, send(router, http.MethodPut, "/markets/"+order.ItemID.String(), sellerToken, url.Values{
+		"status":      {services.TransactionStatusFinished},
+		"pin":         {order.HandoverPIN},
+		"description": {"Collected in two bags"},
+	}), http.StatusOK)
+
+	var item models.MarketItems
+	config.DB.Where("id = ?", order.ItemID).First(&item)
+	if item.Status != models.MarketItemStatusSold || item.Description != "Collected in two bags" {
+		t.Fatalf("item is %s with description %q, want %s with the edit", item.Status, item.Description, models.MarketItemStatusSold)
+	}
+
+	config.DB.Where("id = ?", order.ID).First(&order)
+	if order.Status != services.TransactionStatusFinished {
+		t.Fatalf("order status = %s, want %s", order.Status, services.TransactionStatusFinished)
+	}
+}
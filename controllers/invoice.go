@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"recyco/config"
+	"recyco/models"
+	"recyco/services"
+	"recyco/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetMarketTransactionInvoice serves the invoice of a finished order as PDF,
+// or as HTML with ?format=html. The id is the order's, or like the other
+// market transaction routes its market item's, in which case the item's
+// latest order is invoiced. The invoice is issued on first request for
+// orders finished before invoicing existed; afterwards it never changes.
+func GetMarketTransactionInvoice(c *gin.Context) {
+	format := c.DefaultQuery("format", "pdf")
+	if format != "pdf" && format != "html" {
+		utils.RespondFailed(c, http.StatusBadRequest, "Format must be pdf or html", nil)
+		return
+	}
+
+	var order models.Order
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&order).Error; err != nil {
+		var findErr error
+		order, findErr = findLatestOrderForItem(c.Param("id"))
+		if findErr != nil {
+			utils.RespondFailed(c, http.StatusNotFound, "Transaction not found", nil)
+			return
+		}
+	}
+
+	userID, _ := c.Get("userID")
+	userRole, _ := c.Get("userRole")
+	if !isOrderParty(order, userID) && userRole != "ADMIN" {
+		utils.RespondFailed(c, http.StatusForbidden, "You do not have permission to access this resource", nil)
+		return
+	}
+
+	var invoice models.Invoice
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		invoice, err = services.IssueInvoice(tx, order.ID, time.Now())
+		return err
+	})
+	if errors.Is(err, services.ErrInvoiceNotFinished) {
+		utils.RespondFailed(c, http.StatusConflict, "Only finished transactions have an invoice", nil)
+		return
+	}
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to issue invoice", nil)
+		return
+	}
+
+	var document bytes.Buffer
+	contentType := "application/pdf"
+	if format == "html" {
+		contentType = "text/html; charset=utf-8"
+		err = services.RenderInvoiceHTML(&document, invoice)
+	} else {
+		err = services.RenderInvoicePDF(&document, invoice)
+	}
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to render invoice", nil)
+		return
+	}
+
+	c.Header("Content-Disposition", `inline; filename="`+invoice.Number+"."+format+`"`)
+	c.Data(http.StatusOK, contentType, document.Bytes())
+}
@@ -1,11 +1,14 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"os"
 	"path/filepath"
 	"recyco/config"
 	"recyco/models"
+	"recyco/services"
 	"recyco/utils"
 	"time"
 
@@ -15,20 +18,126 @@ import (
 )
 
 type MarketItemInput struct {
-	Name        string  `form:"name" binding:"required"`
-	Price       float64 `form:"price" binding:"required"`
-	Weight      float64 `form:"weight" binding:"required"`
-	Description string  `form:"description"`
-	OrderedBy   string  `form:"ordered_by"`
+	Name         string       `form:"name" binding:"required"`
+	Price        models.Money `form:"price"`
+	PricePerKg   models.Money `form:"price_per_kg"`
+	Weight       float64      `form:"weight"`
+	WeightUnit   string       `form:"weight_unit"`
+	Quantity     *int         `form:"quantity"`
+	QuantityUnit string       `form:"quantity_unit"`
+	PieceWeight  float64      `form:"piece_weight"`
+	Category     string       `form:"category"`
+	Description  string       `form:"description"`
+	Lat          *float64     `form:"lat"`
+	Lon          *float64     `form:"lon"`
+	OrderedBy    string       `form:"ordered_by"`
+	Status       string       `form:"status"`
 }
 
 type UpdateMarketItemInput struct {
-	Name        string  `form:"name"`
-	Price       float64 `form:"price"`
-	Weight      float64 `form:"weight"`
-	Description string  `form:"description"`
-	OrderedBy   string  `form:"ordered_by"`
-	Status      string  `form:"status"`
+	Name         string       `form:"name"`
+	Price        models.Money `form:"price"`
+	PricePerKg   models.Money `form:"price_per_kg"`
+	Weight       float64      `form:"weight"`
+	WeightUnit   string       `form:"weight_unit"`
+	Quantity     *int         `form:"quantity"`
+	QuantityUnit string       `form:"quantity_unit"`
+	PieceWeight  float64      `form:"piece_weight"`
+	Category     string       `form:"category"`
+	Description  string       `form:"description"`
+	Lat          *float64     `form:"lat"`
+	Lon          *float64     `form:"lon"`
+	OrderedBy    string       `form:"ordered_by"`
+	Status       string       `form:"status"`
+}
+
+var (
+	errInvalidQuantity = errors.New("quantity must be greater than 0")
+	errInvalidWeight   = errors.New("weight must be greater than 0")
+)
+
+// resolveListingWeight normalizes a listed weight to kilograms. Piece-count
+// listings may give the weight of one piece instead of the total, in which
+// case the total is estimated from the count.
+func resolveListingWeight(weight float64, unit string, quantity *int, pieceWeight float64) (float64, bool, error) {
+	if unit == "" {
+		unit = models.WeightUnitKilogram
+	}
+	if !models.IsValidWeightUnit(unit) {
+		return 0, false, models.ErrInvalidWeightUnit
+	}
+
+	isEstimate := false
+	if quantity != nil {
+		if *quantity <= 0 {
+			return 0, false, errInvalidQuantity
+		}
+		if weight <= 0 && pieceWeight > 0 {
+			weight = pieceWeight * float64(*quantity)
+		}
+		isEstimate = true
+	}
+
+	if weight <= 0 {
+		return 0, false, errInvalidWeight
+	}
+
+	kilograms, err := models.NormalizeWeight(weight, unit)
+	if err != nil {
+		return 0, false, err
+	}
+	return kilograms, isEstimate, nil
+}
+
+func respondListingWeightError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrInvalidWeightUnit):
+		utils.RespondFailed(c, http.StatusBadRequest, "Weight unit must be g, kg or ton", nil)
+	case errors.Is(err, errInvalidQuantity):
+		utils.RespondFailed(c, http.StatusBadRequest, "Quantity must be greater than 0", nil)
+	case errors.Is(err, errInvalidWeight):
+		utils.RespondFailed(c, http.StatusBadRequest, "Weight must be greater than 0", nil)
+	default:
+		utils.RespondFailed(c, http.StatusBadRequest, "Invalid weight", nil)
+	}
+}
+
+// scaleNotAllowedMessage explains why a weight falls into a scale the user's
+// role may not post.
+func scaleNotAllowedMessage(rules services.MarketRules, category string, scale string) string {
+	threshold := rules.SmallMaxWeight(category)
+	if scale == "SMALL" {
+		return fmt.Sprintf("Weight for LARGE scale items must be greater than %g kg", threshold)
+	}
+	return fmt.Sprintf("Weight for SMALL scale items must not exceed %g kg", threshold)
+}
+
+// postedByResponse is the seller summary shown on listings, including the
+// reputation earned from reviews, the weight discrepancies flagged on their
+// sales and the disputes resolved against them.
+func postedByResponse(user models.User, reputation services.Reputation) map[string]interface{} {
+	return map[string]interface{}{
+		"id":                   user.ID,
+		"name":                 user.Name,
+		"phone_number":         user.PhoneNumber,
+		"role":                 user.Role,
+		"reputation":           reputation.Score,
+		"review_count":         reputation.ReviewCount,
+		"weight_discrepancies": reputation.WeightDiscrepancies,
+		"disputes_lost":        reputation.DisputesLost,
+	}
+}
+
+func itemPosters(items []models.MarketItems) []uuid.UUID {
+	seen := map[uuid.UUID]bool{}
+	posters := []uuid.UUID{}
+	for _, item := range items {
+		if !seen[item.PostedBy] {
+			seen[item.PostedBy] = true
+			posters = append(posters, item.PostedBy)
+		}
+	}
+	return posters
 }
 
 func CreateMarketItem(c *gin.Context) {
@@ -56,23 +165,50 @@ func CreateMarketItem(c *gin.Context) {
 		return
 	}
 
-	var itemScale string
-	if userRole == "P_SMALL" {
-		itemScale = "SMALL"
-	} else if userRole == "P_LARGE" {
-		itemScale = "LARGE"
-	} else {
+	weight, weightIsEstimate, err := resolveListingWeight(input.Weight, input.WeightUnit, input.Quantity, input.PieceWeight)
+	if err != nil {
+		respondListingWeightError(c, err)
+		return
+	}
+	if input.WeightUnit == "" {
+		input.WeightUnit = models.WeightUnitKilogram
+	}
+
+	rules, err := services.LoadMarketRules(config.DB)
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to load marketplace rules", nil)
+		return
+	}
+
+	if len(rules.PostableScales(userRole.(string))) == 0 {
 		utils.RespondFailed(c, http.StatusForbidden, "Invalid user role for creating market item", nil)
 		return
 	}
 
-	if itemScale == "SMALL" && input.Weight > 15 {
-		utils.RespondFailed(c, http.StatusNotFound, "Weight for SMALL scale items must not exceed 15", nil)
+	itemScale := rules.ScaleForWeight(input.Category, weight)
+	if !rules.CanPost(userRole.(string), itemScale) {
+		utils.RespondFailed(c, http.StatusBadRequest, scaleNotAllowedMessage(rules, input.Category, itemScale), nil)
+		return
+	}
+
+	// Items priced per kg are listed at the total for the listed weight.
+	if input.PricePerKg < 0 {
+		utils.RespondFailed(c, http.StatusBadRequest, "Price per kg must be greater than 0", nil)
+		return
+	}
+	if input.PricePerKg > 0 {
+		input.Price = input.PricePerKg.MulQuantity(weight)
+	}
+	if input.Price <= 0 {
+		utils.RespondFailed(c, http.StatusBadRequest, "Price must be greater than 0", nil)
 		return
 	}
 
-	if itemScale == "LARGE" && input.Weight <= 15 {
-		utils.RespondFailed(c, http.StatusNotFound, "Weight for LARGE scale items must be greater than 15", nil)
+	if input.Status == "" {
+		input.Status = models.MarketItemStatusReady
+	}
+	if input.Status != models.MarketItemStatusDraft && input.Status != models.MarketItemStatusReady {
+		utils.RespondFailed(c, http.StatusBadRequest, "Status must be DRAFT or READY", nil)
 		return
 	}
 
@@ -84,16 +220,25 @@ func CreateMarketItem(c *gin.Context) {
 	}
 
 	marketItem := models.MarketItems{
-		ID:           uuid.New(),
-		Name:         input.Name,
-		Price:        input.Price,
-		Weight:       input.Weight,
-		ItemScale:    itemScale,
-		Description:  input.Description,
-		ThumbnailUrl: "/uploads/markets/" + filename,
-		PostedBy:     userID.(uuid.UUID),
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
+		ID:               uuid.New(),
+		Name:             input.Name,
+		Price:            input.Price,
+		PricePerKg:       input.PricePerKg,
+		Weight:           weight,
+		WeightUnit:       input.WeightUnit,
+		Quantity:         input.Quantity,
+		QuantityUnit:     input.QuantityUnit,
+		WeightIsEstimate: weightIsEstimate,
+		ItemScale:        itemScale,
+		Status:           input.Status,
+		Category:         input.Category,
+		Description:      input.Description,
+		Lat:              input.Lat,
+		Lon:              input.Lon,
+		ThumbnailUrl:     "/uploads/markets/" + filename,
+		PostedBy:         userID.(uuid.UUID),
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
 	}
 
 	if err := config.DB.Create(&marketItem).Error; err != nil {
@@ -102,19 +247,30 @@ func CreateMarketItem(c *gin.Context) {
 	}
 
 	response := map[string]interface{}{
-		"id":             marketItem.ID,
-		"name":           marketItem.Name,
-		"price":          marketItem.Price,
-		"weight":         marketItem.Weight,
-		"item_scale":     marketItem.ItemScale,
-		"description":    marketItem.Description,
-		"thumbnail_url":  marketItem.ThumbnailUrl,
-		"posted_by":      marketItem.PostedBy,
-		"ordered_by":     input.OrderedBy,
-		"created_at":     marketItem.CreatedAt,
-		"updated_at":     marketItem.UpdatedAt,
-		"deleted_at":     marketItem.DeletedAt,
-		"posted_by_user": userID,
+		"id":                 marketItem.ID,
+		"name":               marketItem.Name,
+		"price":              marketItem.Price,
+		"price_per_kg":       marketItem.PricePerKg,
+		"currency":           marketItem.Currency,
+		"weight":             marketItem.Weight,
+		"weight_unit":        marketItem.WeightUnit,
+		"display_weight":     models.WeightInUnit(marketItem.Weight, marketItem.WeightUnit),
+		"quantity":           marketItem.Quantity,
+		"quantity_unit":      marketItem.QuantityUnit,
+		"weight_is_estimate": marketItem.WeightIsEstimate,
+		"item_scale":         marketItem.ItemScale,
+		"status":             marketItem.Status,
+		"category":           marketItem.Category,
+		"description":        marketItem.Description,
+		"lat":                marketItem.Lat,
+		"lon":                marketItem.Lon,
+		"thumbnail_url":      marketItem.ThumbnailUrl,
+		"posted_by":          marketItem.PostedBy,
+		"ordered_by":         input.OrderedBy,
+		"created_at":         marketItem.CreatedAt,
+		"updated_at":         marketItem.UpdatedAt,
+		"deleted_at":         marketItem.DeletedAt,
+		"posted_by_user":     userID,
 	}
 
 	utils.RespondSuccess(c, "Market item created successfully", response)
@@ -128,32 +284,30 @@ func GetMarketItems(c *gin.Context) {
 		utils.RespondFailed(c, http.StatusInternalServerError, "User role not found in context", nil)
 		return
 	}
-	switch userRole {
-	case "C_SMALL":
-		if err := config.DB.Preload("PostedByUser").Where("item_scale = ?", "SMALL").Find(&items).Error; err != nil {
-			utils.RespondFailed(c, http.StatusInternalServerError, "Failed to fetch market items", nil)
-			return
-		}
-	case "C_LARGE":
-		if err := config.DB.Preload("PostedByUser").Where("item_scale = ?", "LARGE").Find(&items).Error; err != nil {
-			utils.RespondFailed(c, http.StatusInternalServerError, "Failed to fetch market items", nil)
-			return
-		}
-	case "P_SMALL":
-		if err := config.DB.Preload("PostedByUser").Where("item_scale = ?", "SMALL").Find(&items).Error; err != nil {
-			utils.RespondFailed(c, http.StatusInternalServerError, "Failed to fetch market items", nil)
-			return
-		}
-	case "P_LARGE":
-		if err := config.DB.Preload("PostedByUser").Where("item_scale = ?", "LARGE").Find(&items).Error; err != nil {
-			utils.RespondFailed(c, http.StatusInternalServerError, "Failed to fetch market items", nil)
-			return
-		}
-	default:
-		if err := config.DB.Preload("PostedByUser").Find(&items).Error; err != nil {
-			utils.RespondFailed(c, http.StatusInternalServerError, "Failed to fetch market items", nil)
-			return
-		}
+
+	status := c.DefaultQuery("status", models.MarketItemStatusReady)
+	if !models.IsValidMarketItemStatus(status) {
+		utils.RespondFailed(c, http.StatusBadRequest, "Invalid status value", nil)
+		return
+	}
+	if status == models.MarketItemStatusDraft && userRole != "ADMIN" {
+		utils.RespondFailed(c, http.StatusForbidden, "You do not have permission to access this resource", nil)
+		return
+	}
+
+	rules, err := services.LoadMarketRules(config.DB)
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to load marketplace rules", nil)
+		return
+	}
+
+	query := config.DB.Preload("PostedByUser").
+		Where("status = ?", status).
+		Where("item_scale IN ?", rules.ViewableScales(userRole.(string)))
+
+	if err := query.Find(&items).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to fetch market items", nil)
+		return
 	}
 
 	if len(items) == 0 {
@@ -161,27 +315,38 @@ func GetMarketItems(c *gin.Context) {
 		return
 	}
 
+	reputations, err := services.LoadReputations(config.DB, itemPosters(items))
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to load reputation", nil)
+		return
+	}
+
 	var responseItems []map[string]interface{}
 	for _, item := range items {
-		postedBy := map[string]interface{}{
-			"id":           item.PostedByUser.ID,
-			"name":         item.PostedByUser.Name,
-			"phone_number": item.PostedByUser.PhoneNumber,
-			"role":         item.PostedByUser.Role,
-		}
+		postedBy := postedByResponse(item.PostedByUser, reputations[item.PostedBy])
 
 		responseItem := map[string]interface{}{
-			"id":            item.ID,
-			"name":          item.Name,
-			"price":         item.Price,
-			"weight":        item.Weight,
-			"scale":         item.ItemScale,
-			"description":   item.Description,
-			"thumbnail_url": item.ThumbnailUrl,
-			"posted_by":     postedBy,
-			"created_at":    item.CreatedAt,
-			"updated_at":    item.UpdatedAt,
-			"deleted_at":    item.DeletedAt,
+			"id":                 item.ID,
+			"name":               item.Name,
+			"price":              item.Price,
+			"price_per_kg":       item.PricePerKg,
+			"currency":           item.Currency,
+			"weight":             item.Weight,
+			"weight_unit":        item.WeightUnit,
+			"display_weight":     models.WeightInUnit(item.Weight, item.WeightUnit),
+			"quantity":           item.Quantity,
+			"quantity_unit":      item.QuantityUnit,
+			"weight_is_estimate": item.WeightIsEstimate,
+			"scale":              item.ItemScale,
+			"status":             item.Status,
+			"listing_type":       item.ListingType,
+			"category":           item.Category,
+			"description":        item.Description,
+			"thumbnail_url":      item.ThumbnailUrl,
+			"posted_by":          postedBy,
+			"created_at":         item.CreatedAt,
+			"updated_at":         item.UpdatedAt,
+			"deleted_at":         item.DeletedAt,
 		}
 		responseItems = append(responseItems, responseItem)
 	}
@@ -205,29 +370,54 @@ func GetMarketItemByID(c *gin.Context) {
 		return
 	}
 
-	if (userRole == "C_SMALL" && item.ItemScale != "SMALL") || (userRole == "C_LARGE" && item.ItemScale != "LARGE") {
+	rules, err := services.LoadMarketRules(config.DB)
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to load marketplace rules", nil)
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	if item.PostedBy != userID && !rules.CanView(userRole.(string), item.ItemScale) {
 		utils.RespondFailed(c, http.StatusForbidden, "You do not have permission to access this resource", nil)
 		return
 	}
 
-	postedBy := map[string]interface{}{
-		"id":           item.PostedByUser.ID,
-		"name":         item.PostedByUser.Name,
-		"phone_number": item.PostedByUser.PhoneNumber,
-		"role":         item.PostedByUser.Role,
+	if item.Status == models.MarketItemStatusDraft && item.PostedBy != userID && userRole != "ADMIN" {
+		utils.RespondFailed(c, http.StatusNotFound, "Market item not found", nil)
+		return
+	}
+
+	reputations, err := services.LoadReputations(config.DB, []uuid.UUID{item.PostedBy})
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to load reputation", nil)
+		return
 	}
 
+	postedBy := postedByResponse(item.PostedByUser, reputations[item.PostedBy])
+
 	responseItem := map[string]interface{}{
-		"id":            item.ID,
-		"name":          item.Name,
-		"price":         item.Price,
-		"weight":        item.Weight,
-		"description":   item.Description,
-		"thumbnail_url": item.ThumbnailUrl,
-		"posted_by":     postedBy,
-		"created_at":    item.CreatedAt,
-		"updated_at":    item.UpdatedAt,
-		"deleted_at":    item.DeletedAt,
+		"id":                 item.ID,
+		"name":               item.Name,
+		"price":              item.Price,
+		"price_per_kg":       item.PricePerKg,
+		"currency":           item.Currency,
+		"weight":             item.Weight,
+		"weight_unit":        item.WeightUnit,
+		"display_weight":     models.WeightInUnit(item.Weight, item.WeightUnit),
+		"quantity":           item.Quantity,
+		"quantity_unit":      item.QuantityUnit,
+		"weight_is_estimate": item.WeightIsEstimate,
+		"status":             item.Status,
+		"listing_type":       item.ListingType,
+		"category":           item.Category,
+		"description":        item.Description,
+		"lat":                item.Lat,
+		"lon":                item.Lon,
+		"thumbnail_url":      item.ThumbnailUrl,
+		"posted_by":          postedBy,
+		"created_at":         item.CreatedAt,
+		"updated_at":         item.UpdatedAt,
+		"deleted_at":         item.DeletedAt,
 	}
 
 	utils.RespondSuccess(c, "Market item fetched successfully", responseItem)
@@ -242,7 +432,17 @@ func GetUserMarketItems(c *gin.Context) {
 		return
 	}
 
-	if err := config.DB.Preload("PostedByUser").Unscoped().Where("posted_by = ?", userID).Find(&items).Error; err != nil {
+	// Withdrawn listings are soft-deleted but still belong to the seller.
+	query := config.DB.Preload("PostedByUser").Unscoped().Where("posted_by = ?", userID)
+	if status := c.Query("status"); status != "" {
+		if !models.IsValidMarketItemStatus(status) {
+			utils.RespondFailed(c, http.StatusBadRequest, "Invalid status value", nil)
+			return
+		}
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Find(&items).Error; err != nil {
 		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to fetch market items", nil)
 		return
 	}
@@ -252,35 +452,37 @@ func GetUserMarketItems(c *gin.Context) {
 		return
 	}
 
+	reputations, err := services.LoadReputations(config.DB, itemPosters(items))
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to load reputation", nil)
+		return
+	}
+
 	var responseItems []map[string]interface{}
 	for _, item := range items {
-		postedBy := map[string]interface{}{
-			"id":           item.PostedByUser.ID,
-			"name":         item.PostedByUser.Name,
-			"phone_number": item.PostedByUser.PhoneNumber,
-			"role":         item.PostedByUser.Role,
-		}
-
-		var transaction models.MarketItemTransactionActivities
-		status := "READY"
-
-		if err := config.DB.Unscoped().Where("item_id = ?", item.ID).Order("created_at desc").First(&transaction).Error; err == nil {
-			status = transaction.Status
-		}
+		postedBy := postedByResponse(item.PostedByUser, reputations[item.PostedBy])
 
 		responseItem := map[string]interface{}{
-			"id":            item.ID,
-			"name":          item.Name,
-			"price":         item.Price,
-			"weight":        item.Weight,
-			"scale":         item.ItemScale,
-			"description":   item.Description,
-			"thumbnail_url": item.ThumbnailUrl,
-			"posted_by":     postedBy,
-			"created_at":    item.CreatedAt,
-			"updated_at":    item.UpdatedAt,
-			"deleted_at":    item.DeletedAt,
-			"status":        status,
+			"id":                 item.ID,
+			"name":               item.Name,
+			"price":              item.Price,
+			"price_per_kg":       item.PricePerKg,
+			"currency":           item.Currency,
+			"weight":             item.Weight,
+			"weight_unit":        item.WeightUnit,
+			"display_weight":     models.WeightInUnit(item.Weight, item.WeightUnit),
+			"quantity":           item.Quantity,
+			"quantity_unit":      item.QuantityUnit,
+			"weight_is_estimate": item.WeightIsEstimate,
+			"scale":              item.ItemScale,
+			"description":        item.Description,
+			"thumbnail_url":      item.ThumbnailUrl,
+			"posted_by":          postedBy,
+			"created_at":         item.CreatedAt,
+			"updated_at":         item.UpdatedAt,
+			"deleted_at":         item.DeletedAt,
+			"status":             item.Status,
+			"listing_type":       item.ListingType,
 		}
 		responseItems = append(responseItems, responseItem)
 	}
@@ -321,24 +523,76 @@ func UpdateMarketItem(c *gin.Context) {
 		return
 	}
 
-	if input.Weight > 0 {
-		if userRole == "P_SMALL" && input.Weight > 15 {
-			utils.RespondFailed(c, http.StatusForbidden, "Weight for SMALL scale items must not exceed 15", nil)
-			return
-		}
+	weightUnit := input.WeightUnit
+	if weightUnit == "" {
+		weightUnit = marketItem.WeightUnit
+	}
+	quantity := input.Quantity
+	if quantity == nil {
+		quantity = marketItem.Quantity
+	}
 
-		if userRole == "P_LARGE" && input.Weight <= 15 {
-			utils.RespondFailed(c, http.StatusForbidden, "Weight for LARGE scale items must be greater than 15", nil)
+	var weight float64
+	weightIsEstimate := marketItem.WeightIsEstimate
+	if input.Weight > 0 || input.PieceWeight > 0 {
+		var err error
+		weight, weightIsEstimate, err = resolveListingWeight(input.Weight, weightUnit, quantity, input.PieceWeight)
+		if err != nil {
+			respondListingWeightError(c, err)
 			return
 		}
+	} else if !models.IsValidWeightUnit(weightUnit) {
+		utils.RespondFailed(c, http.StatusBadRequest, "Weight unit must be g, kg or ton", nil)
+		return
+	}
+
+	rules, err := services.LoadMarketRules(config.DB)
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to load marketplace rules", nil)
+		return
 	}
 
-	if userRole == "P_SMALL" && marketItem.ItemScale != "SMALL" {
-		utils.RespondFailed(c, http.StatusForbidden, "You are not allowed to update LARGE scale items", nil)
+	if !rules.CanPost(userRole.(string), marketItem.ItemScale) {
+		utils.RespondFailed(c, http.StatusForbidden, "You are not allowed to update "+marketItem.ItemScale+" scale items", nil)
 		return
 	}
-	if userRole == "P_LARGE" && marketItem.ItemScale != "LARGE" {
-		utils.RespondFailed(c, http.StatusForbidden, "You are not allowed to update SMALL scale items", nil)
+
+	category := marketItem.Category
+	if input.Category != "" {
+		category = input.Category
+	}
+	newWeight := marketItem.Weight
+	if weight > 0 {
+		newWeight = weight
+	}
+	itemScale := rules.ScaleForWeight(category, newWeight)
+	if !rules.CanPost(userRole.(string), itemScale) {
+		utils.RespondFailed(c, http.StatusBadRequest, scaleNotAllowedMessage(rules, category, itemScale), nil)
+		return
+	}
+	marketItem.ItemScale = itemScale
+
+	if marketItem.ListingType == models.ListingTypeAuction {
+		utils.RespondFailed(c, http.StatusConflict, "Market item is being auctioned", nil)
+		return
+	}
+
+	// Only the edited columns are written, so the update does not undo
+	// changes made to the item since it was read.
+	updates := map[string]interface{}{"ItemScale": itemScale}
+	readStatus := marketItem.Status
+
+	switch input.Status {
+	case "", services.TransactionStatusFinished:
+	case models.MarketItemStatusDraft, models.MarketItemStatusReady:
+		if marketItem.Status != models.MarketItemStatusDraft && marketItem.Status != models.MarketItemStatusReady {
+			utils.RespondFailed(c, http.StatusConflict, "Market item can no longer be moved to "+input.Status, nil)
+			return
+		}
+		marketItem.Status = input.Status
+		updates["Status"] = input.Status
+	default:
+		utils.RespondFailed(c, http.StatusBadRequest, "Invalid status value", nil)
 		return
 	}
 
@@ -355,86 +609,131 @@ func UpdateMarketItem(c *gin.Context) {
 		}
 
 		marketItem.ThumbnailUrl = "/uploads/market/" + filename
+		updates["ThumbnailUrl"] = marketItem.ThumbnailUrl
 	}
 
 	if input.Name != "" {
 		marketItem.Name = input.Name
+		updates["Name"] = input.Name
+	}
+	if input.Price < 0 {
+		utils.RespondFailed(c, http.StatusBadRequest, "Price must be greater than 0", nil)
+		return
 	}
+	if input.PricePerKg < 0 {
+		utils.RespondFailed(c, http.StatusBadRequest, "Price per kg must be greater than 0", nil)
+		return
+	}
+	// A total price switches the item to lot pricing, a price per kg to
+	// pricing by weight; either way the total follows the weight.
 	if input.Price != 0 {
 		marketItem.Price = input.Price
+		marketItem.PricePerKg = 0
+	}
+	if input.PricePerKg != 0 {
+		marketItem.PricePerKg = input.PricePerKg
 	}
-	if input.Weight != 0 {
-		marketItem.Weight = input.Weight
+	if weight != 0 {
+		marketItem.Weight = weight
+		marketItem.WeightIsEstimate = weightIsEstimate
+		updates["Weight"] = weight
+		updates["WeightIsEstimate"] = weightIsEstimate
+	}
+	if marketItem.PricePerKg > 0 {
+		marketItem.Price = marketItem.PricePerKg.MulQuantity(marketItem.Weight)
+	}
+	if input.Price != 0 || input.PricePerKg != 0 || weight != 0 {
+		updates["Price"] = marketItem.Price
+		updates["PricePerKg"] = marketItem.PricePerKg
+	}
+	marketItem.WeightUnit = weightUnit
+	updates["WeightUnit"] = weightUnit
+	if input.Quantity != nil {
+		marketItem.Quantity = input.Quantity
+		updates["Quantity"] = input.Quantity
+	}
+	if input.QuantityUnit != "" {
+		marketItem.QuantityUnit = input.QuantityUnit
+		updates["QuantityUnit"] = input.QuantityUnit
+	}
+	if input.Category != "" {
+		marketItem.Category = input.Category
+		updates["Category"] = input.Category
 	}
 	if input.Description != "" {
 		marketItem.Description = input.Description
+		updates["Description"] = input.Description
 	}
-
-	marketItem.UpdatedAt = time.Now()
-
-	tx := config.DB.Begin()
-	if tx.Error != nil {
-		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to start transaction", nil)
-		return
+	if input.Lat != nil && input.Lon != nil {
+		marketItem.Lat = input.Lat
+		marketItem.Lon = input.Lon
+		updates["Lat"] = input.Lat
+		updates["Lon"] = input.Lon
 	}
 
-	if input.Status == "FINISHED" {
-		transactionActivity := models.MarketItemTransactionActivities{
-			ID:              uuid.New(),
-			ItemID:          marketItem.ID,
-			Status:          input.Status,
-			TransactionByID: uuid.Nil,
-			CreatedAt:       time.Now(),
-			UpdatedAt:       time.Now(),
+	marketItem.UpdatedAt = time.Now()
+	updates["UpdatedAt"] = marketItem.UpdatedAt
+
+	// A status change only applies to the status the item was read in, so
+	// it cannot undo an order placed in the meantime.
+	saveItem := func(tx *gorm.DB) error {
+		query := tx.Model(&models.MarketItems{}).Where("id = ?", marketItem.ID)
+		if _, ok := updates["Status"]; ok {
+			query = query.Where("status = ?", readStatus)
 		}
-
-		var pickupInfo models.MarketItemPickupInformations
-		if err := config.DB.Where("item_id = ?", marketItem.ID).First(&pickupInfo).Error; err == nil {
-			transactionActivity.TransactionByID = pickupInfo.ID
+		result := query.Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return services.ErrTransactionConflict
 		}
+		return nil
+	}
 
-		if err := tx.Create(&transactionActivity).Error; err != nil {
-			tx.Rollback()
-			utils.RespondFailed(c, http.StatusInternalServerError, "Failed to create transaction activity", nil)
+	// Completing the order checks the buyer's PIN and stores the handover
+	// evidence; the item edits are saved in the same transaction.
+	if input.Status == services.TransactionStatusFinished {
+		order, err := findLatestOrderForItem(marketItem.ID)
+		if err != nil {
+			utils.RespondFailed(c, http.StatusConflict, "Market item has no ongoing transaction", nil)
 			return
 		}
 
-		marketItem.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
-	}
-
-	if err := tx.Save(&marketItem).Error; err != nil {
-		tx.Rollback()
-		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to update market item", nil)
-		return
-	}
+		if _, ok := completeOrderWith(c, &order, services.PartySeller, userID.(uuid.UUID), saveItem); !ok {
+			return
+		}
 
-	if err := tx.Commit().Error; err != nil {
-		tx.Rollback()
-		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to commit transaction", nil)
+		marketItem.Status = models.MarketItemStatusSold
+	} else if err := config.DB.Transaction(saveItem); err != nil {
+		respondTransitionError(c, err)
 		return
 	}
 
-	var transaction models.MarketItemTransactionActivities
-	status := "READY"
-
-	if err := config.DB.Unscoped().Where("item_id = ?", marketItem.ID).Order("created_at desc").First(&transaction).Error; err == nil {
-		status = transaction.Status
-	}
-
 	response := map[string]interface{}{
-		"id":            marketItem.ID,
-		"name":          marketItem.Name,
-		"price":         marketItem.Price,
-		"weight":        marketItem.Weight,
-		"item_scale":    marketItem.ItemScale,
-		"description":   marketItem.Description,
-		"thumbnail_url": marketItem.ThumbnailUrl,
-		"posted_by":     marketItem.PostedBy,
-		"ordered_by":    input.OrderedBy,
-		"created_at":    marketItem.CreatedAt,
-		"updated_at":    marketItem.UpdatedAt,
-		"deleted_at":    marketItem.DeletedAt,
-		"status":        status,
+		"id":                 marketItem.ID,
+		"name":               marketItem.Name,
+		"price":              marketItem.Price,
+		"price_per_kg":       marketItem.PricePerKg,
+		"currency":           marketItem.Currency,
+		"weight":             marketItem.Weight,
+		"weight_unit":        marketItem.WeightUnit,
+		"display_weight":     models.WeightInUnit(marketItem.Weight, marketItem.WeightUnit),
+		"quantity":           marketItem.Quantity,
+		"quantity_unit":      marketItem.QuantityUnit,
+		"weight_is_estimate": marketItem.WeightIsEstimate,
+		"item_scale":         marketItem.ItemScale,
+		"category":           marketItem.Category,
+		"description":        marketItem.Description,
+		"lat":                marketItem.Lat,
+		"lon":                marketItem.Lon,
+		"thumbnail_url":      marketItem.ThumbnailUrl,
+		"posted_by":          marketItem.PostedBy,
+		"ordered_by":         input.OrderedBy,
+		"created_at":         marketItem.CreatedAt,
+		"updated_at":         marketItem.UpdatedAt,
+		"deleted_at":         marketItem.DeletedAt,
+		"status":             marketItem.Status,
 	}
 
 	utils.RespondSuccess(c, "Market item updated successfully", response)
@@ -466,23 +765,68 @@ func DeleteMarketItem(c *gin.Context) {
 		return
 	}
 
-	if userRole == "P_SMALL" && marketItem.ItemScale != "SMALL" {
-		utils.RespondFailed(c, http.StatusForbidden, "You are not allowed to delete LARGE scale items", nil)
+	rules, err := services.LoadMarketRules(config.DB)
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to load marketplace rules", nil)
+		return
+	}
+
+	if !rules.CanPost(userRole.(string), marketItem.ItemScale) {
+		utils.RespondFailed(c, http.StatusForbidden, "You are not allowed to delete "+marketItem.ItemScale+" scale items", nil)
 		return
 	}
-	if userRole == "P_LARGE" && marketItem.ItemScale != "LARGE" {
-		utils.RespondFailed(c, http.StatusForbidden, "You are not allowed to delete SMALL scale items", nil)
+
+	if marketItem.ListingType == models.ListingTypeAuction {
+		utils.RespondFailed(c, http.StatusConflict, "Market item is being auctioned", nil)
 		return
 	}
 
-	if marketItem.ThumbnailUrl != "" {
-		os.Remove("." + marketItem.ThumbnailUrl)
+	if marketItem.Status == models.MarketItemStatusReserved || marketItem.Status == models.MarketItemStatusInDelivery {
+		utils.RespondFailed(c, http.StatusConflict, "Market item has an ongoing transaction", nil)
+		return
+	}
+
+	// Orders, invoices and exports still point at sold items.
+	if marketItem.Status == models.MarketItemStatusSold {
+		utils.RespondFailed(c, http.StatusConflict, "Sold market items cannot be deleted", nil)
+		return
 	}
 
-	if err := config.DB.Delete(&marketItem).Error; err != nil {
+	tx := config.DB.Begin()
+	if tx.Error != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to start transaction", nil)
+		return
+	}
+
+	// The item may have been ordered since it was read.
+	result := tx.Model(&models.MarketItems{}).Where("id = ? AND status = ?", marketItem.ID, marketItem.Status).
+		Update("status", models.MarketItemStatusWithdrawn)
+	if result.Error != nil {
+		tx.Rollback()
 		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to delete market item", nil)
 		return
 	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		utils.RespondFailed(c, http.StatusConflict, "Market item has already been ordered or changed", nil)
+		return
+	}
+
+	if err := tx.Delete(&marketItem).Error; err != nil {
+		tx.Rollback()
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to delete market item", nil)
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to commit transaction", nil)
+		return
+	}
+
+	if marketItem.ThumbnailUrl != "" {
+		os.Remove("." + marketItem.ThumbnailUrl)
+	}
 
 	utils.RespondSuccess(c, "Market item deleted successfully", nil)
 }
@@ -0,0 +1,62 @@
+package controllers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"recyco/config"
+	"recyco/models"
+	"testing"
+)
+
+func TestWithdrawnItemsStayListedForSeller(t *testing.T) {
+	router := setupDatabase(t)
+
+	seller, sellerToken := createUser(t, "P_LARGE")
+	item := createItem(t, seller, "LARGE")
+	assertStatus(t, send(router, http.MethodDelete, "/markets/"+item.ID.String(), sellerToken, nil), http.StatusOK)
+
+	recorder := send(router, http.MethodGet, "/markets_self/?status="+models.MarketItemStatusWithdrawn, sellerToken, nil)
+	assertStatus(t, recorder, http.StatusOK)
+
+	var body struct {
+		Data []struct {
+			ID     string `json:"id"`
+			Status string `json:"status"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode items: %v", err)
+	}
+	if len(body.Data) != 1 || body.Data[0].ID != item.ID.String() {
+		t.Fatalf("withdrawn items = %+v, want only %s", body.Data, item.ID)
+	}
+}
+
+func TestSoldItemCannotBeDeleted(t *testing.T) {
+	router := setupDatabase(t)
+
+	seller, sellerToken := createUser(t, "P_LARGE")
+	item := createItem(t, seller, "LARGE")
+	config.DB.Model(&models.MarketItems{}).Where("id = ?", item.ID).Update("status", models.MarketItemStatusSold)
+
+	assertStatus(t, send(router, http.MethodDelete, "/markets/"+item.ID.String(), sellerToken, nil), http.StatusConflict)
+
+	if err := config.DB.Where("id = ?", item.ID).First(&item).Error; err != nil {
+		t.Fatalf("find item: %v", err)
+	}
+	if item.Status != models.MarketItemStatusSold {
+		t.Fatalf("item is %s, want %s", item.Status, models.MarketItemStatusSold)
+	}
+}
+
+func TestUpdateToForbiddenScaleIsInvalidInput(t *testing.T) {
+	router := setupDatabase(t)
+
+	seller, sellerToken := createUser(t, "P_SMALL")
+	item := createItem(t, seller, "SMALL")
+
+	assertStatus(t, send(router, http.MethodPut, "/markets/"+item.ID.String(), sellerToken, url.Values{
+		"weight": {"1000000"},
+	}), http.StatusBadRequest)
+}
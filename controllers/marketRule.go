@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"recyco/config"
+	"recyco/models"
+	"recyco/services"
+	"recyco/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type MarketScaleRuleInput struct {
+	Category       string  `form:"category"`
+	SmallMaxWeight float64 `form:"small_max_weight" binding:"required"`
+}
+
+type MarketRoleRuleInput struct {
+	Role      string `form:"role" binding:"required"`
+	ItemScale string `form:"item_scale" binding:"required"`
+	CanPost   bool   `form:"can_post"`
+	CanView   bool   `form:"can_view"`
+	CanBuy    bool   `form:"can_buy"`
+}
+
+func GetMarketRules(c *gin.Context) {
+	rules, err := services.LoadMarketRules(config.DB)
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to load marketplace rules", nil)
+		return
+	}
+
+	utils.RespondSuccess(c, "Marketplace rules fetched successfully", gin.H{
+		"scale_rules": rules.ScaleRules,
+		"role_rules":  rules.RoleRules,
+	})
+}
+
+// UpsertMarketScaleRule sets the SMALL/LARGE boundary for a category, or the
+// default boundary when category is empty.
+func UpsertMarketScaleRule(c *gin.Context) {
+	var input MarketScaleRuleInput
+	if err := c.ShouldBind(&input); err != nil {
+		utils.RespondFailed(c, http.StatusBadRequest, "Input tidak valid", nil)
+		return
+	}
+
+	if input.SmallMaxWeight <= 0 {
+		utils.RespondFailed(c, http.StatusBadRequest, "small_max_weight must be greater than 0", nil)
+		return
+	}
+
+	var rule models.MarketScaleRule
+	err := config.DB.Where("category = ?", input.Category).First(&rule).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		rule = models.MarketScaleRule{Category: input.Category, SmallMaxWeight: input.SmallMaxWeight}
+		err = config.DB.Create(&rule).Error
+	case err == nil:
+		rule.SmallMaxWeight = input.SmallMaxWeight
+		err = config.DB.Save(&rule).Error
+	}
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to save scale rule", nil)
+		return
+	}
+
+	utils.RespondSuccess(c, "Scale rule saved successfully", rule)
+}
+
+func DeleteMarketScaleRule(c *gin.Context) {
+	var rule models.MarketScaleRule
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&rule).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Scale rule not found", nil)
+		return
+	}
+
+	if rule.Category == "" {
+		utils.RespondFailed(c, http.StatusConflict, "The default scale rule cannot be deleted", nil)
+		return
+	}
+
+	if err := config.DB.Unscoped().Delete(&rule).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to delete scale rule", nil)
+		return
+	}
+
+	utils.RespondSuccess(c, "Scale rule deleted successfully", nil)
+}
+
+// UpsertMarketRoleRule sets what a role may do with items of one scale.
+func UpsertMarketRoleRule(c *gin.Context) {
+	var input MarketRoleRuleInput
+	if err := c.ShouldBind(&input); err != nil {
+		utils.RespondFailed(c, http.StatusBadRequest, "Input tidak valid", nil)
+		return
+	}
+
+	if input.ItemScale != "SMALL" && input.ItemScale != "LARGE" {
+		utils.RespondFailed(c, http.StatusBadRequest, "Invalid item scale", nil)
+		return
+	}
+
+	var rule models.MarketRoleRule
+	err := config.DB.Where("role = ? AND item_scale = ?", input.Role, input.ItemScale).First(&rule).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to save role rule", nil)
+		return
+	}
+
+	rule.Role = input.Role
+	rule.ItemScale = input.ItemScale
+	rule.CanPost = input.CanPost
+	rule.CanView = input.CanView
+	rule.CanBuy = input.CanBuy
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = config.DB.Create(&rule).Error
+	} else {
+		err = config.DB.Save(&rule).Error
+	}
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to save role rule", nil)
+		return
+	}
+
+	utils.RespondSuccess(c, "Role rule saved successfully", rule)
+}
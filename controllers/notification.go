@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"net/http"
+	"recyco/config"
+	"recyco/models"
+	"recyco/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetNotifications(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.RespondFailed(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	query := config.DB.Where("user_id = ?", userID)
+	if c.Query("unread") == "true" {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var notifications []models.Notification
+	if err := query.Order("created_at desc").Find(&notifications).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to fetch notifications", nil)
+		return
+	}
+
+	utils.RespondSuccess(c, "Notifications fetched successfully", notifications)
+}
+
+func MarkNotificationRead(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.RespondFailed(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	var notification models.Notification
+	if err := config.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&notification).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Notification not found", nil)
+		return
+	}
+
+	if notification.ReadAt == nil {
+		now := time.Now()
+		notification.ReadAt = &now
+		if err := config.DB.Model(&notification).Update("read_at", now).Error; err != nil {
+			utils.RespondFailed(c, http.StatusInternalServerError, "Failed to update notification", nil)
+			return
+		}
+	}
+
+	utils.RespondSuccess(c, "Notification marked as read", notification)
+}
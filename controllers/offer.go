@@ -0,0 +1,488 @@
+package controllers
+
+import (
+	"net/http"
+	"recyco/config"
+	"recyco/models"
+	"recyco/services"
+	"recyco/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultOfferValidity = 48 * time.Hour
+	maxOfferValidity     = 30 * 24 * time.Hour
+)
+
+type OfferInput struct {
+	ItemID                    string       `form:"item_id" binding:"required"`
+	Price                     models.Money `form:"price" binding:"required"`
+	Message                   string       `form:"message"`
+	ExpiresInHours            int          `form:"expires_in_hours"`
+	Fulfillment               string       `form:"fulfillment"`
+	RecipientName             string       `form:"recipient_name" binding:"required"`
+	RecipientPhone            string       `form:"recipient_phone" binding:"required"`
+	PickupLocationAddress     string       `form:"pickup_location_address"`
+	PickupLocationDescription string       `form:"pickup_location_description"`
+	PickupLat                 *float64     `form:"pickup_lat"`
+	PickupLon                 *float64     `form:"pickup_lon"`
+}
+
+type CounterOfferInput struct {
+	Price          models.Money `form:"price" binding:"required"`
+	Message        string       `form:"message"`
+	ExpiresInHours int          `form:"expires_in_hours"`
+}
+
+func offerValidity(hours int) (time.Duration, bool) {
+	if hours == 0 {
+		return defaultOfferValidity, true
+	}
+	validity := time.Duration(hours) * time.Hour
+	return validity, hours > 0 && validity <= maxOfferValidity
+}
+
+// offerParty returns which side of offer the user is on, or an empty string.
+func offerParty(offer models.Offer, userID interface{}) string {
+	switch userID {
+	case offer.BuyerID:
+		return services.PartyBuyer
+	case offer.SellerID:
+		return services.PartySeller
+	}
+	return ""
+}
+
+func offerResponse(offer models.Offer) map[string]interface{} {
+	return map[string]interface{}{
+		"id":                          offer.ID,
+		"item_id":                     offer.ItemID,
+		"item_name":                   offer.MarketItem.Name,
+		"buyer":                       orderUserResponse(offer.Buyer),
+		"seller":                      orderUserResponse(offer.Seller),
+		"parent_offer_id":             offer.ParentOfferID,
+		"proposed_by":                 offer.ProposedBy,
+		"price":                       offer.Price,
+		"currency":                    offer.Currency,
+		"message":                     offer.Message,
+		"status":                      offer.Status,
+		"expires_at":                  offer.ExpiresAt.Format(time.RFC3339),
+		"fulfillment":                 offer.Fulfillment,
+		"recipient_name":              offer.RecipientName,
+		"recipient_phone":             offer.RecipientPhone,
+		"pickup_location_address":     offer.PickupLocationAddress,
+		"pickup_location_description": offer.PickupLocationDescription,
+		"order_id":                    offer.OrderID,
+		"created_at":                  offer.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func preloadOffer(db *gorm.DB) *gorm.DB {
+	return db.Preload("MarketItem", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("Buyer").
+		Preload("Seller")
+}
+
+// loadPendingOffer fetches the offer in the URL and makes sure it can still be
+// acted on, marking it EXPIRED when its deadline has passed. It writes the
+// error response itself and reports whether the caller may continue.
+func loadPendingOffer(c *gin.Context) (models.Offer, bool) {
+	var offer models.Offer
+	if err := preloadOffer(config.DB).Where("id = ?", c.Param("id")).First(&offer).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Offer not found", nil)
+		return offer, false
+	}
+
+	userID, _ := c.Get("userID")
+	if offerParty(offer, userID) == "" {
+		utils.RespondFailed(c, http.StatusForbidden, "You do not have permission to access this resource", nil)
+		return offer, false
+	}
+
+	if offer.Status == models.OfferStatusPending && offer.ExpiresAt.Before(time.Now()) {
+		config.DB.Model(&models.Offer{}).
+			Where("id = ? AND status = ?", offer.ID, models.OfferStatusPending).
+			Update("status", models.OfferStatusExpired)
+		offer.Status = models.OfferStatusExpired
+	}
+
+	if offer.Status != models.OfferStatusPending {
+		utils.RespondFailed(c, http.StatusConflict, "Offer is already "+offer.Status, nil)
+		return offer, false
+	}
+
+	return offer, true
+}
+
+// moveOffer changes a pending offer's status inside tx. It reports false when
+// another request already moved the offer.
+func moveOffer(tx *gorm.DB, offer models.Offer, status string) (bool, error) {
+	result := tx.Model(&models.Offer{}).
+		Where("id = ? AND status = ?", offer.ID, models.OfferStatusPending).
+		Updates(map[string]interface{}{"Status": status, "UpdatedAt": time.Now()})
+	return result.RowsAffected == 1, result.Error
+}
+
+func CreateOffer(c *gin.Context) {
+	var input OfferInput
+	if err := c.ShouldBind(&input); err != nil {
+		utils.RespondFailed(c, http.StatusBadRequest, "Input tidak valid", nil)
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.RespondFailed(c, http.StatusInternalServerError, "User ID not found in context", nil)
+		return
+	}
+
+	userRole, exists := c.Get("userRole")
+	if !exists {
+		utils.RespondFailed(c, http.StatusInternalServerError, "User role not found in context", nil)
+		return
+	}
+
+	if input.Price <= 0 {
+		utils.RespondFailed(c, http.StatusBadRequest, "Price must be greater than 0", nil)
+		return
+	}
+
+	validity, ok := offerValidity(input.ExpiresInHours)
+	if !ok {
+		utils.RespondFailed(c, http.StatusBadRequest, "expires_in_hours must be between 1 and 720", nil)
+		return
+	}
+
+	var marketItem models.MarketItems
+	if err := config.DB.Where("id = ?", input.ItemID).First(&marketItem).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Market item not found", nil)
+		return
+	}
+
+	rules, err := services.LoadMarketRules(config.DB)
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to load marketplace rules", nil)
+		return
+	}
+
+	if !rules.CanBuy(userRole.(string), marketItem.ItemScale) {
+		utils.RespondFailed(c, http.StatusForbidden, "You do not have permission to order this market item", nil)
+		return
+	}
+
+	if marketItem.PostedBy == userID {
+		utils.RespondFailed(c, http.StatusBadRequest, "You cannot make an offer on your own market item", nil)
+		return
+	}
+
+	if marketItem.Status != models.MarketItemStatusReady {
+		utils.RespondFailed(c, http.StatusConflict, "Market item is not available for order", nil)
+		return
+	}
+
+	if marketItem.ListingType == models.ListingTypeAuction {
+		utils.RespondFailed(c, http.StatusConflict, "Market item is being auctioned", nil)
+		return
+	}
+
+	if marketItem.ItemScale == "LARGE" {
+		input.Fulfillment = models.FulfillmentDelivery
+	} else if input.Fulfillment != models.FulfillmentSelfPickup && input.Fulfillment != models.FulfillmentDropOff {
+		utils.RespondFailed(c, http.StatusBadRequest, "Fulfillment must be SELF_PICKUP or DROP_OFF", nil)
+		return
+	}
+
+	if input.Fulfillment != models.FulfillmentSelfPickup && input.PickupLocationAddress == "" {
+		utils.RespondFailed(c, http.StatusBadRequest, "pickup_location_address is required", nil)
+		return
+	}
+
+	var pending int64
+	if err := config.DB.Model(&models.Offer{}).
+		Where("item_id = ? AND buyer_id = ? AND status = ?", marketItem.ID, userID, models.OfferStatusPending).
+		Count(&pending).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to create offer", nil)
+		return
+	}
+	if pending > 0 {
+		utils.RespondFailed(c, http.StatusConflict, "You already have a pending offer on this market item", nil)
+		return
+	}
+
+	offer := models.Offer{
+		ItemID:                    marketItem.ID,
+		BuyerID:                   userID.(uuid.UUID),
+		SellerID:                  marketItem.PostedBy,
+		ProposedBy:                services.PartyBuyer,
+		Price:                     input.Price,
+		Currency:                  marketItem.Currency,
+		Message:                   input.Message,
+		Status:                    models.OfferStatusPending,
+		ExpiresAt:                 time.Now().Add(validity),
+		Fulfillment:               input.Fulfillment,
+		RecipientName:             input.RecipientName,
+		RecipientPhone:            input.RecipientPhone,
+		PickupLocationAddress:     input.PickupLocationAddress,
+		PickupLocationDescription: input.PickupLocationDescription,
+		PickupLat:                 input.PickupLat,
+		PickupLon:                 input.PickupLon,
+	}
+
+	if err := config.DB.Create(&offer).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to create offer", nil)
+		return
+	}
+
+	offer.MarketItem = marketItem
+	utils.RespondSuccess(c, "Offer created successfully", offerResponse(offer))
+}
+
+func GetOffers(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.RespondFailed(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	query := preloadOffer(config.DB).Where("buyer_id = ? OR seller_id = ?", userID, userID)
+	if itemID := c.Query("item_id"); itemID != "" {
+		query = query.Where("item_id = ?", itemID)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var offers []models.Offer
+	if err := query.Order("created_at desc").Find(&offers).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to fetch offers", nil)
+		return
+	}
+
+	responseItems := []map[string]interface{}{}
+	for _, offer := range offers {
+		responseItems = append(responseItems, offerResponse(offer))
+	}
+
+	utils.RespondSuccess(c, "Offers fetched successfully", responseItems)
+}
+
+func GetOfferByID(c *gin.Context) {
+	var offer models.Offer
+	if err := preloadOffer(config.DB).Where("id = ?", c.Param("id")).First(&offer).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Offer not found", nil)
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	if offerParty(offer, userID) == "" {
+		utils.RespondFailed(c, http.StatusForbidden, "You do not have permission to access this resource", nil)
+		return
+	}
+
+	utils.RespondSuccess(c, "Offer fetched successfully", offerResponse(offer))
+}
+
+// CounterOffer answers a pending offer from the other side with a new price.
+// The answered offer becomes COUNTERED and the counter-offer is pending.
+func CounterOffer(c *gin.Context) {
+	var input CounterOfferInput
+	if err := c.ShouldBind(&input); err != nil {
+		utils.RespondFailed(c, http.StatusBadRequest, "Input tidak valid", nil)
+		return
+	}
+
+	if input.Price <= 0 {
+		utils.RespondFailed(c, http.StatusBadRequest, "Price must be greater than 0", nil)
+		return
+	}
+
+	validity, ok := offerValidity(input.ExpiresInHours)
+	if !ok {
+		utils.RespondFailed(c, http.StatusBadRequest, "expires_in_hours must be between 1 and 720", nil)
+		return
+	}
+
+	offer, ok := loadPendingOffer(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	party := offerParty(offer, userID)
+	if party == offer.ProposedBy {
+		utils.RespondFailed(c, http.StatusForbidden, "You cannot counter your own offer", nil)
+		return
+	}
+
+	tx := config.DB.Begin()
+	if tx.Error != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to start transaction", nil)
+		return
+	}
+
+	moved, err := moveOffer(tx, offer, models.OfferStatusCountered)
+	if err != nil {
+		tx.Rollback()
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to counter offer", nil)
+		return
+	}
+	if !moved {
+		tx.Rollback()
+		utils.RespondFailed(c, http.StatusConflict, "Offer was changed by another request", nil)
+		return
+	}
+
+	counter := offer
+	counter.ParentOfferID = &offer.ID
+	counter.ProposedBy = party
+	counter.Price = input.Price
+	counter.Message = input.Message
+	counter.Status = models.OfferStatusPending
+	counter.ExpiresAt = time.Now().Add(validity)
+	counter.OrderID = nil
+
+	if err := tx.Omit("MarketItem", "Buyer", "Seller").Create(&counter).Error; err != nil {
+		tx.Rollback()
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to counter offer", nil)
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to commit transaction", nil)
+		return
+	}
+
+	utils.RespondSuccess(c, "Counter offer created successfully", offerResponse(counter))
+}
+
+// AcceptOffer closes the negotiation: the order is placed at the offered
+// price with the buyer's handover details, and every other pending offer on
+// the item is rejected.
+func AcceptOffer(c *gin.Context) {
+	offer, ok := loadPendingOffer(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	if offerParty(offer, userID) == offer.ProposedBy {
+		utils.RespondFailed(c, http.StatusForbidden, "You cannot accept your own offer", nil)
+		return
+	}
+
+	tx := config.DB.Begin()
+	if tx.Error != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to start transaction", nil)
+		return
+	}
+
+	moved, err := moveOffer(tx, offer, models.OfferStatusAccepted)
+	if err != nil {
+		tx.Rollback()
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to accept offer", nil)
+		return
+	}
+	if !moved {
+		tx.Rollback()
+		utils.RespondFailed(c, http.StatusConflict, "Offer was changed by another request", nil)
+		return
+	}
+
+	var marketItem models.MarketItems
+	if err := tx.Where("id = ?", offer.ItemID).First(&marketItem).Error; err != nil {
+		tx.Rollback()
+		utils.RespondFailed(c, http.StatusConflict, "Market item is no longer available", nil)
+		return
+	}
+
+	pickupInformation := models.MarketItemPickupInformations{
+		RecipientName:             offer.RecipientName,
+		RecipientPhone:            offer.RecipientPhone,
+		Description:               offer.Message,
+		PickupLocationAddress:     offer.PickupLocationAddress,
+		PickupLocationDescription: offer.PickupLocationDescription,
+		PickupLat:                 offer.PickupLat,
+		PickupLon:                 offer.PickupLon,
+	}
+
+	order, _, err := services.PlaceOrder(tx, marketItem, offer.BuyerID, &pickupInformation, offer.Price, offer.Fulfillment)
+	if err != nil {
+		tx.Rollback()
+		respondTransitionError(c, err)
+		return
+	}
+
+	if err := tx.Model(&models.Offer{}).Where("id = ?", offer.ID).Update("order_id", order.ID).Error; err != nil {
+		tx.Rollback()
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to accept offer", nil)
+		return
+	}
+
+	if err := tx.Model(&models.Offer{}).
+		Where("item_id = ? AND status = ? AND id <> ?", offer.ItemID, models.OfferStatusPending, offer.ID).
+		Updates(map[string]interface{}{"Status": models.OfferStatusRejected, "UpdatedAt": time.Now()}).Error; err != nil {
+		tx.Rollback()
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to reject other offers", nil)
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to commit transaction", nil)
+		return
+	}
+
+	offer.Status = models.OfferStatusAccepted
+	offer.OrderID = &order.ID
+	utils.RespondSuccess(c, "Offer accepted successfully", offerResponse(offer))
+}
+
+func RejectOffer(c *gin.Context) {
+	offer, ok := loadPendingOffer(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	if offerParty(offer, userID) == offer.ProposedBy {
+		utils.RespondFailed(c, http.StatusForbidden, "Withdraw your own offer instead of rejecting it", nil)
+		return
+	}
+
+	finishOffer(c, offer, models.OfferStatusRejected, "Offer rejected successfully")
+}
+
+func WithdrawOffer(c *gin.Context) {
+	offer, ok := loadPendingOffer(c)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	if offerParty(offer, userID) != offer.ProposedBy {
+		utils.RespondFailed(c, http.StatusForbidden, "Only the party who made the offer can withdraw it", nil)
+		return
+	}
+
+	finishOffer(c, offer, models.OfferStatusWithdrawn, "Offer withdrawn successfully")
+}
+
+func finishOffer(c *gin.Context, offer models.Offer, status string, message string) {
+	moved, err := moveOffer(config.DB, offer, status)
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to update offer", nil)
+		return
+	}
+	if !moved {
+		utils.RespondFailed(c, http.StatusConflict, "Offer was changed by another request", nil)
+		return
+	}
+
+	offer.Status = status
+	utils.RespondSuccess(c, message, offerResponse(offer))
+}
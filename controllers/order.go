@@ -0,0 +1,476 @@
+package controllers
+
+import (
+	"net/http"
+	"recyco/config"
+	"recyco/models"
+	"recyco/services"
+	"recyco/utils"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type OrderStatusUpdateInput struct {
+	Status     string `form:"status" binding:"required"`
+	ReasonCode string `form:"reason_code"`
+	Reason     string `form:"reason"`
+}
+
+type CancelOrderInput struct {
+	ReasonCode string `form:"reason_code" binding:"required"`
+	Reason     string `form:"reason"`
+}
+
+func preloadOrder(db *gorm.DB) *gorm.DB {
+	return db.Preload("MarketItem", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("Buyer").
+		Preload("Seller").
+		Preload("PickupInformation").
+		Preload("PickupSlot").
+		Preload("Driver").
+		Preload("Activities", func(db *gorm.DB) *gorm.DB { return db.Order("created_at asc") }).
+		Preload("Activities.Actor").
+		Preload("Activities.ProofPhotos").
+		Preload("Disputes").
+		Preload("Invoice")
+}
+
+func findLatestOrderForItem(itemID interface{}) (models.Order, error) {
+	var order models.Order
+	err := preloadOrder(config.DB).Where("item_id = ?", itemID).Order("created_at desc").First(&order).Error
+	return order, err
+}
+
+func isOrderParty(order models.Order, userID interface{}) bool {
+	return order.BuyerID == userID || order.SellerID == userID
+}
+
+// orderParty returns whether userID is the buyer or the seller of order, or
+// "" when they are neither.
+func orderParty(order models.Order, userID interface{}) string {
+	switch userID {
+	case order.BuyerID:
+		return services.PartyBuyer
+	case order.SellerID:
+		return services.PartySeller
+	}
+	return ""
+}
+
+func isOrderDriver(order models.Order, userID interface{}) bool {
+	return order.DriverID != nil && *order.DriverID == userID
+}
+
+func orderUserResponse(user models.User) map[string]interface{} {
+	if user.ID == uuid.Nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"id":           user.ID,
+		"phone_number": user.PhoneNumber,
+		"name":         user.Name,
+		"role":         user.Role,
+	}
+}
+
+// actorResponse identifies who performed a step of an order, such as the
+// driver who picked it up. System steps have no actor.
+func actorResponse(user *models.User) map[string]interface{} {
+	if user == nil || user.ID == uuid.Nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"id":   user.ID,
+		"name": user.Name,
+		"role": user.Role,
+	}
+}
+
+func orderResponse(order models.Order) map[string]interface{} {
+	item := map[string]interface{}{
+		"id":                 order.MarketItem.ID,
+		"name":               order.MarketItem.Name,
+		"price":              order.MarketItem.Price,
+		"price_per_kg":       order.MarketItem.PricePerKg,
+		"currency":           order.MarketItem.Currency,
+		"weight":             order.MarketItem.Weight,
+		"weight_unit":        order.MarketItem.WeightUnit,
+		"display_weight":     models.WeightInUnit(order.MarketItem.Weight, order.MarketItem.WeightUnit),
+		"quantity":           order.MarketItem.Quantity,
+		"quantity_unit":      order.MarketItem.QuantityUnit,
+		"weight_is_estimate": order.MarketItem.WeightIsEstimate,
+		"description":        order.MarketItem.Description,
+		"thumbnail_url":      order.MarketItem.ThumbnailUrl,
+		"status":             order.MarketItem.Status,
+		"posted_at":          order.MarketItem.CreatedAt.Format(time.RFC3339),
+	}
+
+	pickup := order.PickupInformation
+	pickupInformation := map[string]interface{}{
+		"id":                          pickup.ID,
+		"recipient_name":              pickup.RecipientName,
+		"recipient_phone":             pickup.RecipientPhone,
+		"description":                 pickup.Description,
+		"pickup_location_address":     pickup.PickupLocationAddress,
+		"pickup_location_description": pickup.PickupLocationDescription,
+		"service_price":               pickup.ServicePrice,
+		"delivery_price":              pickup.DeliveryPrice,
+		"distance_km":                 pickup.DistanceKm,
+		"tariff_id":                   pickup.TariffID,
+	}
+
+	weighing := map[string]interface{}{
+		"priced_per_kg":           order.PricedPerKg,
+		"declared_weight":         order.DeclaredWeight,
+		"measured_weight":         order.MeasuredWeight,
+		"declared_item_price":     order.DeclaredItemPrice,
+		"declared_service_price":  order.DeclaredServicePrice,
+		"declared_delivery_price": order.DeclaredDeliveryPrice,
+		"declared_total_price":    order.DeclaredItemPrice + order.DeclaredServicePrice + order.DeclaredDeliveryPrice,
+		"discrepancy":             order.WeightDiscrepancy,
+		"discrepancy_flagged":     order.WeightDiscrepancyFlagged,
+	}
+
+	// The timeline interleaves the status changes with the disputes opened
+	// and resolved on the order.
+	type timelineEvent struct {
+		at    time.Time
+		entry map[string]interface{}
+	}
+	events := []timelineEvent{}
+	for _, activity := range order.Activities {
+		events = append(events, timelineEvent{activity.CreatedAt, map[string]interface{}{
+			"id":              activity.ID,
+			"event":           "STATUS",
+			"status":          activity.Status,
+			"actor_id":        activity.ActorID,
+			"actor_party":     activity.ActorParty,
+			"actor":           actorResponse(activity.Actor),
+			"reason_code":     activity.ReasonCode,
+			"reason":          activity.Reason,
+			"handover_weight": activity.HandoverWeight,
+			"pin_verified":    activity.PinVerified,
+			"proof_photos":    proofPhotoUrls(activity.ProofPhotos),
+			"created_at":      activity.CreatedAt.Format(time.RFC3339),
+		}})
+	}
+	openDispute := false
+	for _, dispute := range order.Disputes {
+		openDispute = openDispute || dispute.Status == models.DisputeStatusOpen
+		events = append(events, timelineEvent{dispute.CreatedAt, map[string]interface{}{
+			"id":          dispute.ID,
+			"event":       "DISPUTE_OPENED",
+			"actor_id":    dispute.OpenedByID,
+			"actor_party": dispute.OpenedByParty,
+			"reason":      dispute.Reason + ": " + dispute.Description,
+			"created_at":  dispute.CreatedAt.Format(time.RFC3339),
+		}})
+		if dispute.ResolvedAt != nil {
+			events = append(events, timelineEvent{*dispute.ResolvedAt, map[string]interface{}{
+				"id":            dispute.ID,
+				"event":         "DISPUTE_RESOLVED",
+				"actor_id":      dispute.ResolvedByID,
+				"actor_party":   services.PartyAdmin,
+				"reason":        dispute.ResolutionNote,
+				"resolution":    dispute.Resolution,
+				"refund_amount": dispute.RefundAmount,
+				"created_at":    dispute.ResolvedAt.Format(time.RFC3339),
+			}})
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].at.Before(events[j].at) })
+
+	var invoiceNumber interface{}
+	if order.Invoice != nil {
+		invoiceNumber = order.Invoice.Number
+	}
+
+	timeline := []map[string]interface{}{}
+	for _, event := range events {
+		timeline = append(timeline, event.entry)
+	}
+
+	return map[string]interface{}{
+		"id":                 order.ID,
+		"item":               item,
+		"buyer":              orderUserResponse(order.Buyer),
+		"seller":             orderUserResponse(order.Seller),
+		"driver":             actorResponse(order.Driver),
+		"pickup_information": pickupInformation,
+		"item_price":         order.ItemPrice,
+		"service_price":      pickup.ServicePrice,
+		"delivery_price":     pickup.DeliveryPrice,
+		"total_price":        services.OrderTotal(order, pickup),
+		"refunded_amount":    order.RefundedAmount,
+		"disputed":           openDispute,
+		"currency":           order.Currency,
+		"fulfillment":        order.Fulfillment,
+		"status":             order.Status,
+		"reserved_until":     order.ReservedUntil,
+		"contract_id":        order.ContractID,
+		"pickup_slot":        pickupSlotResponse(order.PickupSlot),
+		"weighing":           weighing,
+		"invoice_number":     invoiceNumber,
+		"timeline":           timeline,
+		"created_at":         order.CreatedAt.Format(time.RFC3339),
+		"updated_at":         order.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// updateOrderStatus applies a status change by the seller, or a cancellation
+// by the buyer, to order and writes the response. It is shared by the order
+// and the legacy item-keyed routes.
+func updateOrderStatus(c *gin.Context, order models.Order, input OrderStatusUpdateInput) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.RespondFailed(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	party := orderParty(order, userID)
+	if party == "" || (party == services.PartyBuyer && input.Status != services.TransactionStatusCancelled) {
+		utils.RespondFailed(c, http.StatusForbidden, "You do not have permission to access this resource", nil)
+		return
+	}
+
+	var transaction models.MarketItemTransactionActivities
+	var ok bool
+	switch input.Status {
+	case services.TransactionStatusFinished:
+		transaction, ok = completeOrder(c, &order, party, userID.(uuid.UUID))
+	case services.TransactionStatusCancelled:
+		transaction, ok = cancelOrder(c, &order, party, userID.(uuid.UUID), input.ReasonCode, input.Reason)
+	default:
+		transaction, ok = recordOrderStatus(c, &order, input.Status, party, userID.(uuid.UUID), input.Reason)
+	}
+	if !ok {
+		return
+	}
+
+	utils.RespondSuccess(c, "Transaction status updated successfully", transitionResponse(order, transaction))
+}
+
+func transitionResponse(order models.Order, transaction models.MarketItemTransactionActivities) map[string]interface{} {
+	return map[string]interface{}{
+		"id":              transaction.ID,
+		"order_id":        order.ID,
+		"item_id":         transaction.ItemID,
+		"status":          transaction.Status,
+		"reason_code":     transaction.ReasonCode,
+		"reason":          transaction.Reason,
+		"handover_weight": transaction.HandoverWeight,
+		"pin_verified":    transaction.PinVerified,
+		"proof_photos":    proofPhotoUrls(transaction.ProofPhotos),
+		"created_at":      transaction.CreatedAt,
+		"updated_at":      transaction.UpdatedAt,
+		"transaction_by":  transaction.TransactionByID,
+	}
+}
+
+// cancelOrder cancels order as party in its own transaction, lets both
+// parties know and writes the error response itself when that fails.
+func cancelOrder(c *gin.Context, order *models.Order, party string, actorID uuid.UUID, code, reason string) (models.MarketItemTransactionActivities, bool) {
+	code = strings.ToUpper(code)
+	if !services.IsValidCancellationReason(party, code) {
+		utils.RespondFailed(c, http.StatusBadRequest, "reason_code must be one of "+strings.Join(services.CancellationReasons[party], ", "), nil)
+		return models.MarketItemTransactionActivities{}, false
+	}
+	if code == services.CancelReasonOther && reason == "" {
+		utils.RespondFailed(c, http.StatusBadRequest, "A reason is required when reason_code is OTHER", nil)
+		return models.MarketItemTransactionActivities{}, false
+	}
+	if party == services.PartyBuyer && order.Status != services.TransactionStatusOnProcess {
+		utils.RespondFailed(c, http.StatusConflict, "Buyers can only cancel orders before they are on delivery", nil)
+		return models.MarketItemTransactionActivities{}, false
+	}
+
+	var activity models.MarketItemTransactionActivities
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		activity, err = services.CancelOrder(tx, order, party, actorID, code, reason)
+		if err != nil {
+			return err
+		}
+		return services.NotifyOrderParties(tx, *order, services.NotificationOrderCancelled,
+			"Order cancelled",
+			"The order was cancelled by the "+strings.ToLower(party)+": "+activity.Reason)
+	})
+	if err != nil {
+		respondTransitionError(c, err)
+		return activity, false
+	}
+
+	sendOrderRefunds(order.ID)
+	return activity, true
+}
+
+// CancelOrder lets the buyer or the seller cancel an order with a reason code.
+// The buyer can only cancel before the order is on delivery.
+func CancelOrder(c *gin.Context) {
+	var input CancelOrderInput
+	if err := c.ShouldBind(&input); err != nil {
+		utils.RespondFailed(c, http.StatusBadRequest, "reason_code is required", nil)
+		return
+	}
+
+	var order models.Order
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&order).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Order not found", nil)
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	party := orderParty(order, userID)
+	if party == "" {
+		utils.RespondFailed(c, http.StatusForbidden, "You do not have permission to access this resource", nil)
+		return
+	}
+
+	activity, ok := cancelOrder(c, &order, party, userID.(uuid.UUID), input.ReasonCode, input.Reason)
+	if !ok {
+		return
+	}
+
+	utils.RespondSuccess(c, "Order cancelled successfully", transitionResponse(order, activity))
+}
+
+// recordOrderStatus moves order to any status but FINISHED in its own
+// transaction and writes the error response itself when that fails.
+func recordOrderStatus(c *gin.Context, order *models.Order, status, party string, actorID uuid.UUID, reason string) (models.MarketItemTransactionActivities, bool) {
+	tx := config.DB.Begin()
+	if tx.Error != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to start transaction", nil)
+		return models.MarketItemTransactionActivities{}, false
+	}
+
+	activity, err := services.RecordTransactionStatus(tx, order, status, party, actorID, reason)
+	if err != nil {
+		tx.Rollback()
+		respondTransitionError(c, err)
+		return activity, false
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to commit transaction", nil)
+		return activity, false
+	}
+
+	return activity, true
+}
+
+func proofPhotoUrls(photos []models.DeliveryProofPhoto) []string {
+	urls := []string{}
+	for _, photo := range photos {
+		urls = append(urls, photo.Url)
+	}
+	return urls
+}
+
+func GetOrders(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.RespondFailed(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	query := preloadOrder(config.DB)
+	switch c.Query("as") {
+	case "buyer":
+		query = query.Where("buyer_id = ?", userID)
+	case "seller":
+		query = query.Where("seller_id = ?", userID)
+	case "":
+		query = query.Where("buyer_id = ? OR seller_id = ?", userID, userID)
+	default:
+		utils.RespondFailed(c, http.StatusBadRequest, "Invalid value for as", nil)
+		return
+	}
+
+	if status := c.Query("status"); status != "" {
+		if !services.IsValidTransactionStatus(status) {
+			utils.RespondFailed(c, http.StatusBadRequest, "Invalid status value", nil)
+			return
+		}
+		query = query.Where("status = ?", status)
+	}
+
+	var orders []models.Order
+	if err := query.Order("created_at desc").Find(&orders).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to fetch orders", nil)
+		return
+	}
+
+	responseItems := []map[string]interface{}{}
+	for _, order := range orders {
+		responseItems = append(responseItems, orderResponse(order))
+	}
+
+	utils.RespondSuccess(c, "Orders fetched successfully", responseItems)
+}
+
+func GetOrderByID(c *gin.Context) {
+	var order models.Order
+	if err := preloadOrder(config.DB).Where("id = ?", c.Param("id")).First(&order).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Order not found", nil)
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	userRole, _ := c.Get("userRole")
+	if !isOrderParty(order, userID) && !isOrderDriver(order, userID) && userRole != "ADMIN" {
+		utils.RespondFailed(c, http.StatusForbidden, "You do not have permission to access this resource", nil)
+		return
+	}
+
+	utils.RespondSuccess(c, "Order fetched successfully", orderResponse(order))
+}
+
+func UpdateOrderStatus(c *gin.Context) {
+	var input OrderStatusUpdateInput
+	if err := c.ShouldBind(&input); err != nil {
+		utils.RespondFailed(c, http.StatusBadRequest, "Input tidak valid", nil)
+		return
+	}
+
+	var order models.Order
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&order).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Order not found", nil)
+		return
+	}
+
+	updateOrderStatus(c, order, input)
+}
+
+func GetMarketItemOrders(c *gin.Context) {
+	var item models.MarketItems
+	if err := config.DB.Unscoped().Where("id = ?", c.Param("id")).First(&item).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Market item not found", nil)
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	userRole, _ := c.Get("userRole")
+	if item.PostedBy != userID && userRole != "ADMIN" {
+		utils.RespondFailed(c, http.StatusForbidden, "You do not have permission to access this resource", nil)
+		return
+	}
+
+	var orders []models.Order
+	if err := preloadOrder(config.DB).Where("item_id = ?", item.ID).Order("created_at desc").Find(&orders).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to fetch orders", nil)
+		return
+	}
+
+	responseItems := []map[string]interface{}{}
+	for _, order := range orders {
+		responseItems = append(responseItems, orderResponse(order))
+	}
+
+	utils.RespondSuccess(c, "Market item orders fetched successfully", responseItems)
+}
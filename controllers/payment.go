@@ -0,0 +1,304 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"recyco/config"
+	"recyco/models"
+	"recyco/services"
+	"recyco/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PaymentSimulationInput struct {
+	Outcome string `form:"outcome"`
+}
+
+type PayoutPaidInput struct {
+	Reference string `form:"reference" binding:"required"`
+}
+
+// findPaymentOrder loads the order of the request and checks that the user
+// is one of its parties or an admin.
+func findPaymentOrder(c *gin.Context) (models.Order, bool) {
+	var order models.Order
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&order).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Order not found", nil)
+		return order, false
+	}
+
+	userID, _ := c.Get("userID")
+	userRole, _ := c.Get("userRole")
+	if !isOrderParty(order, userID) && userRole != "ADMIN" {
+		utils.RespondFailed(c, http.StatusForbidden, "You do not have permission to access this resource", nil)
+		return order, false
+	}
+	return order, true
+}
+
+// sendOrderRefunds passes the refunds recorded for an order on to the
+// payment provider once the transaction that recorded them is committed.
+// Refunds that cannot be sent now are retried by the refund sender job.
+func sendOrderRefunds(orderID uuid.UUID) {
+	if services.Payments == nil {
+		return
+	}
+	if _, err := services.SendRefunds(config.DB, services.Payments, orderID); err != nil {
+		log.Printf("refunds of order %s: %v", orderID, err)
+	}
+}
+
+func respondPaymentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrPaymentsDisabled):
+		utils.RespondFailed(c, http.StatusServiceUnavailable, "Payments are not available", nil)
+	case errors.Is(err, services.ErrPaymentNotAllowed):
+		utils.RespondFailed(c, http.StatusConflict, "Only orders in progress can be paid", nil)
+	case errors.Is(err, services.ErrAlreadyPaid):
+		utils.RespondFailed(c, http.StatusConflict, "This order is already paid", nil)
+	case errors.Is(err, services.ErrWebhookSignature):
+		utils.RespondFailed(c, http.StatusUnauthorized, "Invalid webhook signature", nil)
+	case errors.Is(err, services.ErrUnknownCharge):
+		utils.RespondFailed(c, http.StatusNotFound, "Charge not found", nil)
+	case errors.Is(err, services.ErrPaymentProvider):
+		utils.RespondFailed(c, http.StatusConflict, "Payment belongs to another payment provider", nil)
+	case errors.Is(err, services.ErrPaymentAmount):
+		utils.RespondFailed(c, http.StatusBadRequest, "Amount does not match the payment", nil)
+	case errors.Is(err, services.ErrPayoutPaid):
+		utils.RespondFailed(c, http.StatusConflict, "Payout is already paid", nil)
+	default:
+		respondTransitionError(c, err)
+	}
+}
+
+// CreateOrderPayment starts the buyer's payment of an order and returns the
+// checkout URL to complete it at. The money is held in escrow until the order
+// is finished.
+func CreateOrderPayment(c *gin.Context) {
+	if services.Payments == nil {
+		respondPaymentError(c, services.ErrPaymentsDisabled)
+		return
+	}
+
+	var order models.Order
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&order).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Order not found", nil)
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	if order.BuyerID != userID {
+		utils.RespondFailed(c, http.StatusForbidden, "Only the buyer can pay for this order", nil)
+		return
+	}
+
+	payment, err := services.StartPayment(config.DB, services.Payments, order)
+	if err != nil {
+		respondPaymentError(c, err)
+		return
+	}
+
+	utils.RespondSuccess(c, "Payment created successfully", payment)
+}
+
+// GetOrderPayments lists the payments of an order with its ledger entries,
+// oldest first.
+func GetOrderPayments(c *gin.Context) {
+	order, ok := findPaymentOrder(c)
+	if !ok {
+		return
+	}
+
+	var payments []models.Payment
+	if err := config.DB.Where("order_id = ?", order.ID).Order("created_at asc").Find(&payments).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to fetch payments", nil)
+		return
+	}
+
+	var entries []models.LedgerEntry
+	if err := config.DB.Where("order_id = ?", order.ID).Order("created_at asc").Find(&entries).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to fetch ledger entries", nil)
+		return
+	}
+
+	var payout *models.Payout
+	var found models.Payout
+	if err := config.DB.Where("order_id = ?", order.ID).First(&found).Error; err == nil {
+		payout = &found
+	}
+
+	utils.RespondSuccess(c, "Payments fetched successfully", map[string]interface{}{
+		"payments": payments,
+		"ledger":   entries,
+		"payout":   payout,
+	})
+}
+
+// applyPaymentWebhook verifies and applies a webhook body of the configured
+// provider and writes the response.
+func applyPaymentWebhook(c *gin.Context, payload []byte, signature string) {
+	event, err := services.Payments.VerifyWebhook(payload, signature)
+	if errors.Is(err, services.ErrWebhookSignature) {
+		respondPaymentError(c, err)
+		return
+	}
+	if err != nil {
+		utils.RespondFailed(c, http.StatusBadRequest, "Input tidak valid", nil)
+		return
+	}
+
+	var payment models.Payment
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		payment, err = services.HandlePaymentWebhook(tx, services.Payments, event, time.Now())
+		return err
+	})
+	if err != nil {
+		respondPaymentError(c, err)
+		return
+	}
+
+	sendOrderRefunds(payment.OrderID)
+	utils.RespondSuccess(c, "Webhook processed successfully", payment)
+}
+
+// PaymentWebhook receives charge notifications from the payment provider
+// named in the URL. It is not authenticated with a JWT; the body must carry
+// the provider's signature in the X-Payment-Signature header instead.
+func PaymentWebhook(c *gin.Context) {
+	if services.Payments == nil || c.Param("provider") != services.Payments.Name() {
+		utils.RespondFailed(c, http.StatusNotFound, "Payment provider not found", nil)
+		return
+	}
+
+	payload, err := c.GetRawData()
+	if err != nil {
+		utils.RespondFailed(c, http.StatusBadRequest, "Input tidak valid", nil)
+		return
+	}
+
+	applyPaymentWebhook(c, payload, c.GetHeader("X-Payment-Signature"))
+}
+
+// SimulatePayment stands in for the provider's checkout page while the fake
+// provider is configured for development, the only time it is routed: it
+// sends the webhook the provider would send once
+// the buyer paid, or failed to with ?outcome=failed.
+func SimulatePayment(c *gin.Context) {
+	provider, ok := services.Payments.(*services.FakePaymentProvider)
+	if !ok {
+		utils.RespondFailed(c, http.StatusNotFound, "Payment simulation is not available", nil)
+		return
+	}
+
+	var input PaymentSimulationInput
+	if err := c.ShouldBind(&input); err != nil {
+		utils.RespondFailed(c, http.StatusBadRequest, "Input tidak valid", nil)
+		return
+	}
+
+	eventType := services.PaymentEventChargeSucceeded
+	switch input.Outcome {
+	case "", "succeeded":
+	case "failed":
+		eventType = services.PaymentEventChargeFailed
+	default:
+		utils.RespondFailed(c, http.StatusBadRequest, "Outcome must be succeeded or failed", nil)
+		return
+	}
+
+	var payment models.Payment
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&payment).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Payment not found", nil)
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	userRole, _ := c.Get("userRole")
+	if payment.BuyerID != userID && userRole != "ADMIN" {
+		utils.RespondFailed(c, http.StatusForbidden, "You do not have permission to access this resource", nil)
+		return
+	}
+
+	if payment.ChargeID == nil {
+		utils.RespondFailed(c, http.StatusConflict, "Payment has no charge yet", nil)
+		return
+	}
+
+	payload, signature, err := provider.SimulateWebhook(eventType, *payment.ChargeID, payment.Amount)
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to simulate payment", nil)
+		return
+	}
+
+	applyPaymentWebhook(c, payload, signature)
+}
+
+// GetPayouts lists payouts newest first: all of them for admins, otherwise
+// the seller's own. ?status= filters by PENDING or PAID.
+func GetPayouts(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	userRole, _ := c.Get("userRole")
+
+	query := config.DB.Model(&models.Payout{})
+	if userRole != "ADMIN" {
+		query = query.Where("seller_id = ?", userID)
+	}
+
+	if status := c.Query("status"); status != "" {
+		if status != models.PayoutStatusPending && status != models.PayoutStatusPaid {
+			utils.RespondFailed(c, http.StatusBadRequest, "Invalid status value", nil)
+			return
+		}
+		query = query.Where("status = ?", status)
+	}
+
+	var payouts []models.Payout
+	if err := query.Order("created_at desc").Find(&payouts).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to fetch payouts", nil)
+		return
+	}
+
+	utils.RespondSuccess(c, "Payouts fetched successfully", payouts)
+}
+
+// MarkPayoutPaid records that an admin transferred a payout to its seller.
+func MarkPayoutPaid(c *gin.Context) {
+	var input PayoutPaidInput
+	if err := c.ShouldBind(&input); err != nil {
+		utils.RespondFailed(c, http.StatusBadRequest, "Input tidak valid", nil)
+		return
+	}
+
+	var payout models.Payout
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&payout).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Payout not found", nil)
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		return services.MarkPayoutPaid(tx, &payout, input.Reference, time.Now())
+	})
+	if err != nil {
+		respondPaymentError(c, err)
+		return
+	}
+
+	utils.RespondSuccess(c, "Payout marked as paid", payout)
+}
+
+// GetLedgerBalances sums the escrow ledger by account for admins.
+func GetLedgerBalances(c *gin.Context) {
+	balances, err := services.LedgerBalances(config.DB)
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to fetch ledger balances", nil)
+		return
+	}
+
+	utils.RespondSuccess(c, "Ledger balances fetched successfully", balances)
+}
@@ -0,0 +1,317 @@
+package controllers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"recyco/config"
+	"recyco/models"
+	"recyco/services"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// setupPayments configures the fake payment provider for the rest of the
+// test and returns a router with its simulate route.
+func setupPayments(t *testing.T) (*gin.Engine, *services.FakePaymentProvider) {
+	t.Helper()
+
+	provider := services.NewFakePaymentProvider("test_webhook_secret")
+	services.Payments = provider
+	t.Cleanup(func() { services.Payments = nil })
+	return setupDatabase(t), provider
+}
+
+// orderNewItem has a new buyer order a new LARGE item of a new seller. It
+// returns the order and the buyer's token.
+func orderNewItem(t *testing.T, router *gin.Engine) (models.Order, string) {
+	t.Helper()
+
+	seller, _ := createUser(t, "P_LARGE")
+	_, buyerToken := createUser(t, "C_LARGE")
+	item := createItem(t, seller, "LARGE")
+
+	assertStatus(t, send(router, http.MethodPost, "/market_transactions/", buyerToken, url.Values{
+		"item_id":                 {item.ID.String()},
+		"recipient_name":          {"Buyer"},
+		"recipient_phone":         {"08123456789"},
+		"pickup_location_address": {"Jl. Merdeka 1"},
+	}), http.StatusOK)
+
+	var order models.Order
+	if err := config.DB.Where("item_id = ?", item.ID).First(&order).Error; err != nil {
+		t.Fatalf("find order: %v", err)
+	}
+	return order, buyerToken
+}
+
+// payOrder starts the buyer's payment of order and settles it through the
+// simulated checkout.
+func payOrder(t *testing.T, router *gin.Engine, order models.Order, buyerToken string) models.Payment {
+	t.Helper()
+
+	recorder := send(router, http.MethodPost, "/orders/"+order.ID.String()+"/payments", buyerToken, nil)
+	assertStatus(t, recorder, http.StatusOK)
+
+	var body struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode payment: %v", err)
+	}
+	assertStatus(t, send(router, http.MethodPost, "/payments/"+body.Data.ID+"/simulate", buyerToken, nil), http.StatusOK)
+
+	var payment models.Payment
+	if err := config.DB.Where("id = ?", body.Data.ID).First(&payment).Error; err != nil {
+		t.Fatalf("find payment: %v", err)
+	}
+	if payment.Status != models.PaymentStatusPaid {
+		t.Fatalf("payment is %s, want %s", payment.Status, models.PaymentStatusPaid)
+	}
+	return payment
+}
+
+// ledgerBalances sums the ledger entries of orderID by account, debits less
+// credits, and fails the test unless every journal balances.
+func ledgerBalances(t *testing.T, orderID uuid.UUID) map[string]models.Money {
+	t.Helper()
+
+	var entries []models.LedgerEntry
+	config.DB.Where("order_id = ?", orderID).Find(&entries)
+
+	balances := map[string]models.Money{}
+	journals := map[uuid.UUID]models.Money{}
+	for _, entry := range entries {
+		balances[entry.Account] += entry.Debit - entry.Credit
+		journals[entry.JournalID] += entry.Debit - entry.Credit
+	}
+	for journalID, net := range journals {
+		if net != 0 {
+			t.Fatalf("journal %s is off by %s", journalID, net)
+		}
+	}
+	return balances
+}
+
+func cancelByBuyer(t *testing.T, router *gin.Engine, order models.Order, buyerToken string) {
+	t.Helper()
+	assertStatus(t, send(router, http.MethodPost, "/orders/"+order.ID.String()+"/cancel", buyerToken, url.Values{
+		"reason_code": {services.CancelReasonChangedMind},
+	}), http.StatusOK)
+}
+
+func TestPaidOrderIsHeldInEscrowAndRefundedOnCancel(t *testing.T) {
+	router, _ := setupPayments(t)
+	order, buyerToken := orderNewItem(t, router)
+	payment := payOrder(t, router, order, buyerToken)
+
+	balances := ledgerBalances(t, order.ID)
+	if balances[models.LedgerAccountProviderCash] != payment.Amount || balances[models.LedgerAccountEscrow] != -payment.Amount {
+		t.Fatalf("balances after paying = %v, want %s in %s and escrow", balances, payment.Amount, models.LedgerAccountProviderCash)
+	}
+
+	cancelByBuyer(t, router, order, buyerToken)
+
+	var refund models.PaymentRefund
+	if err := config.DB.Where("payment_id = ?", payment.ID).First(&refund).Error; err != nil {
+		t.Fatalf("find refund: %v", err)
+	}
+	if refund.Status != models.RefundStatusSent || refund.Amount != payment.Amount {
+		t.Fatalf("refund is %s of %s, want %s of %s", refund.Status, refund.Amount, models.RefundStatusSent, payment.Amount)
+	}
+
+	config.DB.Where("id = ?", payment.ID).First(&payment)
+	if payment.Status != models.PaymentStatusRefunded {
+		t.Fatalf("payment is %s, want %s", payment.Status, models.PaymentStatusRefunded)
+	}
+	for account, balance := range ledgerBalances(t, order.ID) {
+		if balance != 0 {
+			t.Fatalf("%s holds %s after the refund, want 0", account, balance)
+		}
+	}
+}
+
+func TestUnsentRefundIsSentLater(t *testing.T) {
+	router, provider := setupPayments(t)
+	order, buyerToken := orderNewItem(t, router)
+	payment := payOrder(t, router, order, buyerToken)
+
+	// Without a provider the cancellation commits and the refund waits.
+	services.Payments = nil
+	cancelByBuyer(t, router, order, buyerToken)
+
+	var refund models.PaymentRefund
+	config.DB.Where("payment_id = ?", payment.ID).First(&refund)
+	if refund.Status != models.RefundStatusPending {
+		t.Fatalf("refund is %s without a provider, want %s", refund.Status, models.RefundStatusPending)
+	}
+	if balances := ledgerBalances(t, order.ID); balances[models.LedgerAccountRefundsPayable] != -payment.Amount {
+		t.Fatalf("balances with the refund pending = %v, want %s owed to the buyer", balances, payment.Amount)
+	}
+
+	if _, err := services.SendPendingRefunds(config.DB, provider); err != nil {
+		t.Fatalf("send pending refunds: %v", err)
+	}
+	config.DB.Where("id = ?", refund.ID).First(&refund)
+	if refund.Status != models.RefundStatusSent {
+		t.Fatalf("refund is %s after sending, want %s", refund.Status, models.RefundStatusSent)
+	}
+	if balances := ledgerBalances(t, order.ID); balances[models.LedgerAccountRefundsPayable] != 0 || balances[models.LedgerAccountProviderCash] != 0 {
+		t.Fatalf("balances after sending = %v, want the refund settled", balances)
+	}
+}
+
+func TestConcurrentPaymentStartsShareOnePayment(t *testing.T) {
+	router, _ := setupPayments(t)
+	order, buyerToken := orderNewItem(t, router)
+
+	codes := make([]int, 5)
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			codes[i] = send(router, http.MethodPost, "/orders/"+order.ID.String()+"/payments", buyerToken, nil).Code
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	for _, code := range codes {
+		if code != http.StatusOK {
+			t.Fatalf("responses = %v, want all %d", codes, http.StatusOK)
+		}
+	}
+
+	var payments []models.Payment
+	config.DB.Where("order_id = ?", order.ID).Find(&payments)
+	if len(payments) != 1 || payments[0].ChargeID == nil {
+		t.Fatalf("order has %d payments, want one with a charge", len(payments))
+	}
+}
+
+func TestWebhookRejectsBadSignature(t *testing.T) {
+	router, provider := setupPayments(t)
+	order, buyerToken := orderNewItem(t, router)
+
+	assertStatus(t, send(router, http.MethodPost, "/orders/"+order.ID.String()+"/payments", buyerToken, nil), http.StatusOK)
+	var payment models.Payment
+	config.DB.Where("order_id = ?", order.ID).First(&payment)
+
+	payload, _, err := provider.SimulateWebhook(services.PaymentEventChargeSucceeded, *payment.ChargeID, payment.Amount)
+	if err != nil {
+		t.Fatalf("simulate webhook: %v", err)
+	}
+	request := httptest.NewRequest(http.MethodPost, "/payments/webhooks/"+provider.Name(), strings.NewReader(string(payload)))
+	request.Header.Set("X-Payment-Signature", "forged")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	assertStatus(t, recorder, http.StatusUnauthorized)
+
+	config.DB.Where("id = ?", payment.ID).First(&payment)
+	if payment.Status != models.PaymentStatusPending {
+		t.Fatalf("payment is %s after a forged webhook, want %s", payment.Status, models.PaymentStatusPending)
+	}
+}
+
+func TestSimulateIsOnlyRoutedForFakeProvider(t *testing.T) {
+	router := setupDatabase(t)
+	_, token := createUser(t, "C_LARGE")
+
+	assertStatus(t, send(router, http.MethodPost, "/payments/"+uuid.NewString()+"/simulate", token, nil), http.StatusNotFound)
+	assertStatus(t, send(router, http.MethodPost, "/payments/webhooks/fake", "", nil), http.StatusNotFound)
+}
+
+func TestWeighingShortfallIsOwedByBuyer(t *testing.T) {
+	router, _ := setupPayments(t)
+	order, buyerToken := orderNewItem(t, router)
+	payment := payOrder(t, router, order, buyerToken)
+
+	// The handover weighing found more than was listed.
+	shortfall := models.NewMoney(10000)
+	config.DB.Model(&models.Order{}).Where("id = ?", order.ID).Update("item_price", order.ItemPrice+shortfall)
+	config.DB.Where("id = ?", order.ID).First(&order)
+
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		return services.ReleaseEscrow(tx, order, time.Now())
+	}); err != nil {
+		t.Fatalf("release escrow: %v", err)
+	}
+
+	var pickup models.MarketItemPickupInformations
+	config.DB.Where("id = ?", order.PickupInformationID).First(&pickup)
+	var payout models.Payout
+	if err := config.DB.Where("order_id = ?", order.ID).First(&payout).Error; err != nil {
+		t.Fatalf("find payout: %v", err)
+	}
+	sellerShare := services.OrderTotal(order, pickup) - pickup.ServicePrice - pickup.DeliveryPrice
+	if payout.Amount != sellerShare {
+		t.Fatalf("payout is %s, want the full seller share %s", payout.Amount, sellerShare)
+	}
+
+	balances := ledgerBalances(t, order.ID)
+	if balances[models.LedgerAccountBuyerReceivable] != shortfall || balances[models.LedgerAccountEscrow] != 0 {
+		t.Fatalf("balances after release = %v, want %s owed by the buyer", balances, shortfall)
+	}
+
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		return services.RefundCancelledOrder(tx, order)
+	}); err != nil {
+		t.Fatalf("refund cancelled order: %v", err)
+	}
+	balances = ledgerBalances(t, order.ID)
+	if balances[models.LedgerAccountRefundsPayable] != -payment.Amount {
+		t.Fatalf("balances after cancelling = %v, want %s owed to the buyer", balances, payment.Amount)
+	}
+	for _, account := range []string{models.LedgerAccountBuyerReceivable, models.LedgerAccountSellerPayable, models.LedgerAccountPlatformFees, models.LedgerAccountDeliveryFees} {
+		if balances[account] != 0 {
+			t.Fatalf("%s holds %s after cancelling, want 0", account, balances[account])
+		}
+	}
+	config.DB.Where("id = ?", payout.ID).First(&payout)
+	if payout.Amount != 0 {
+		t.Fatalf("payout is %s after cancelling, want 0", payout.Amount)
+	}
+}
+
+func TestConcurrentRefundsStayWithinPayment(t *testing.T) {
+	router, _ := setupPayments(t)
+	order, buyerToken := orderNewItem(t, router)
+	payment := payOrder(t, router, order, buyerToken)
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			config.DB.Transaction(func(tx *gorm.DB) error {
+				return services.RefundCancelledOrder(tx, order)
+			})
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	var refunded models.Money
+	var refunds []models.PaymentRefund
+	config.DB.Where("payment_id = ?", payment.ID).Find(&refunds)
+	for _, refund := range refunds {
+		refunded += refund.Amount
+	}
+	if refunded != payment.Amount {
+		t.Fatalf("refunded %s in %d refunds, want %s", refunded, len(refunds), payment.Amount)
+	}
+}
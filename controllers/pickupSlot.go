@@ -0,0 +1,509 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"recyco/config"
+	"recyco/models"
+	"recyco/services"
+	"recyco/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const maxPickupSlotCapacity = 100
+
+var errReschedulePending = errors.New("order already has a pending reschedule request")
+
+type PickupSlotInput struct {
+	StartsAt string `form:"starts_at" binding:"required"`
+	EndsAt   string `form:"ends_at" binding:"required"`
+	Capacity int    `form:"capacity"`
+	Note     string `form:"note"`
+}
+
+type RescheduleInput struct {
+	SlotID string `form:"slot_id" binding:"required"`
+	Reason string `form:"reason"`
+}
+
+func pickupSlotResponse(slot *models.PickupSlot) map[string]interface{} {
+	if slot == nil || slot.ID == uuid.Nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"id":        slot.ID,
+		"seller_id": slot.SellerID,
+		"starts_at": slot.StartsAt.Format(time.RFC3339),
+		"ends_at":   slot.EndsAt.Format(time.RFC3339),
+		"capacity":  slot.Capacity,
+		"booked":    slot.Booked,
+		"available": slot.Capacity - slot.Booked,
+		"note":      slot.Note,
+	}
+}
+
+func rescheduleResponse(request models.RescheduleRequest) map[string]interface{} {
+	return map[string]interface{}{
+		"id":              request.ID,
+		"order_id":        request.OrderID,
+		"requested_by":    request.RequestedBy,
+		"requested_party": request.RequestedParty,
+		"from_slot_id":    request.FromSlotID,
+		"to_slot":         pickupSlotResponse(&request.ToSlot),
+		"reason":          request.Reason,
+		"status":          request.Status,
+		"created_at":      request.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// parseOptionalSlotID parses the slot_id of an order request, returning the
+// nil UUID when no slot was picked.
+func parseOptionalSlotID(value string) (uuid.UUID, error) {
+	if value == "" {
+		return uuid.Nil, nil
+	}
+	return uuid.Parse(value)
+}
+
+func CreatePickupSlot(c *gin.Context) {
+	var input PickupSlotInput
+	if err := c.ShouldBind(&input); err != nil {
+		utils.RespondFailed(c, http.StatusBadRequest, "Input tidak valid", nil)
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.RespondFailed(c, http.StatusInternalServerError, "User ID not found in context", nil)
+		return
+	}
+
+	startsAt, err := time.Parse(time.RFC3339, input.StartsAt)
+	if err != nil || !startsAt.After(time.Now()) {
+		utils.RespondFailed(c, http.StatusBadRequest, "starts_at must be a future RFC3339 time", nil)
+		return
+	}
+
+	endsAt, err := time.Parse(time.RFC3339, input.EndsAt)
+	if err != nil || !endsAt.After(startsAt) {
+		utils.RespondFailed(c, http.StatusBadRequest, "ends_at must be an RFC3339 time after starts_at", nil)
+		return
+	}
+
+	if input.Capacity == 0 {
+		input.Capacity = 1
+	}
+	if input.Capacity < 0 || input.Capacity > maxPickupSlotCapacity {
+		utils.RespondFailed(c, http.StatusBadRequest, "capacity must be between 1 and 100", nil)
+		return
+	}
+
+	slot := models.PickupSlot{
+		SellerID: userID.(uuid.UUID),
+		StartsAt: startsAt,
+		EndsAt:   endsAt,
+		Capacity: input.Capacity,
+		Note:     input.Note,
+	}
+
+	if err := config.DB.Create(&slot).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to create pickup slot", nil)
+		return
+	}
+
+	utils.RespondSuccess(c, "Pickup slot created successfully", pickupSlotResponse(&slot))
+}
+
+// GetPickupSlots lists the upcoming slots with room left of the seller given
+// by ?seller_id, or of the seller of ?item_id.
+func GetPickupSlots(c *gin.Context) {
+	sellerID := c.Query("seller_id")
+	if itemID := c.Query("item_id"); itemID != "" {
+		var item models.MarketItems
+		if err := config.DB.Where("id = ?", itemID).First(&item).Error; err != nil {
+			utils.RespondFailed(c, http.StatusNotFound, "Market item not found", nil)
+			return
+		}
+		sellerID = item.PostedBy.String()
+	}
+
+	if sellerID == "" {
+		utils.RespondFailed(c, http.StatusBadRequest, "seller_id or item_id is required", nil)
+		return
+	}
+
+	var slots []models.PickupSlot
+	if err := config.DB.Where("seller_id = ? AND starts_at > ? AND booked < capacity", sellerID, time.Now()).
+		Order("starts_at asc").
+		Find(&slots).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to fetch pickup slots", nil)
+		return
+	}
+
+	responseItems := []map[string]interface{}{}
+	for i := range slots {
+		responseItems = append(responseItems, pickupSlotResponse(&slots[i]))
+	}
+
+	utils.RespondSuccess(c, "Pickup slots fetched successfully", responseItems)
+}
+
+// GetUserPickupSlots lists the signed-in seller's slots that have not ended,
+// including full ones.
+func GetUserPickupSlots(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.RespondFailed(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	var slots []models.PickupSlot
+	if err := config.DB.Where("seller_id = ? AND ends_at > ?", userID, time.Now()).
+		Order("starts_at asc").
+		Find(&slots).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to fetch pickup slots", nil)
+		return
+	}
+
+	responseItems := []map[string]interface{}{}
+	for i := range slots {
+		responseItems = append(responseItems, pickupSlotResponse(&slots[i]))
+	}
+
+	utils.RespondSuccess(c, "Pickup slots fetched successfully", responseItems)
+}
+
+// DeletePickupSlot removes a seller's slot as long as nothing is booked in it.
+func DeletePickupSlot(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var slot models.PickupSlot
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&slot).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Pickup slot not found", nil)
+		return
+	}
+
+	if slot.SellerID != userID {
+		utils.RespondFailed(c, http.StatusForbidden, "You are not allowed to delete this pickup slot", nil)
+		return
+	}
+
+	result := config.DB.Where("id = ? AND booked = 0", slot.ID).Delete(&models.PickupSlot{})
+	if result.Error != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to delete pickup slot", nil)
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.RespondFailed(c, http.StatusConflict, "Pickup slot has bookings", nil)
+		return
+	}
+
+	utils.RespondSuccess(c, "Pickup slot deleted successfully", nil)
+}
+
+// findOpenOrder loads the order in the URL for one of its parties and
+// reports which party the user is. Only orders still waiting for their
+// handover can be rescheduled. It writes the error response itself.
+func findOpenOrder(c *gin.Context) (models.Order, string, bool) {
+	userID, _ := c.Get("userID")
+
+	var order models.Order
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&order).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Order not found", nil)
+		return order, "", false
+	}
+
+	party := ""
+	switch userID {
+	case order.BuyerID:
+		party = services.PartyBuyer
+	case order.SellerID:
+		party = services.PartySeller
+	default:
+		utils.RespondFailed(c, http.StatusForbidden, "You do not have permission to access this resource", nil)
+		return order, "", false
+	}
+
+	if order.Status != services.TransactionStatusOnProcess {
+		utils.RespondFailed(c, http.StatusConflict, "Only orders waiting for handover can be rescheduled", nil)
+		return order, "", false
+	}
+
+	return order, party, true
+}
+
+// RequestOrderReschedule proposes moving an order to another slot of its
+// seller. The other party is notified and has to accept.
+func RequestOrderReschedule(c *gin.Context) {
+	var input RescheduleInput
+	if err := c.ShouldBind(&input); err != nil {
+		utils.RespondFailed(c, http.StatusBadRequest, "Input tidak valid", nil)
+		return
+	}
+
+	order, party, ok := findOpenOrder(c)
+	if !ok {
+		return
+	}
+
+	slotID, err := uuid.Parse(input.SlotID)
+	if err != nil {
+		utils.RespondFailed(c, http.StatusBadRequest, "Invalid slot_id", nil)
+		return
+	}
+
+	var slot models.PickupSlot
+	if err := config.DB.Where("id = ? AND seller_id = ? AND starts_at > ? AND booked < capacity", slotID, order.SellerID, time.Now()).
+		First(&slot).Error; err != nil {
+		utils.RespondFailed(c, http.StatusConflict, "Pickup slot is not available", nil)
+		return
+	}
+
+	if order.SlotID != nil && *order.SlotID == slot.ID {
+		utils.RespondFailed(c, http.StatusBadRequest, "Order is already booked in this slot", nil)
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	request := models.RescheduleRequest{
+		OrderID:        order.ID,
+		RequestedBy:    userID.(uuid.UUID),
+		RequestedParty: party,
+		FromSlotID:     order.SlotID,
+		ToSlotID:       slot.ID,
+		Reason:         input.Reason,
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// Requests for one order are made one at a time, so two cannot both
+		// find no pending request and be created side by side.
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", order.ID).First(&models.Order{}).Error; err != nil {
+			return err
+		}
+		var pending int64
+		if err := tx.Model(&models.RescheduleRequest{}).
+			Where("order_id = ? AND status = ?", order.ID, models.RescheduleStatusPending).
+			Count(&pending).Error; err != nil {
+			return err
+		}
+		if pending > 0 {
+			return errReschedulePending
+		}
+
+		if err := tx.Create(&request).Error; err != nil {
+			return err
+		}
+
+		recipient := order.SellerID
+		if party == services.PartySeller {
+			recipient = order.BuyerID
+		}
+		notification := models.Notification{
+			UserID:  recipient,
+			Type:    models.NotificationRescheduleRequested,
+			Title:   "Pickup reschedule requested",
+			Message: "The pickup was asked to move to " + slot.StartsAt.Format(time.RFC3339) + ".",
+			OrderID: &order.ID,
+		}
+		return tx.Create(&notification).Error
+	})
+	if errors.Is(err, errReschedulePending) {
+		utils.RespondFailed(c, http.StatusConflict, "Order already has a pending reschedule request", nil)
+		return
+	}
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to create reschedule request", nil)
+		return
+	}
+
+	request.ToSlot = slot
+	utils.RespondSuccess(c, "Reschedule request created successfully", rescheduleResponse(request))
+}
+
+func GetOrderReschedules(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var order models.Order
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&order).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Order not found", nil)
+		return
+	}
+
+	if !isOrderParty(order, userID) {
+		utils.RespondFailed(c, http.StatusForbidden, "You do not have permission to access this resource", nil)
+		return
+	}
+
+	var requests []models.RescheduleRequest
+	if err := config.DB.Preload("ToSlot").Where("order_id = ?", order.ID).Order("created_at desc").Find(&requests).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to fetch reschedule requests", nil)
+		return
+	}
+
+	responseItems := []map[string]interface{}{}
+	for _, request := range requests {
+		responseItems = append(responseItems, rescheduleResponse(request))
+	}
+
+	utils.RespondSuccess(c, "Reschedule requests fetched successfully", responseItems)
+}
+
+func findPendingReschedule(c *gin.Context, order models.Order) (models.RescheduleRequest, bool) {
+	var request models.RescheduleRequest
+	if err := config.DB.Preload("ToSlot").
+		Where("id = ? AND order_id = ?", c.Param("requestId"), order.ID).
+		First(&request).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Reschedule request not found", nil)
+		return request, false
+	}
+
+	if request.Status != models.RescheduleStatusPending {
+		utils.RespondFailed(c, http.StatusConflict, "Reschedule request is already "+request.Status, nil)
+		return request, false
+	}
+
+	return request, true
+}
+
+func moveReschedule(tx *gorm.DB, request models.RescheduleRequest, status string) error {
+	result := tx.Model(&models.RescheduleRequest{}).
+		Where("id = ? AND status = ?", request.ID, models.RescheduleStatusPending).
+		Updates(map[string]interface{}{"Status": status, "UpdatedAt": time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return services.ErrTransactionConflict
+	}
+	return nil
+}
+
+// AcceptOrderReschedule moves the order into the requested slot, freeing
+// its previous one. The slot is booked only now, so it may have filled up
+// since the request; that is answered with 409.
+func AcceptOrderReschedule(c *gin.Context) {
+	order, party, ok := findOpenOrder(c)
+	if !ok {
+		return
+	}
+
+	request, ok := findPendingReschedule(c, order)
+	if !ok {
+		return
+	}
+
+	if request.RequestedParty == party {
+		utils.RespondFailed(c, http.StatusForbidden, "The other party has to accept this reschedule request", nil)
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := moveReschedule(tx, request, models.RescheduleStatusAccepted); err != nil {
+			return err
+		}
+		if err := services.MoveOrderSlot(tx, &order, request.ToSlotID); err != nil {
+			return err
+		}
+
+		notification := models.Notification{
+			UserID:  request.RequestedBy,
+			Type:    models.NotificationRescheduleAccepted,
+			Title:   "Pickup rescheduled",
+			Message: "The pickup now takes place at " + request.ToSlot.StartsAt.Format(time.RFC3339) + ".",
+			OrderID: &order.ID,
+		}
+		return tx.Create(&notification).Error
+	})
+	switch {
+	case errors.Is(err, services.ErrSlotUnavailable):
+		utils.RespondFailed(c, http.StatusConflict, "Pickup slot is no longer available", nil)
+		return
+	case errors.Is(err, services.ErrTransactionConflict):
+		utils.RespondFailed(c, http.StatusConflict, "Reschedule request was changed by another request", nil)
+		return
+	case err != nil:
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to accept reschedule request", nil)
+		return
+	}
+
+	request.Status = models.RescheduleStatusAccepted
+	utils.RespondSuccess(c, "Reschedule request accepted successfully", rescheduleResponse(request))
+}
+
+func DeclineOrderReschedule(c *gin.Context) {
+	order, party, ok := findOpenOrder(c)
+	if !ok {
+		return
+	}
+
+	request, ok := findPendingReschedule(c, order)
+	if !ok {
+		return
+	}
+
+	if request.RequestedParty == party {
+		utils.RespondFailed(c, http.StatusForbidden, "Cancel your own reschedule request instead of declining it", nil)
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := moveReschedule(tx, request, models.RescheduleStatusDeclined); err != nil {
+			return err
+		}
+
+		notification := models.Notification{
+			UserID:  request.RequestedBy,
+			Type:    models.NotificationRescheduleDeclined,
+			Title:   "Pickup reschedule declined",
+			Message: "The pickup keeps its current time.",
+			OrderID: &order.ID,
+		}
+		return tx.Create(&notification).Error
+	})
+	if errors.Is(err, services.ErrTransactionConflict) {
+		utils.RespondFailed(c, http.StatusConflict, "Reschedule request was changed by another request", nil)
+		return
+	}
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to decline reschedule request", nil)
+		return
+	}
+
+	request.Status = models.RescheduleStatusDeclined
+	utils.RespondSuccess(c, "Reschedule request declined successfully", rescheduleResponse(request))
+}
+
+func CancelOrderReschedule(c *gin.Context) {
+	order, party, ok := findOpenOrder(c)
+	if !ok {
+		return
+	}
+
+	request, ok := findPendingReschedule(c, order)
+	if !ok {
+		return
+	}
+
+	if request.RequestedParty != party {
+		utils.RespondFailed(c, http.StatusForbidden, "Only the party who asked can cancel this reschedule request", nil)
+		return
+	}
+
+	err := moveReschedule(config.DB, request, models.RescheduleStatusCancelled)
+	if errors.Is(err, services.ErrTransactionConflict) {
+		utils.RespondFailed(c, http.StatusConflict, "Reschedule request was changed by another request", nil)
+		return
+	}
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to cancel reschedule request", nil)
+		return
+	}
+
+	request.Status = models.RescheduleStatusCancelled
+	utils.RespondSuccess(c, "Reschedule request cancelled successfully", rescheduleResponse(request))
+}
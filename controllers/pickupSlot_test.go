@@ -0,0 +1,88 @@
+package controllers_test
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"recyco/config"
+	"recyco/models"
+	"recyco/services"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// createSlot stores a pickup slot of sellerID tomorrow with room for two.
+func createSlot(t *testing.T, sellerID uuid.UUID) models.PickupSlot {
+	t.Helper()
+
+	startsAt := time.Now().Add(24 * time.Hour)
+	slot := models.PickupSlot{SellerID: sellerID, StartsAt: startsAt, EndsAt: startsAt.Add(time.Hour), Capacity: 2}
+	if err := config.DB.Create(&slot).Error; err != nil {
+		t.Fatalf("create slot: %v", err)
+	}
+	return slot
+}
+
+func TestConcurrentRescheduleRequestsLeaveOnePending(t *testing.T) {
+	router := setupDatabase(t)
+	order, buyerToken := orderNewItem(t, router)
+	slot := createSlot(t, order.SellerID)
+
+	codes := make([]int, 5)
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			codes[i] = send(router, http.MethodPost, "/orders/"+order.ID.String()+"/reschedules", buyerToken, url.Values{
+				"slot_id": {slot.ID.String()},
+			}).Code
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	counts := map[int]int{}
+	for _, code := range codes {
+		counts[code]++
+	}
+	if counts[http.StatusOK] != 1 || counts[http.StatusConflict] != len(codes)-1 {
+		t.Fatalf("responses = %v, want one %d and the rest %d", codes, http.StatusOK, http.StatusConflict)
+	}
+}
+
+func TestStaleSlotMoveReleasesPreviousSlotOnce(t *testing.T) {
+	router := setupDatabase(t)
+	order, _ := orderNewItem(t, router)
+	first, second, third := createSlot(t, order.SellerID), createSlot(t, order.SellerID), createSlot(t, order.SellerID)
+
+	move := func(order models.Order, slotID uuid.UUID) error {
+		return config.DB.Transaction(func(tx *gorm.DB) error {
+			return services.MoveOrderSlot(tx, &order, slotID)
+		})
+	}
+	if err := move(order, first.ID); err != nil {
+		t.Fatalf("book first slot: %v", err)
+	}
+	config.DB.Where("id = ?", order.ID).First(&order)
+
+	if err := move(order, second.ID); err != nil {
+		t.Fatalf("move to second slot: %v", err)
+	}
+	if err := move(order, third.ID); !errors.Is(err, services.ErrTransactionConflict) {
+		t.Fatalf("move from a stale read: %v, want %v", err, services.ErrTransactionConflict)
+	}
+
+	for _, slot := range []*models.PickupSlot{&first, &second, &third} {
+		config.DB.Where("id = ?", slot.ID).First(slot)
+	}
+	if first.Booked != 0 || second.Booked != 1 || third.Booked != 0 {
+		t.Fatalf("slots booked %d, %d, %d, want 0, 1, 0", first.Booked, second.Booked, third.Booked)
+	}
+}
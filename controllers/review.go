@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"net/http"
+	"recyco/config"
+	"recyco/models"
+	"recyco/services"
+	"recyco/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type ReviewInput struct {
+	Rating  int    `form:"rating" binding:"required,min=1,max=5"`
+	Comment string `form:"comment"`
+}
+
+func reviewResponse(review models.Review) map[string]interface{} {
+	return map[string]interface{}{
+		"id":             review.ID,
+		"order_id":       review.OrderID,
+		"reviewer_party": review.ReviewerParty,
+		"reviewer": map[string]interface{}{
+			"id":   review.Reviewer.ID,
+			"name": review.Reviewer.Name,
+		},
+		"reviewee_id": review.RevieweeID,
+		"rating":      review.Rating,
+		"comment":     review.Comment,
+		"created_at":  review.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// CreateOrderReview lets the buyer or the seller of a finished order rate the
+// other party once.
+func CreateOrderReview(c *gin.Context) {
+	var input ReviewInput
+	if err := c.ShouldBind(&input); err != nil {
+		utils.RespondFailed(c, http.StatusBadRequest, "Rating must be between 1 and 5", nil)
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.RespondFailed(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	var order models.Order
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&order).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Order not found", nil)
+		return
+	}
+
+	review := models.Review{
+		OrderID: order.ID,
+		Rating:  input.Rating,
+		Comment: input.Comment,
+	}
+	switch userID {
+	case order.BuyerID:
+		review.ReviewerID, review.RevieweeID, review.ReviewerParty = order.BuyerID, order.SellerID, services.PartyBuyer
+	case order.SellerID:
+		review.ReviewerID, review.RevieweeID, review.ReviewerParty = order.SellerID, order.BuyerID, services.PartySeller
+	default:
+		utils.RespondFailed(c, http.StatusForbidden, "You do not have permission to access this resource", nil)
+		return
+	}
+
+	if order.Status != services.TransactionStatusFinished {
+		utils.RespondFailed(c, http.StatusConflict, "Only finished orders can be reviewed", nil)
+		return
+	}
+
+	if review.RevieweeID == uuid.Nil {
+		utils.RespondFailed(c, http.StatusConflict, "The other party of this order is unknown", nil)
+		return
+	}
+
+	var existing int64
+	if err := config.DB.Model(&models.Review{}).Where("order_id = ? AND reviewer_id = ?", order.ID, review.ReviewerID).Count(&existing).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to check existing reviews", nil)
+		return
+	}
+	if existing > 0 {
+		utils.RespondFailed(c, http.StatusConflict, "You have already reviewed this order", nil)
+		return
+	}
+
+	if err := config.DB.Create(&review).Error; err != nil {
+		// The unique index catches a concurrent duplicate.
+		if services.IsDuplicateKey(err) {
+			utils.RespondFailed(c, http.StatusConflict, "You have already reviewed this order", nil)
+			return
+		}
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to create review", nil)
+		return
+	}
+
+	config.DB.Where("id = ?", review.ReviewerID).First(&review.Reviewer)
+	utils.RespondSuccess(c, "Review created successfully", reviewResponse(review))
+}
+
+func GetOrderReviews(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	userRole, _ := c.Get("userRole")
+
+	var order models.Order
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&order).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Order not found", nil)
+		return
+	}
+
+	if !isOrderParty(order, userID) && userRole != "ADMIN" {
+		utils.RespondFailed(c, http.StatusForbidden, "You do not have permission to access this resource", nil)
+		return
+	}
+
+	var reviews []models.Review
+	if err := config.DB.Preload("Reviewer").Where("order_id = ?", order.ID).Order("created_at asc").Find(&reviews).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to fetch reviews", nil)
+		return
+	}
+
+	responseItems := []map[string]interface{}{}
+	for _, review := range reviews {
+		responseItems = append(responseItems, reviewResponse(review))
+	}
+
+	utils.RespondSuccess(c, "Reviews fetched successfully", responseItems)
+}
+
+// GetPublicProfile shows what any signed-in user may see about another user:
+// their name, role, reputation, cancellation record and the latest reviews
+// they received.
+func GetPublicProfile(c *gin.Context) {
+	var user models.User
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&user).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "User not found", nil)
+		return
+	}
+
+	reputations, err := services.LoadReputations(config.DB, []uuid.UUID{user.ID})
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to load reputation", nil)
+		return
+	}
+
+	cancellations, err := services.LoadCancellationStats(config.DB, user.ID)
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to load cancellation statistics", nil)
+		return
+	}
+
+	var reviews []models.Review
+	if err := config.DB.Preload("Reviewer").
+		Where("reviewee_id = ?", user.ID).
+		Order("created_at desc").
+		Limit(20).
+		Find(&reviews).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to fetch reviews", nil)
+		return
+	}
+
+	recentReviews := []map[string]interface{}{}
+	for _, review := range reviews {
+		recentReviews = append(recentReviews, reviewResponse(review))
+	}
+
+	utils.RespondSuccess(c, "User profile retrieved successfully", gin.H{
+		"id":             user.ID,
+		"name":           user.Name,
+		"role":           user.Role,
+		"reputation":     reputations[user.ID],
+		"cancellations":  cancellations,
+		"recent_reviews": recentReviews,
+		"member_since":   user.CreatedAt.Format(time.RFC3339),
+	})
+}
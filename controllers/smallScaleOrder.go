@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	"net/http"
+	"recyco/config"
+	"recyco/models"
+	"recyco/services"
+	"recyco/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type SmallScaleOrderInput struct {
+	ItemID         string `form:"item_id" binding:"required"`
+	Fulfillment    string `form:"fulfillment" binding:"required"`
+	RecipientName  string `form:"recipient_name" binding:"required"`
+	RecipientPhone string `form:"recipient_phone" binding:"required"`
+	Description    string `form:"description"`
+	// Address is where the seller drops the item off; it is ignored for
+	// self-pickup, where the buyer collects from the seller.
+	Address            string `form:"address"`
+	AddressDescription string `form:"address_description"`
+	SlotID             string `form:"slot_id"`
+}
+
+// CreateSmallScaleOrder orders a SMALL item without a courier: the buyer
+// either collects it from the seller or the seller drops it off. The order
+// uses the same pickup, order and activity records as large-scale orders and
+// waits in ON_PROCESS until the seller confirms it.
+func CreateSmallScaleOrder(c *gin.Context) {
+	var input SmallScaleOrderInput
+	if err := c.ShouldBind(&input); err != nil {
+		utils.RespondFailed(c, http.StatusBadRequest, "Input tidak valid", nil)
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.RespondFailed(c, http.StatusInternalServerError, "User ID not found in context", nil)
+		return
+	}
+
+	userRole, exists := c.Get("userRole")
+	if !exists {
+		utils.RespondFailed(c, http.StatusInternalServerError, "User role not found in context", nil)
+		return
+	}
+
+	switch input.Fulfillment {
+	case models.FulfillmentSelfPickup:
+		input.Address = ""
+		input.AddressDescription = ""
+	case models.FulfillmentDropOff:
+		if input.Address == "" {
+			utils.RespondFailed(c, http.StatusBadRequest, "Address is required for drop-off", nil)
+			return
+		}
+	default:
+		utils.RespondFailed(c, http.StatusBadRequest, "Fulfillment must be SELF_PICKUP or DROP_OFF", nil)
+		return
+	}
+
+	itemID, err := uuid.Parse(input.ItemID)
+	if err != nil {
+		utils.RespondFailed(c, http.StatusBadRequest, "Invalid UUID for item_id", nil)
+		return
+	}
+
+	var marketItem models.MarketItems
+	if err := config.DB.Preload("PostedByUser").Where("id = ?", itemID).First(&marketItem).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Market item not found", nil)
+		return
+	}
+
+	rules, err := services.LoadMarketRules(config.DB)
+	if err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to load marketplace rules", nil)
+		return
+	}
+
+	// Who may sell and buy small items is up to the marketplace rules, so
+	// the flow follows them rather than fixed roles.
+	if marketItem.ItemScale != "SMALL" || !rules.CanPost(marketItem.PostedByUser.Role, marketItem.ItemScale) {
+		utils.RespondFailed(c, http.StatusBadRequest, "Only SMALL scale items posted by small producers can be ordered this way", nil)
+		return
+	}
+
+	if !rules.CanBuy(userRole.(string), marketItem.ItemScale) {
+		utils.RespondFailed(c, http.StatusForbidden, "You do not have permission to order this market item", nil)
+		return
+	}
+
+	if marketItem.PostedBy == userID {
+		utils.RespondFailed(c, http.StatusBadRequest, "You cannot order your own market item", nil)
+		return
+	}
+
+	if marketItem.Status != models.MarketItemStatusReady {
+		utils.RespondFailed(c, http.StatusConflict, "Market item is not available for order", nil)
+		return
+	}
+
+	slotID, err := parseOptionalSlotID(input.SlotID)
+	if err != nil {
+		utils.RespondFailed(c, http.StatusBadRequest, "Invalid slot_id", nil)
+		return
+	}
+
+	tx := config.DB.Begin()
+	if tx.Error != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to start transaction", nil)
+		return
+	}
+
+	pickupInformation := models.MarketItemPickupInformations{
+		RecipientName:             input.RecipientName,
+		RecipientPhone:            input.RecipientPhone,
+		Description:               input.Description,
+		PickupLocationAddress:     input.Address,
+		PickupLocationDescription: input.AddressDescription,
+	}
+
+	order, _, err := services.PlaceOrder(tx, marketItem, userID.(uuid.UUID), &pickupInformation, marketItem.Price, input.Fulfillment)
+	if err == nil && slotID != uuid.Nil {
+		err = services.BookOrderSlot(tx, &order, slotID)
+	}
+	if err != nil {
+		tx.Rollback()
+		respondTransitionError(c, err)
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to commit transaction", nil)
+		return
+	}
+
+	if err := preloadOrder(config.DB).Where("id = ?", order.ID).First(&order).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to fetch order", nil)
+		return
+	}
+
+	utils.RespondSuccess(c, "Small scale order created successfully", orderResponse(order))
+}
+
+// ConfirmSmallScaleOrder is the seller accepting a small-scale order: the
+// order moves to ON_DELIVER, meaning the item is ready to be collected or is
+// on its way to the buyer. Completing or rejecting it goes through
+// UpdateOrderStatus like any other order.
+func ConfirmSmallScaleOrder(c *gin.Context) {
+	var order models.Order
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&order).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Order not found", nil)
+		return
+	}
+
+	if order.Fulfillment == models.FulfillmentDelivery {
+		utils.RespondFailed(c, http.StatusBadRequest, "Only small scale orders need seller confirmation", nil)
+		return
+	}
+
+	updateOrderStatus(c, order, OrderStatusUpdateInput{Status: services.TransactionStatusOnDeliver})
+}
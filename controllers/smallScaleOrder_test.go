@@ -0,0 +1,98 @@
+package controllers_test
+
+import (
+	"net/http"
+	"net/url"
+	"recyco/config"
+	"recyco/models"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func smallScaleOrderForm(item models.MarketItems) url.Values {
+	return url.Values{
+		"item_id":         {item.ID.String()},
+		"fulfillment":     {models.FulfillmentSelfPickup},
+		"recipient_name":  {"Buyer"},
+		"recipient_phone": {"08123456789"},
+	}
+}
+
+// setRoleRule changes what role may do with items of scale for the rest of
+// the test, adding the rule if there is none, and restores it afterwards.
+func setRoleRule(t *testing.T, role, scale string, updates map[string]interface{}) {
+	t.Helper()
+
+	var rule models.MarketRoleRule
+	err := config.DB.Where("role = ? AND item_scale = ?", role, scale).First(&rule).Error
+	if err == gorm.ErrRecordNotFound {
+		rule = models.MarketRoleRule{Role: role, ItemScale: scale}
+		if err := config.DB.Create(&rule).Error; err != nil {
+			t.Fatalf("create %s %s rule: %v", role, scale, err)
+		}
+		t.Cleanup(func() { config.DB.Unscoped().Delete(&models.MarketRoleRule{}, "id = ?", rule.ID) })
+	} else if err != nil {
+		t.Fatalf("find %s %s rule: %v", role, scale, err)
+	} else {
+		t.Cleanup(func() {
+			config.DB.Model(&models.MarketRoleRule{}).Where("id = ?", rule.ID).Updates(map[string]interface{}{
+				"can_post": rule.CanPost,
+				"can_view": rule.CanView,
+				"can_buy":  rule.CanBuy,
+			})
+		})
+	}
+
+	if err := config.DB.Model(&models.MarketRoleRule{}).Where("id = ?", rule.ID).Updates(updates).Error; err != nil {
+		t.Fatalf("update %s %s rule: %v", role, scale, err)
+	}
+}
+
+func TestSmallScaleOrderFollowsBuyRules(t *testing.T) {
+	router := setupDatabase(t)
+
+	seller, sellerToken := createUser(t, "P_SMALL")
+	_, smallToken := createUser(t, "C_SMALL")
+	_, largeToken := createUser(t, "C_LARGE")
+
+	item := createItem(t, seller, "SMALL")
+	assertStatus(t, send(router, http.MethodPost, "/market_transactions/small", largeToken, smallScaleOrderForm(item)), http.StatusForbidden)
+	assertStatus(t, send(router, http.MethodPost, "/market_transactions/small", sellerToken, smallScaleOrderForm(item)), http.StatusForbidden)
+	assertStatus(t, send(router, http.MethodPost, "/market_transactions/small", smallToken, smallScaleOrderForm(item)), http.StatusOK)
+
+	setRoleRule(t, "C_LARGE", "SMALL", map[string]interface{}{"can_buy": true})
+	item = createItem(t, seller, "SMALL")
+	assertStatus(t, send(router, http.MethodPost, "/market_transactions/small", largeToken, smallScaleOrderForm(item)), http.StatusOK)
+}
+
+func TestSmallScaleOrderFollowsPostRules(t *testing.T) {
+	router := setupDatabase(t)
+
+	seller, _ := createUser(t, "P_LARGE")
+	_, buyerToken := createUser(t, "C_SMALL")
+
+	item := createItem(t, seller, "SMALL")
+	assertStatus(t, send(router, http.MethodPost, "/market_transactions/small", buyerToken, smallScaleOrderForm(item)), http.StatusBadRequest)
+
+	setRoleRule(t, "P_LARGE", "SMALL", map[string]interface{}{"can_post": true})
+	assertStatus(t, send(router, http.MethodPost, "/market_transactions/small", buyerToken, smallScaleOrderForm(item)), http.StatusOK)
+}
+
+func TestConfirmSmallScaleOrderBySeller(t *testing.T) {
+	router := setupDatabase(t)
+
+	seller, sellerToken := createUser(t, "P_SMALL")
+	_, buyerToken := createUser(t, "C_SMALL")
+
+	item := createItem(t, seller, "SMALL")
+	assertStatus(t, send(router, http.MethodPost, "/market_transactions/small", buyerToken, smallScaleOrderForm(item)), http.StatusOK)
+
+	var order models.Order
+	if err := config.DB.Where("item_id = ?", item.ID).First(&order).Error; err != nil {
+		t.Fatalf("find order: %v", err)
+	}
+
+	assertStatus(t, send(router, http.MethodPut, "/orders/"+order.ID.String()+"/confirm", buyerToken, nil), http.StatusForbidden)
+	assertStatus(t, send(router, http.MethodPut, "/orders/"+order.ID.String()+"/confirm", sellerToken, nil), http.StatusOK)
+}
@@ -0,0 +1,149 @@
+package controllers
+
+import (
+	"net/http"
+	"recyco/config"
+	"recyco/models"
+	"recyco/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type TariffInput struct {
+	Name               string       `form:"name" binding:"required"`
+	ItemScale          string       `form:"item_scale"`
+	Category           string       `form:"category"`
+	MinWeight          float64      `form:"min_weight"`
+	MaxWeight          float64      `form:"max_weight"`
+	ServiceBlockWeight float64      `form:"service_block_weight" binding:"required"`
+	ServiceFeePerBlock models.Money `form:"service_fee_per_block"`
+	DeliveryFeePerKg   models.Money `form:"delivery_fee_per_kg"`
+	DeliveryFeePerKm   models.Money `form:"delivery_fee_per_km"`
+	MinimumFee         models.Money `form:"minimum_fee"`
+	EffectiveFrom      string       `form:"effective_from"`
+	EffectiveUntil     string       `form:"effective_until"`
+}
+
+func CreateTariff(c *gin.Context) {
+	var input TariffInput
+	if err := c.ShouldBind(&input); err != nil {
+		utils.RespondFailed(c, http.StatusBadRequest, "Input tidak valid", nil)
+		return
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.RespondFailed(c, http.StatusInternalServerError, "User ID not found in context", nil)
+		return
+	}
+
+	if input.ItemScale != "" && input.ItemScale != "SMALL" && input.ItemScale != "LARGE" {
+		utils.RespondFailed(c, http.StatusBadRequest, "Invalid item scale", nil)
+		return
+	}
+
+	if input.MinWeight < 0 || input.MaxWeight < 0 || (input.MaxWeight > 0 && input.MaxWeight <= input.MinWeight) {
+		utils.RespondFailed(c, http.StatusBadRequest, "Invalid weight bracket", nil)
+		return
+	}
+
+	if input.ServiceBlockWeight <= 0 || input.ServiceFeePerBlock < 0 || input.DeliveryFeePerKg < 0 || input.DeliveryFeePerKm < 0 || input.MinimumFee < 0 {
+		utils.RespondFailed(c, http.StatusBadRequest, "Fees must not be negative", nil)
+		return
+	}
+
+	effectiveFrom := time.Now()
+	if input.EffectiveFrom != "" {
+		parsed, err := time.Parse(time.RFC3339, input.EffectiveFrom)
+		if err != nil {
+			utils.RespondFailed(c, http.StatusBadRequest, "effective_from must be an RFC3339 timestamp", nil)
+			return
+		}
+		effectiveFrom = parsed
+	}
+
+	var effectiveUntil *time.Time
+	if input.EffectiveUntil != "" {
+		parsed, err := time.Parse(time.RFC3339, input.EffectiveUntil)
+		if err != nil || !parsed.After(effectiveFrom) {
+			utils.RespondFailed(c, http.StatusBadRequest, "effective_until must be an RFC3339 timestamp after effective_from", nil)
+			return
+		}
+		effectiveUntil = &parsed
+	}
+
+	tariff := models.Tariff{
+		Name:               input.Name,
+		ItemScale:          input.ItemScale,
+		Category:           input.Category,
+		MinWeight:          input.MinWeight,
+		MaxWeight:          input.MaxWeight,
+		ServiceBlockWeight: input.ServiceBlockWeight,
+		ServiceFeePerBlock: input.ServiceFeePerBlock,
+		DeliveryFeePerKg:   input.DeliveryFeePerKg,
+		DeliveryFeePerKm:   input.DeliveryFeePerKm,
+		MinimumFee:         input.MinimumFee,
+		EffectiveFrom:      effectiveFrom,
+		EffectiveUntil:     effectiveUntil,
+		CreatedBy:          userID.(uuid.UUID),
+	}
+
+	if err := config.DB.Create(&tariff).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to create tariff", nil)
+		return
+	}
+
+	utils.RespondSuccess(c, "Tariff created successfully", tariff)
+}
+
+func GetTariffs(c *gin.Context) {
+	var tariffs []models.Tariff
+
+	query := config.DB.Order("effective_from desc")
+	if c.Query("active") == "true" {
+		now := time.Now()
+		query = query.Where("effective_from <= ? AND (effective_until IS NULL OR effective_until > ?)", now, now)
+	}
+
+	if err := query.Find(&tariffs).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to fetch tariffs", nil)
+		return
+	}
+
+	utils.RespondSuccess(c, "Tariffs fetched successfully", tariffs)
+}
+
+func GetTariffByID(c *gin.Context) {
+	var tariff models.Tariff
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&tariff).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Tariff not found", nil)
+		return
+	}
+
+	utils.RespondSuccess(c, "Tariff fetched successfully", tariff)
+}
+
+// RetireTariff ends a tariff's validity now. The row is kept because pickup
+// records refer to the tariff version that priced them.
+func RetireTariff(c *gin.Context) {
+	var tariff models.Tariff
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&tariff).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Tariff not found", nil)
+		return
+	}
+
+	now := time.Now()
+	if tariff.EffectiveUntil != nil && !tariff.EffectiveUntil.After(now) {
+		utils.RespondFailed(c, http.StatusConflict, "Tariff is already retired", nil)
+		return
+	}
+
+	if err := config.DB.Model(&tariff).Update("effective_until", now).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to retire tariff", nil)
+		return
+	}
+
+	utils.RespondSuccess(c, "Tariff retired successfully", tariff)
+}
@@ -0,0 +1,226 @@
+package controllers
+
+import (
+	"io"
+	"net/http"
+	"recyco/config"
+	"recyco/models"
+	"recyco/services"
+	"recyco/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	trackingStatusPollInterval = 5 * time.Second
+	trackingHeartbeatInterval  = 15 * time.Second
+	locationHistoryLimit       = 200
+)
+
+type LocationPingInput struct {
+	Lat        *float64 `form:"lat" binding:"required"`
+	Lon        *float64 `form:"lon" binding:"required"`
+	AccuracyM  *float64 `form:"accuracy_m"`
+	SpeedKmh   *float64 `form:"speed_kmh"`
+	Heading    *float64 `form:"heading"`
+	RecordedAt string   `form:"recorded_at"`
+}
+
+// CreateLocationPing stores a GPS position of an order on its way and pushes
+// it to the tracking streams of that order. Only the assigned driver, or the
+// seller when delivering themselves, can report positions, and only while
+// the order is ON_DELIVER.
+func CreateLocationPing(c *gin.Context) {
+	var input LocationPingInput
+	if err := c.ShouldBind(&input); err != nil {
+		utils.RespondFailed(c, http.StatusBadRequest, "Input tidak valid", nil)
+		return
+	}
+
+	if *input.Lat < -90 || *input.Lat > 90 || *input.Lon < -180 || *input.Lon > 180 {
+		utils.RespondFailed(c, http.StatusBadRequest, "lat or lon is out of range", nil)
+		return
+	}
+
+	userID, _ := c.Get("userID")
+
+	var order models.Order
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&order).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Order not found", nil)
+		return
+	}
+
+	party := ""
+	switch {
+	case isOrderDriver(order, userID):
+		party = services.PartyDriver
+	case order.SellerID == userID:
+		party = services.PartySeller
+	default:
+		utils.RespondFailed(c, http.StatusForbidden, "Only the driver or the seller can report the position", nil)
+		return
+	}
+
+	if order.Status != services.TransactionStatusOnDeliver {
+		utils.RespondFailed(c, http.StatusConflict, "Positions can only be reported while the order is ON_DELIVER", nil)
+		return
+	}
+
+	recordedAt := time.Now()
+	if input.RecordedAt != "" {
+		parsed, err := time.Parse(time.RFC3339, input.RecordedAt)
+		if err != nil || parsed.After(recordedAt.Add(time.Minute)) || parsed.Before(recordedAt.Add(-services.LocationPingRetention)) {
+			utils.RespondFailed(c, http.StatusBadRequest, "recorded_at must be a recent RFC3339 time", nil)
+			return
+		}
+		recordedAt = parsed
+	}
+
+	var recent int64
+	config.DB.Model(&models.LocationPing{}).
+		Where("order_id = ? AND created_at > ?", order.ID, time.Now().Add(-services.LocationPingMinInterval)).
+		Count(&recent)
+	if recent > 0 {
+		utils.RespondFailed(c, http.StatusTooManyRequests, "Positions are accepted at most every 5 seconds", nil)
+		return
+	}
+
+	ping := models.LocationPing{
+		OrderID:       order.ID,
+		ReporterID:    userID.(uuid.UUID),
+		ReporterParty: party,
+		Lat:           *input.Lat,
+		Lon:           *input.Lon,
+		AccuracyM:     input.AccuracyM,
+		SpeedKmh:      input.SpeedKmh,
+		Heading:       input.Heading,
+		RecordedAt:    recordedAt,
+	}
+
+	if err := config.DB.Create(&ping).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to save position", nil)
+		return
+	}
+
+	services.Tracking.Publish(services.TrackingEvent{
+		Type:     "location",
+		OrderID:  order.ID,
+		Status:   order.Status,
+		Location: &ping,
+		At:       time.Now(),
+	})
+
+	utils.RespondSuccess(c, "Position saved successfully", ping)
+}
+
+// findTrackedOrder loads the order in the URL with the same ownership check
+// as GetMarketItemPickupInformationByID: only its buyer and seller may
+// follow it. It writes the error response itself.
+func findTrackedOrder(c *gin.Context) (models.Order, bool) {
+	var order models.Order
+	if err := config.DB.Where("id = ?", c.Param("id")).First(&order).Error; err != nil {
+		utils.RespondFailed(c, http.StatusNotFound, "Order not found", nil)
+		return order, false
+	}
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		utils.RespondFailed(c, http.StatusUnauthorized, "Unauthorized", nil)
+		return order, false
+	}
+
+	if !isOrderParty(order, userID) {
+		utils.RespondFailed(c, http.StatusForbidden, "You do not have permission to access this resource", nil)
+		return order, false
+	}
+
+	return order, true
+}
+
+// GetOrderLocations returns the retained positions of an order, oldest
+// first, for drawing the route taken so far.
+func GetOrderLocations(c *gin.Context) {
+	order, ok := findTrackedOrder(c)
+	if !ok {
+		return
+	}
+
+	var pings []models.LocationPing
+	if err := config.DB.Where("order_id = ?", order.ID).
+		Order("recorded_at desc").
+		Limit(locationHistoryLimit).
+		Find(&pings).Error; err != nil {
+		utils.RespondFailed(c, http.StatusInternalServerError, "Failed to fetch positions", nil)
+		return
+	}
+
+	for i, j := 0, len(pings)-1; i < j; i, j = i+1, j-1 {
+		pings[i], pings[j] = pings[j], pings[i]
+	}
+
+	utils.RespondSuccess(c, "Positions fetched successfully", pings)
+}
+
+// StreamOrderTracking is a Server-Sent Events stream of an order's position
+// and status. It starts with a "status" event carrying the last known
+// position, then sends "location" events as pings arrive and a "status"
+// event whenever the order moves on. Status changes are picked up by polling
+// the order, so they are seen whichever endpoint made them. The stream ends
+// once the order is FINISHED or CANCELLED.
+func StreamOrderTracking(c *gin.Context) {
+	order, ok := findTrackedOrder(c)
+	if !ok {
+		return
+	}
+
+	events, unsubscribe := services.Tracking.Subscribe(order.ID)
+	defer unsubscribe()
+
+	var last models.LocationPing
+	snapshot := services.TrackingEvent{Type: "status", OrderID: order.ID, Status: order.Status, At: time.Now()}
+	if err := config.DB.Where("order_id = ?", order.ID).Order("recorded_at desc").First(&last).Error; err == nil {
+		snapshot.Location = &last
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	statusPoll := time.NewTicker(trackingStatusPollInterval)
+	defer statusPoll.Stop()
+	heartbeat := time.NewTicker(trackingHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	status := order.Status
+	c.SSEvent(snapshot.Type, snapshot)
+	c.Writer.Flush()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event := <-events:
+			c.SSEvent(event.Type, event)
+		case <-statusPoll.C:
+			var current models.Order
+			if err := config.DB.Select("id", "status").Where("id = ?", order.ID).First(&current).Error; err != nil {
+				return false
+			}
+			if current.Status != status {
+				status = current.Status
+				c.SSEvent("status", services.TrackingEvent{Type: "status", OrderID: order.ID, Status: status, At: time.Now()})
+			}
+			if status == services.TransactionStatusFinished || status == services.TransactionStatusCancelled {
+				return false
+			}
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return false
+			}
+		}
+		return true
+	})
+}
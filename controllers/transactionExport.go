@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"recyco/config"
+	"recyco/services"
+	"recyco/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const exportDateLayout = "2006-01-02"
+
+// flushingTableWriter pushes each flushed batch of rows on to the client
+// instead of leaving it in the response buffer.
+type flushingTableWriter struct {
+	services.TableWriter
+	response gin.ResponseWriter
+}
+
+func (table flushingTableWriter) Flush() error {
+	err := table.TableWriter.Flush()
+	table.response.Flush()
+	return err
+}
+
+// ExportMarketTransactions streams every order the user bought or sold as a
+// CSV or Excel file for reconciliation. ?from= and ?to= are inclusive dates
+// (YYYY-MM-DD) and default to the current month so far; ?format= is csv, the
+// default, or xlsx.
+func ExportMarketTransactions(c *gin.Context) {
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "xlsx" {
+		utils.RespondFailed(c, http.StatusBadRequest, "Format must be csv or xlsx", nil)
+		return
+	}
+
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	for param, date := range map[string]*time.Time{"from": &from, "to": &to} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.ParseInLocation(exportDateLayout, value, time.Local)
+		if err != nil {
+			utils.RespondFailed(c, http.StatusBadRequest, "Dates must be formatted as YYYY-MM-DD", nil)
+			return
+		}
+		*date = parsed
+	}
+	if to.Before(from) {
+		utils.RespondFailed(c, http.StatusBadRequest, "from must not be after to", nil)
+		return
+	}
+
+	userID, _ := c.Get("userID")
+
+	filename := "transactions-" + from.Format(exportDateLayout) + "-" + to.Format(exportDateLayout) + "." + format
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+
+	var table services.TableWriter
+	var err error
+	if format == "xlsx" {
+		c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		c.Status(http.StatusOK)
+		table, err = services.NewXLSXTableWriter(c.Writer, "Transactions", services.TransactionExportColumns)
+	} else {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Status(http.StatusOK)
+		table, err = services.NewCSVTableWriter(c.Writer, services.TransactionExportColumns)
+	}
+	if err == nil {
+		err = services.ExportTransactions(config.DB.WithContext(c.Request.Context()), userID.(uuid.UUID),
+			from, to.AddDate(0, 0, 1), flushingTableWriter{TableWriter: table, response: c.Writer})
+	}
+	if err == nil {
+		err = table.Close()
+	}
+	if err != nil {
+		// The response has already started, so the client is left with a
+		// truncated file; all that can be done is to record why.
+		log.Printf("transaction export for %v: %v", userID, err)
+		c.Abort()
+	}
+}
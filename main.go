@@ -1,12 +1,44 @@
 package main
 
 import (
+	"log"
 	"recyco/config"
 	"recyco/routes"
+	"recyco/services"
+	"time"
+
+	"gorm.io/gorm"
 )
 
+// runEvery calls job every interval until the process exits, logging what
+// each run did under name. It is meant to be started in its own goroutine.
+func runEvery(db *gorm.DB, interval time.Duration, name string, job func(*gorm.DB, time.Time) (int, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		count, err := job(db, time.Now())
+		if err != nil {
+			log.Printf("%s: %v", name, err)
+		}
+		if count > 0 {
+			log.Printf("%s: processed %d", name, count)
+		}
+	}
+}
+
 func main() {
 	config.ConnectDatabase()
+	config.ConfigurePayments()
+	go runEvery(config.DB, time.Minute, "reservation expiry", services.ExpireReservations)
+	go runEvery(config.DB, 15*time.Second, "auction closer", services.CloseDueAuctions)
+	go runEvery(config.DB, time.Minute, "contract scheduler", services.RunDueContracts)
+	go runEvery(config.DB, time.Hour, "location ping purge", services.PurgeLocationPings)
+	if services.Payments != nil {
+		go runEvery(config.DB, time.Minute, "refund sender", func(db *gorm.DB, now time.Time) (int, error) {
+			return services.SendPendingRefunds(db, services.Payments)
+		})
+	}
 	r := routes.SetupRouter()
 	r.Run(":8080")
 }
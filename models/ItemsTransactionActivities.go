@@ -10,13 +10,22 @@ import (
 type MarketItemTransactionActivities struct {
 	ID              uuid.UUID      `json:"id" gorm:"type:varchar(255);primary_key"`
 	ItemID          uuid.UUID      `json:"item_id" gorm:"type:varchar(255);not null"`
+	OrderID         *uuid.UUID     `json:"order_id" gorm:"type:varchar(255);index"`
 	Status          string         `json:"status" gorm:"type:enum('ON_PROCESS', 'ON_DELIVER', 'FINISHED', 'CANCELLED');not null"`
 	TransactionByID uuid.UUID      `json:"transaction_by_id" gorm:"type:varchar(255)"`
+	ActorID         *uuid.UUID     `json:"actor_id" gorm:"type:varchar(255)"`
+	ActorParty      string         `json:"actor_party" gorm:"type:varchar(32)"`
+	ReasonCode      string         `json:"reason_code" gorm:"type:varchar(32);index"`
+	Reason          string         `json:"reason" gorm:"type:text"`
+	HandoverWeight  *float64       `json:"handover_weight" gorm:"type:decimal(12,3)"`
+	PinVerified     bool           `json:"pin_verified" gorm:"not null;default:false"`
 	CreatedAt       time.Time      `json:"created_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
 	UpdatedAt       time.Time      `json:"updated_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
 	DeletedAt       gorm.DeletedAt `json:"deleted_at" gorm:"type:datetime"`
 
-	MarketItem MarketItems `gorm:"foreignKey:ItemID;references:ID"`
+	MarketItem  MarketItems          `gorm:"foreignKey:ItemID;references:ID"`
+	Actor       *User                `gorm:"foreignKey:ActorID;references:ID"`
+	ProofPhotos []DeliveryProofPhoto `gorm:"foreignKey:ActivityID;references:ID"`
 }
 
 func (model *MarketItemTransactionActivities) BeforeCreate(tx *gorm.DB) error {
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	AuctionStatusOpen      = "OPEN"
+	AuctionStatusSold      = "SOLD"
+	AuctionStatusUnsold    = "UNSOLD"
+	AuctionStatusCancelled = "CANCELLED"
+)
+
+// Auction sells a market item to the highest bidder. The leading bid is kept
+// on the auction itself so that placing a bid is one conditional update on
+// BidCount, which serializes concurrent bids. A bid placed within
+// ExtensionSeconds of EndsAt pushes EndsAt back by that much.
+type Auction struct {
+	ID               uuid.UUID      `json:"id" gorm:"type:varchar(255);primary_key"`
+	ItemID           uuid.UUID      `json:"item_id" gorm:"type:varchar(255);not null;index"`
+	SellerID         uuid.UUID      `json:"seller_id" gorm:"type:varchar(255);not null;index"`
+	StartingPrice    Money          `json:"starting_price" gorm:"type:decimal(15,2);not null"`
+	ReservePrice     Money          `json:"reserve_price" gorm:"type:decimal(15,2);not null"`
+	MinIncrement     Money          `json:"min_increment" gorm:"type:decimal(15,2);not null"`
+	Currency         string         `json:"currency" gorm:"type:varchar(3);not null;default:'IDR'"`
+	CurrentPrice     Money          `json:"current_price" gorm:"type:decimal(15,2);not null;default:0"`
+	HighestBidID     *uuid.UUID     `json:"highest_bid_id" gorm:"type:varchar(255)"`
+	HighestBidderID  *uuid.UUID     `json:"highest_bidder_id" gorm:"type:varchar(255)"`
+	BidCount         int            `json:"bid_count" gorm:"not null;default:0"`
+	EndsAt           time.Time      `json:"ends_at" gorm:"type:datetime;not null;index"`
+	ExtensionSeconds int            `json:"extension_seconds" gorm:"not null;default:300"`
+	Status           string         `json:"status" gorm:"type:enum('OPEN', 'SOLD', 'UNSOLD', 'CANCELLED');not null;default:'OPEN';index"`
+	OrderID          *uuid.UUID     `json:"order_id" gorm:"type:varchar(255)"`
+	CreatedAt        time.Time      `json:"created_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt        time.Time      `json:"updated_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	DeletedAt        gorm.DeletedAt `json:"deleted_at" gorm:"type:datetime"`
+
+	MarketItem MarketItems `json:"market_item" gorm:"foreignKey:ItemID;references:ID"`
+	Bids       []Bid       `json:"bids" gorm:"foreignKey:AuctionID;references:ID"`
+}
+
+func (model *Auction) BeforeCreate(tx *gorm.DB) error {
+	model.ID = uuid.New()
+	if model.Status == "" {
+		model.Status = AuctionStatusOpen
+	}
+	if model.Currency == "" {
+		model.Currency = DefaultCurrency
+	}
+	model.CreatedAt = time.Now()
+	model.UpdatedAt = time.Now()
+	return nil
+}
+
+func (model *Auction) BeforeUpdate(tx *gorm.DB) error {
+	model.UpdatedAt = time.Now()
+	return nil
+}
+
+// Bid is one bid on an auction. Sequence is the bid's position in the
+// auction, unique per auction. The bidder's handover details are stored with
+// the bid so the winning one can become an order without further input.
+type Bid struct {
+	ID                        uuid.UUID      `json:"id" gorm:"type:varchar(255);primary_key"`
+	AuctionID                 uuid.UUID      `json:"auction_id" gorm:"type:varchar(255);not null;uniqueIndex:idx_bid_auction_sequence"`
+	Sequence                  int            `json:"sequence" gorm:"not null;uniqueIndex:idx_bid_auction_sequence"`
+	BidderID                  uuid.UUID      `json:"bidder_id" gorm:"type:varchar(255);not null;index"`
+	Amount                    Money          `json:"amount" gorm:"type:decimal(15,2);not null"`
+	RecipientName             string         `json:"recipient_name" gorm:"type:varchar(255);not null"`
+	RecipientPhone            string         `json:"recipient_phone" gorm:"type:varchar(32);not null"`
+	PickupLocationAddress     string         `json:"pickup_location_address" gorm:"type:varchar(255);not null"`
+	PickupLocationDescription string         `json:"pickup_location_description" gorm:"type:varchar(255)"`
+	PickupLat                 *float64       `json:"pickup_lat" gorm:"type:float"`
+	PickupLon                 *float64       `json:"pickup_lon" gorm:"type:float"`
+	CreatedAt                 time.Time      `json:"created_at" gorm:"type:datetime(3);not null;default:CURRENT_TIMESTAMP(3)"`
+	UpdatedAt                 time.Time      `json:"updated_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	DeletedAt                 gorm.DeletedAt `json:"deleted_at" gorm:"type:datetime"`
+
+	Bidder User `json:"bidder" gorm:"foreignKey:BidderID;references:ID"`
+}
+
+func (model *Bid) BeforeCreate(tx *gorm.DB) error {
+	model.ID = uuid.New()
+	model.CreatedAt = time.Now()
+	model.UpdatedAt = time.Now()
+	return nil
+}
+
+func (model *Bid) BeforeUpdate(tx *gorm.DB) error {
+	model.UpdatedAt = time.Now()
+	return nil
+}
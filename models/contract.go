@@ -0,0 +1,86 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	ContractStatusProposed   = "PROPOSED"
+	ContractStatusActive     = "ACTIVE"
+	ContractStatusPaused     = "PAUSED"
+	ContractStatusEnded      = "ENDED"
+	ContractStatusTerminated = "TERMINATED"
+)
+
+// Contract schedule frequencies. WEEKLY and BIWEEKLY run on Weekday, MONTHLY
+// on DayOfMonth.
+const (
+	ContractFrequencyWeekly   = "WEEKLY"
+	ContractFrequencyBiweekly = "BIWEEKLY"
+	ContractFrequencyMonthly  = "MONTHLY"
+)
+
+// Contract is a standing agreement for a large producer to sell the same
+// material to a recycler on a schedule. While ACTIVE, every run generates a
+// market item and an order for the buyer; those orders carry the contract's
+// ID and make up its history. The seller proposes the terms and the buyer
+// accepts them with the handover details used for every order.
+type Contract struct {
+	ID                        uuid.UUID      `json:"id" gorm:"type:varchar(255);primary_key"`
+	SellerID                  uuid.UUID      `json:"seller_id" gorm:"type:varchar(255);not null;index"`
+	BuyerID                   uuid.UUID      `json:"buyer_id" gorm:"type:varchar(255);not null;index"`
+	Title                     string         `json:"title" gorm:"type:varchar(255);not null"`
+	Category                  string         `json:"category" gorm:"type:varchar(64);not null"`
+	Description               string         `json:"description" gorm:"type:text"`
+	EstimatedWeight           float64        `json:"estimated_weight" gorm:"type:decimal(12,3);not null"`
+	WeightUnit                string         `json:"weight_unit" gorm:"type:enum('g', 'kg', 'ton');not null;default:'kg'"`
+	PricePerKg                Money          `json:"price_per_kg" gorm:"type:decimal(15,2);not null"`
+	Currency                  string         `json:"currency" gorm:"type:varchar(3);not null;default:'IDR'"`
+	Frequency                 string         `json:"frequency" gorm:"type:enum('WEEKLY', 'BIWEEKLY', 'MONTHLY');not null"`
+	Weekday                   int            `json:"weekday" gorm:"not null;default:0"`
+	DayOfMonth                int            `json:"day_of_month" gorm:"not null;default:0"`
+	StartsOn                  time.Time      `json:"starts_on" gorm:"type:datetime;not null"`
+	EndsOn                    *time.Time     `json:"ends_on" gorm:"type:datetime"`
+	NextRunAt                 *time.Time     `json:"next_run_at" gorm:"type:datetime;index"`
+	Lat                       *float64       `json:"lat" gorm:"type:float"`
+	Lon                       *float64       `json:"lon" gorm:"type:float"`
+	RecipientName             string         `json:"recipient_name" gorm:"type:varchar(255)"`
+	RecipientPhone            string         `json:"recipient_phone" gorm:"type:varchar(32)"`
+	PickupLocationAddress     string         `json:"pickup_location_address" gorm:"type:varchar(255)"`
+	PickupLocationDescription string         `json:"pickup_location_description" gorm:"type:varchar(255)"`
+	PickupLat                 *float64       `json:"pickup_lat" gorm:"type:float"`
+	PickupLon                 *float64       `json:"pickup_lon" gorm:"type:float"`
+	Status                    string         `json:"status" gorm:"type:enum('PROPOSED', 'ACTIVE', 'PAUSED', 'ENDED', 'TERMINATED');not null;default:'PROPOSED';index"`
+	TerminatedBy              *uuid.UUID     `json:"terminated_by" gorm:"type:varchar(255)"`
+	TerminationReason         string         `json:"termination_reason" gorm:"type:text"`
+	CreatedAt                 time.Time      `json:"created_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt                 time.Time      `json:"updated_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	DeletedAt                 gorm.DeletedAt `json:"deleted_at" gorm:"type:datetime"`
+
+	Seller User `json:"seller" gorm:"foreignKey:SellerID;references:ID"`
+	Buyer  User `json:"buyer" gorm:"foreignKey:BuyerID;references:ID"`
+}
+
+func (model *Contract) BeforeCreate(tx *gorm.DB) error {
+	model.ID = uuid.New()
+	if model.Status == "" {
+		model.Status = ContractStatusProposed
+	}
+	if model.Currency == "" {
+		model.Currency = DefaultCurrency
+	}
+	if model.WeightUnit == "" {
+		model.WeightUnit = WeightUnitKilogram
+	}
+	model.CreatedAt = time.Now()
+	model.UpdatedAt = time.Now()
+	return nil
+}
+
+func (model *Contract) BeforeUpdate(tx *gorm.DB) error {
+	model.UpdatedAt = time.Now()
+	return nil
+}
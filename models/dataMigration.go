@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// DataMigration marks a one-off data fix that has been applied to the
+// database, so Migrate applies each fix only once.
+type DataMigration struct {
+	Name      string    `json:"name" gorm:"type:varchar(128);primary_key"`
+	AppliedAt time.Time `json:"applied_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DeliveryProofPhoto is a photo taken at handover, attached to the FINISHED
+// activity of an order.
+type DeliveryProofPhoto struct {
+	ID         uuid.UUID      `json:"id" gorm:"type:varchar(255);primary_key"`
+	ActivityID uuid.UUID      `json:"activity_id" gorm:"type:varchar(255);not null;index"`
+	Url        string         `json:"url" gorm:"type:varchar(2048);not null"`
+	CreatedAt  time.Time      `json:"created_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt  time.Time      `json:"updated_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	DeletedAt  gorm.DeletedAt `json:"deleted_at" gorm:"type:datetime"`
+}
+
+func (model *DeliveryProofPhoto) BeforeCreate(tx *gorm.DB) error {
+	model.ID = uuid.New()
+	model.CreatedAt = time.Now()
+	model.UpdatedAt = time.Now()
+	return nil
+}
+
+func (model *DeliveryProofPhoto) BeforeUpdate(tx *gorm.DB) error {
+	model.UpdatedAt = time.Now()
+	return nil
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	DemandStatusOpen      = "OPEN"
+	DemandStatusFulfilled = "FULFILLED"
+	DemandStatusClosed    = "CLOSED"
+)
+
+const (
+	DemandResponseStatusPending   = "PENDING"
+	DemandResponseStatusAccepted  = "ACCEPTED"
+	DemandResponseStatusRejected  = "REJECTED"
+	DemandResponseStatusWithdrawn = "WITHDRAWN"
+)
+
+// DemandListing is a buyer's standing request to buy a material, the mirror
+// image of a market item. Producers answer it with DemandResponses; each
+// accepted response becomes a regular market item and order, and its weight
+// counts towards FulfilledWeight until the wanted Weight is reached.
+type DemandListing struct {
+	ID              uuid.UUID      `json:"id" gorm:"type:varchar(255);primary_key"`
+	Title           string         `json:"title" gorm:"type:varchar(255);not null"`
+	Category        string         `json:"category" gorm:"type:varchar(64);not null;index"`
+	Description     string         `json:"description" gorm:"type:text"`
+	Weight          float64        `json:"weight" gorm:"type:decimal(12,3);not null"`
+	WeightUnit      string         `json:"weight_unit" gorm:"type:enum('g', 'kg', 'ton');not null;default:'kg'"`
+	FulfilledWeight float64        `json:"fulfilled_weight" gorm:"type:decimal(12,3);not null;default:0"`
+	PricePerKg      Money          `json:"price_per_kg" gorm:"type:decimal(15,2);not null"`
+	Currency        string         `json:"currency" gorm:"type:varchar(3);not null;default:'IDR'"`
+	ItemScale       string         `json:"item_scale" gorm:"type:enum('SMALL', 'LARGE');not null;index"`
+	Fulfillment     string         `json:"fulfillment" gorm:"type:enum('DELIVERY', 'SELF_PICKUP', 'DROP_OFF');not null;default:'DELIVERY'"`
+	AreaAddress     string         `json:"area_address" gorm:"type:varchar(255);not null"`
+	Lat             *float64       `json:"lat" gorm:"type:float"`
+	Lon             *float64       `json:"lon" gorm:"type:float"`
+	ValidUntil      time.Time      `json:"valid_until" gorm:"type:datetime;not null;index"`
+	Status          string         `json:"status" gorm:"type:enum('OPEN', 'FULFILLED', 'CLOSED');not null;default:'OPEN';index"`
+	PostedBy        uuid.UUID      `json:"posted_by" gorm:"type:varchar(255);not null;index"`
+	CreatedAt       time.Time      `json:"created_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt       time.Time      `json:"updated_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	DeletedAt       gorm.DeletedAt `json:"deleted_at" gorm:"type:datetime"`
+
+	PostedByUser User `json:"posted_by_user" gorm:"foreignKey:PostedBy;references:ID"`
+}
+
+func (model *DemandListing) BeforeCreate(tx *gorm.DB) error {
+	model.ID = uuid.New()
+	if model.Status == "" {
+		model.Status = DemandStatusOpen
+	}
+	if model.Currency == "" {
+		model.Currency = DefaultCurrency
+	}
+	if model.WeightUnit == "" {
+		model.WeightUnit = WeightUnitKilogram
+	}
+	model.CreatedAt = time.Now()
+	model.UpdatedAt = time.Now()
+	return nil
+}
+
+func (model *DemandListing) BeforeUpdate(tx *gorm.DB) error {
+	model.UpdatedAt = time.Now()
+	return nil
+}
+
+// DemandResponse is a producer's proposal to supply part or all of a demand
+// listing. ItemID and OrderID are set once the buyer accepts it.
+type DemandResponse struct {
+	ID           uuid.UUID      `json:"id" gorm:"type:varchar(255);primary_key"`
+	DemandID     uuid.UUID      `json:"demand_id" gorm:"type:varchar(255);not null;index"`
+	ResponderID  uuid.UUID      `json:"responder_id" gorm:"type:varchar(255);not null;index"`
+	Weight       float64        `json:"weight" gorm:"type:decimal(12,3);not null"`
+	WeightUnit   string         `json:"weight_unit" gorm:"type:enum('g', 'kg', 'ton');not null;default:'kg'"`
+	PricePerKg   Money          `json:"price_per_kg" gorm:"type:decimal(15,2);not null"`
+	Message      string         `json:"message" gorm:"type:text"`
+	Lat          *float64       `json:"lat" gorm:"type:float"`
+	Lon          *float64       `json:"lon" gorm:"type:float"`
+	ThumbnailUrl string         `json:"thumbnail_url" gorm:"type:varchar(2048)"`
+	Status       string         `json:"status" gorm:"type:enum('PENDING', 'ACCEPTED', 'REJECTED', 'WITHDRAWN');not null;default:'PENDING';index"`
+	ItemID       *uuid.UUID     `json:"item_id" gorm:"type:varchar(255)"`
+	OrderID      *uuid.UUID     `json:"order_id" gorm:"type:varchar(255)"`
+	CreatedAt    time.Time      `json:"created_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt    time.Time      `json:"updated_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	DeletedAt    gorm.DeletedAt `json:"deleted_at" gorm:"type:datetime"`
+
+	Responder User `json:"responder" gorm:"foreignKey:ResponderID;references:ID"`
+}
+
+func (model *DemandResponse) BeforeCreate(tx *gorm.DB) error {
+	model.ID = uuid.New()
+	if model.Status == "" {
+		model.Status = DemandResponseStatusPending
+	}
+	if model.WeightUnit == "" {
+		model.WeightUnit = WeightUnitKilogram
+	}
+	model.CreatedAt = time.Now()
+	model.UpdatedAt = time.Now()
+	return nil
+}
+
+func (model *DemandResponse) BeforeUpdate(tx *gorm.DB) error {
+	model.UpdatedAt = time.Now()
+	return nil
+}
@@ -0,0 +1,146 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	DisputeStatusOpen     = "OPEN"
+	DisputeStatusResolved = "RESOLVED"
+)
+
+// Dispute reasons a party can give when opening a dispute.
+const (
+	DisputeReasonContaminated   = "CONTAMINATED"
+	DisputeReasonShortWeight    = "SHORT_WEIGHT"
+	DisputeReasonNotDelivered   = "NOT_DELIVERED"
+	DisputeReasonNotAsDescribed = "NOT_AS_DESCRIBED"
+	DisputeReasonNonPayment     = "NON_PAYMENT"
+	DisputeReasonOther          = "OTHER"
+)
+
+// Dispute resolutions an admin can decide on. PARTIAL_REFUND returns part of
+// the order total to the buyer, CANCELLED voids the order and UPHELD lets the
+// order stand as it is.
+const (
+	DisputeResolutionPartialRefund = "PARTIAL_REFUND"
+	DisputeResolutionCancelled     = "CANCELLED"
+	DisputeResolutionUpheld        = "UPHELD"
+)
+
+var DisputeReasons = []string{
+	DisputeReasonContaminated,
+	DisputeReasonShortWeight,
+	DisputeReasonNotDelivered,
+	DisputeReasonNotAsDescribed,
+	DisputeReasonNonPayment,
+	DisputeReasonOther,
+}
+
+// Dispute is a complaint by the buyer or the seller about an order, settled
+// by an admin. The conversation about it is kept as DisputeMessages and the
+// evidence as DisputeEvidencePhotos. AtFaultID is the party the resolution
+// went against, if any, and counts against their reputation.
+type Dispute struct {
+	ID             uuid.UUID      `json:"id" gorm:"type:varchar(255);primary_key"`
+	OrderID        uuid.UUID      `json:"order_id" gorm:"type:varchar(255);not null;index"`
+	OpenedByID     uuid.UUID      `json:"opened_by_id" gorm:"type:varchar(255);not null;index"`
+	OpenedByParty  string         `json:"opened_by_party" gorm:"type:enum('BUYER', 'SELLER');not null"`
+	Reason         string         `json:"reason" gorm:"type:enum('CONTAMINATED', 'SHORT_WEIGHT', 'NOT_DELIVERED', 'NOT_AS_DESCRIBED', 'NON_PAYMENT', 'OTHER');not null"`
+	Description    string         `json:"description" gorm:"type:text;not null"`
+	Status         string         `json:"status" gorm:"type:enum('OPEN', 'RESOLVED');not null;default:'OPEN';index"`
+	Resolution     *string        `json:"resolution" gorm:"type:enum('PARTIAL_REFUND', 'CANCELLED', 'UPHELD')"`
+	RefundAmount   Money          `json:"refund_amount" gorm:"type:decimal(15,2);not null;default:0"`
+	ResolutionNote string         `json:"resolution_note" gorm:"type:text"`
+	AtFaultID      *uuid.UUID     `json:"at_fault_id" gorm:"type:varchar(255);index"`
+	ResolvedByID   *uuid.UUID     `json:"resolved_by_id" gorm:"type:varchar(255)"`
+	ResolvedAt     *time.Time     `json:"resolved_at" gorm:"type:datetime"`
+	CreatedAt      time.Time      `json:"created_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt      time.Time      `json:"updated_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	DeletedAt      gorm.DeletedAt `json:"deleted_at" gorm:"type:datetime"`
+
+	Order    Order                  `json:"order" gorm:"foreignKey:OrderID;references:ID"`
+	OpenedBy User                   `json:"opened_by" gorm:"foreignKey:OpenedByID;references:ID"`
+	Messages []DisputeMessage       `json:"messages" gorm:"foreignKey:DisputeID;references:ID"`
+	Photos   []DisputeEvidencePhoto `json:"photos" gorm:"foreignKey:DisputeID;references:ID"`
+}
+
+func (model *Dispute) BeforeCreate(tx *gorm.DB) error {
+	model.ID = uuid.New()
+	if model.Status == "" {
+		model.Status = DisputeStatusOpen
+	}
+	model.CreatedAt = time.Now()
+	model.UpdatedAt = time.Now()
+	return nil
+}
+
+func (model *Dispute) BeforeUpdate(tx *gorm.DB) error {
+	model.UpdatedAt = time.Now()
+	return nil
+}
+
+// DisputeMessage is one entry of the conversation about a dispute between the
+// buyer, the seller and the admins.
+type DisputeMessage struct {
+	ID          uuid.UUID      `json:"id" gorm:"type:varchar(255);primary_key"`
+	DisputeID   uuid.UUID      `json:"dispute_id" gorm:"type:varchar(255);not null;index"`
+	AuthorID    uuid.UUID      `json:"author_id" gorm:"type:varchar(255);not null"`
+	AuthorParty string         `json:"author_party" gorm:"type:enum('BUYER', 'SELLER', 'ADMIN');not null"`
+	Body        string         `json:"body" gorm:"type:text;not null"`
+	CreatedAt   time.Time      `json:"created_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt   time.Time      `json:"updated_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	DeletedAt   gorm.DeletedAt `json:"deleted_at" gorm:"type:datetime"`
+
+	Author User                   `json:"author" gorm:"foreignKey:AuthorID;references:ID"`
+	Photos []DisputeEvidencePhoto `json:"photos" gorm:"foreignKey:MessageID;references:ID"`
+}
+
+func (model *DisputeMessage) BeforeCreate(tx *gorm.DB) error {
+	model.ID = uuid.New()
+	model.CreatedAt = time.Now()
+	model.UpdatedAt = time.Now()
+	return nil
+}
+
+func (model *DisputeMessage) BeforeUpdate(tx *gorm.DB) error {
+	model.UpdatedAt = time.Now()
+	return nil
+}
+
+// DisputeEvidencePhoto is a photo attached to a dispute, either when it was
+// opened or with a later message.
+type DisputeEvidencePhoto struct {
+	ID           uuid.UUID      `json:"id" gorm:"type:varchar(255);primary_key"`
+	DisputeID    uuid.UUID      `json:"dispute_id" gorm:"type:varchar(255);not null;index"`
+	MessageID    *uuid.UUID     `json:"message_id" gorm:"type:varchar(255);index"`
+	UploadedByID uuid.UUID      `json:"uploaded_by_id" gorm:"type:varchar(255);not null"`
+	Url          string         `json:"url" gorm:"type:varchar(2048);not null"`
+	CreatedAt    time.Time      `json:"created_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt    time.Time      `json:"updated_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	DeletedAt    gorm.DeletedAt `json:"deleted_at" gorm:"type:datetime"`
+}
+
+func (model *DisputeEvidencePhoto) BeforeCreate(tx *gorm.DB) error {
+	model.ID = uuid.New()
+	model.CreatedAt = time.Now()
+	model.UpdatedAt = time.Now()
+	return nil
+}
+
+func (model *DisputeEvidencePhoto) BeforeUpdate(tx *gorm.DB) error {
+	model.UpdatedAt = time.Now()
+	return nil
+}
+
+func IsValidDisputeReason(reason string) bool {
+	for _, r := range DisputeReasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
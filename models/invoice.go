@@ -0,0 +1,66 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrInvoiceImmutable = errors.New("issued invoices cannot be changed")
+
+// Invoice is the tax document of a finished order. It is numbered
+// sequentially within the year it was issued in and keeps a copy of every
+// figure it shows, so later edits to users, items or the order do not change
+// an invoice that was already handed out. Once created it can be neither
+// updated nor deleted.
+type Invoice struct {
+	ID             uuid.UUID `json:"id" gorm:"type:varchar(255);primary_key"`
+	OrderID        uuid.UUID `json:"order_id" gorm:"type:varchar(255);not null;uniqueIndex"`
+	Number         string    `json:"number" gorm:"type:varchar(32);not null;uniqueIndex"`
+	Year           int       `json:"year" gorm:"not null;uniqueIndex:idx_invoice_year_sequence"`
+	Sequence       int       `json:"sequence" gorm:"not null;uniqueIndex:idx_invoice_year_sequence"`
+	IssuedAt       time.Time `json:"issued_at" gorm:"type:datetime;not null"`
+	SellerID       uuid.UUID `json:"seller_id" gorm:"type:varchar(255);not null;index"`
+	SellerName     string    `json:"seller_name" gorm:"type:varchar(255);not null"`
+	SellerPhone    string    `json:"seller_phone" gorm:"type:varchar(255)"`
+	BuyerID        uuid.UUID `json:"buyer_id" gorm:"type:varchar(255);not null;index"`
+	BuyerName      string    `json:"buyer_name" gorm:"type:varchar(255);not null"`
+	BuyerPhone     string    `json:"buyer_phone" gorm:"type:varchar(255)"`
+	ItemName       string    `json:"item_name" gorm:"type:varchar(255);not null"`
+	Category       string    `json:"category" gorm:"type:varchar(64)"`
+	Weight         float64   `json:"weight" gorm:"type:decimal(12,3);not null"`
+	WeightUnit     string    `json:"weight_unit" gorm:"type:enum('g', 'kg', 'ton');not null;default:'kg'"`
+	WeightMeasured bool      `json:"weight_measured" gorm:"not null;default:false"`
+	Fulfillment    string    `json:"fulfillment" gorm:"type:enum('DELIVERY', 'SELF_PICKUP', 'DROP_OFF');not null"`
+	ItemPrice      Money     `json:"item_price" gorm:"type:decimal(15,2);not null"`
+	ServicePrice   Money     `json:"service_price" gorm:"type:decimal(15,2);not null"`
+	DeliveryPrice  Money     `json:"delivery_price" gorm:"type:decimal(15,2);not null"`
+	Total          Money     `json:"total" gorm:"type:decimal(15,2);not null"`
+	Currency       string    `json:"currency" gorm:"type:varchar(3);not null;default:'IDR'"`
+	CreatedAt      time.Time `json:"created_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+}
+
+func (model *Invoice) BeforeCreate(tx *gorm.DB) error {
+	model.ID = uuid.New()
+	if model.Currency == "" {
+		model.Currency = DefaultCurrency
+	}
+	model.CreatedAt = time.Now()
+	return nil
+}
+
+func (model *Invoice) BeforeUpdate(tx *gorm.DB) error {
+	return ErrInvoiceImmutable
+}
+
+func (model *Invoice) BeforeDelete(tx *gorm.DB) error {
+	return ErrInvoiceImmutable
+}
+
+// InvoiceSequence holds the last invoice number handed out in a year.
+type InvoiceSequence struct {
+	Year       int `json:"year" gorm:"primary_key;autoIncrement:false"`
+	LastNumber int `json:"last_number" gorm:"not null;default:0"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// LocationPing is one GPS position reported for an order on its way to the
+// buyer. Pings are only kept for a limited time, see
+// services.LocationPingRetention.
+type LocationPing struct {
+	ID            uuid.UUID      `json:"id" gorm:"type:varchar(255);primary_key"`
+	OrderID       uuid.UUID      `json:"order_id" gorm:"type:varchar(255);not null;index:idx_location_ping_order_recorded"`
+	ReporterID    uuid.UUID      `json:"reporter_id" gorm:"type:varchar(255);not null"`
+	ReporterParty string         `json:"reporter_party" gorm:"type:varchar(32);not null"`
+	Lat           float64        `json:"lat" gorm:"type:double;not null"`
+	Lon           float64        `json:"lon" gorm:"type:double;not null"`
+	AccuracyM     *float64       `json:"accuracy_m" gorm:"type:float"`
+	SpeedKmh      *float64       `json:"speed_kmh" gorm:"type:float"`
+	Heading       *float64       `json:"heading" gorm:"type:float"`
+	RecordedAt    time.Time      `json:"recorded_at" gorm:"type:datetime;not null;index:idx_location_ping_order_recorded"`
+	CreatedAt     time.Time      `json:"created_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP;index"`
+	UpdatedAt     time.Time      `json:"updated_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	DeletedAt     gorm.DeletedAt `json:"deleted_at" gorm:"type:datetime"`
+}
+
+func (model *LocationPing) BeforeCreate(tx *gorm.DB) error {
+	model.ID = uuid.New()
+	model.CreatedAt = time.Now()
+	model.UpdatedAt = time.Now()
+	return nil
+}
+
+func (model *LocationPing) BeforeUpdate(tx *gorm.DB) error {
+	model.UpdatedAt = time.Now()
+	return nil
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MarketScaleRule sets the weight boundary between SMALL and LARGE items.
+// The rule with an empty Category is the default; other rows override it for
+// items of their category.
+type MarketScaleRule struct {
+	ID             uuid.UUID      `json:"id" gorm:"type:varchar(255);primary_key"`
+	Category       string         `json:"category" gorm:"type:varchar(64);not null;uniqueIndex"`
+	SmallMaxWeight float64        `json:"small_max_weight" gorm:"type:decimal(12,3);not null"`
+	CreatedAt      time.Time      `json:"created_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt      time.Time      `json:"updated_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	DeletedAt      gorm.DeletedAt `json:"deleted_at" gorm:"type:datetime"`
+}
+
+func (model *MarketScaleRule) BeforeCreate(tx *gorm.DB) error {
+	model.ID = uuid.New()
+	model.CreatedAt = time.Now()
+	model.UpdatedAt = time.Now()
+	return nil
+}
+
+func (model *MarketScaleRule) BeforeUpdate(tx *gorm.DB) error {
+	model.UpdatedAt = time.Now()
+	return nil
+}
+
+// MarketRoleRule states what a role may do with items of one scale.
+type MarketRoleRule struct {
+	ID        uuid.UUID      `json:"id" gorm:"type:varchar(255);primary_key"`
+	Role      string         `json:"role" gorm:"type:varchar(32);not null;uniqueIndex:idx_market_role_rules_role_scale"`
+	ItemScale string         `json:"item_scale" gorm:"type:enum('SMALL', 'LARGE');not null;uniqueIndex:idx_market_role_rules_role_scale"`
+	CanPost   bool           `json:"can_post" gorm:"not null;default:false"`
+	CanView   bool           `json:"can_view" gorm:"not null;default:false"`
+	CanBuy    bool           `json:"can_buy" gorm:"not null;default:false"`
+	CreatedAt time.Time      `json:"created_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"type:datetime"`
+}
+
+func (model *MarketRoleRule) BeforeCreate(tx *gorm.DB) error {
+	model.ID = uuid.New()
+	model.CreatedAt = time.Now()
+	model.UpdatedAt = time.Now()
+	return nil
+}
+
+func (model *MarketRoleRule) BeforeUpdate(tx *gorm.DB) error {
+	model.UpdatedAt = time.Now()
+	return nil
+}
@@ -7,21 +7,60 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	MarketItemStatusDraft      = "DRAFT"
+	MarketItemStatusReady      = "READY"
+	MarketItemStatusReserved   = "RESERVED"
+	MarketItemStatusInDelivery = "IN_DELIVERY"
+	MarketItemStatusSold       = "SOLD"
+	MarketItemStatusWithdrawn  = "WITHDRAWN"
+)
+
+// Listing types. AUCTION items are sold through their open auction only and
+// cannot be ordered, offered on, edited or withdrawn directly.
+const (
+	ListingTypeFixed   = "FIXED"
+	ListingTypeAuction = "AUCTION"
+)
+
+var MarketItemStatuses = []string{
+	MarketItemStatusDraft,
+	MarketItemStatusReady,
+	MarketItemStatusReserved,
+	MarketItemStatusInDelivery,
+	MarketItemStatusSold,
+	MarketItemStatusWithdrawn,
+}
+
+// MarketItems is a listing on the marketplace. Price is always the total for
+// the listed weight; items with a PricePerKg are priced by weight, so their
+// Price follows the weight and is settled on the weight measured at handover.
 type MarketItems struct {
-	ID            uuid.UUID      `json:"id" gorm:"type:varchar(255);primary_key"`
-	Name          string         `json:"name" gorm:"type:varchar(255);not null"`
-	Price         float64        `json:"price" gorm:"type:decimal(15,2);not null"`
-	Weight        float64        `json:"weight" gorm:"type:decimal(10,2);not null"`
-	ItemScale     string         `json:"item_scale" gorm:"type:enum('SMALL', 'LARGE');not null"`
-	Description   string         `json:"description" gorm:"type:text"`
-	ThumbnailUrl  string         `json:"thumbnail_url" gorm:"type:varchar(2048)"`
-	PostedBy      uuid.UUID      `json:"posted_by" gorm:"type:varchar(255);not null"`
-	OrderedBy     *uuid.UUID     `json:"ordered_by" gorm:"type:varchar(255)"`
-	CreatedAt     time.Time      `json:"created_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
-	UpdatedAt     time.Time      `json:"updated_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
-	DeletedAt     gorm.DeletedAt `json:"deleted_at" gorm:"type:datetime"`
-	PostedByUser  User           `json:"posted_by_user" gorm:"foreignKey:PostedBy;references:ID"`
-	OrderedByUser User           `json:"ordered_by_user" gorm:"foreignKey:OrderedBy;references:ID"`
+	ID               uuid.UUID      `json:"id" gorm:"type:varchar(255);primary_key"`
+	Name             string         `json:"name" gorm:"type:varchar(255);not null"`
+	Price            Money          `json:"price" gorm:"type:decimal(15,2);not null"`
+	PricePerKg       Money          `json:"price_per_kg" gorm:"type:decimal(15,2);not null;default:0"`
+	Currency         string         `json:"currency" gorm:"type:varchar(3);not null;default:'IDR'"`
+	Weight           float64        `json:"weight" gorm:"type:decimal(12,3);not null"`
+	WeightUnit       string         `json:"weight_unit" gorm:"type:enum('g', 'kg', 'ton');not null;default:'kg'"`
+	Quantity         *int           `json:"quantity" gorm:"type:int"`
+	QuantityUnit     string         `json:"quantity_unit" gorm:"type:varchar(32)"`
+	WeightIsEstimate bool           `json:"weight_is_estimate" gorm:"not null;default:false"`
+	ItemScale        string         `json:"item_scale" gorm:"type:enum('SMALL', 'LARGE');not null"`
+	Status           string         `json:"status" gorm:"type:enum('DRAFT', 'READY', 'RESERVED', 'IN_DELIVERY', 'SOLD', 'WITHDRAWN');not null;default:'READY';index"`
+	ListingType      string         `json:"listing_type" gorm:"type:enum('FIXED', 'AUCTION');not null;default:'FIXED'"`
+	Category         string         `json:"category" gorm:"type:varchar(64)"`
+	Description      string         `json:"description" gorm:"type:text"`
+	Lat              *float64       `json:"lat" gorm:"type:float"`
+	Lon              *float64       `json:"lon" gorm:"type:float"`
+	ThumbnailUrl     string         `json:"thumbnail_url" gorm:"type:varchar(2048)"`
+	PostedBy         uuid.UUID      `json:"posted_by" gorm:"type:varchar(255);not null"`
+	OrderedBy        *uuid.UUID     `json:"ordered_by" gorm:"type:varchar(255)"`
+	CreatedAt        time.Time      `json:"created_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt        time.Time      `json:"updated_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	DeletedAt        gorm.DeletedAt `json:"deleted_at" gorm:"type:datetime"`
+	PostedByUser     User           `json:"posted_by_user" gorm:"foreignKey:PostedBy;references:ID"`
+	OrderedByUser    User           `json:"ordered_by_user" gorm:"foreignKey:OrderedBy;references:ID"`
 
 	TransactionActivities []MarketItemTransactionActivities `json:"transaction_activities" gorm:"foreignKey:ItemID;references:ID"`
 	PickupInformations    []MarketItemPickupInformations    `json:"pickup_informations" gorm:"foreignKey:ItemID;references:ID"`
@@ -29,6 +68,18 @@ type MarketItems struct {
 
 func (model *MarketItems) BeforeCreate(tx *gorm.DB) error {
 	model.ID = uuid.New()
+	if model.Status == "" {
+		model.Status = MarketItemStatusReady
+	}
+	if model.ListingType == "" {
+		model.ListingType = ListingTypeFixed
+	}
+	if model.Currency == "" {
+		model.Currency = DefaultCurrency
+	}
+	if model.WeightUnit == "" {
+		model.WeightUnit = WeightUnitKilogram
+	}
 	model.CreatedAt = time.Now()
 	model.UpdatedAt = time.Now()
 	return nil
@@ -38,3 +89,27 @@ func (model *MarketItems) BeforeUpdate(tx *gorm.DB) error {
 	model.UpdatedAt = time.Now()
 	return nil
 }
+
+func IsValidMarketItemStatus(status string) bool {
+	for _, s := range MarketItemStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// MarketItemStatusForTransaction maps a transaction activity status to the
+// listing status the item should carry while that activity is the latest one.
+func MarketItemStatusForTransaction(transactionStatus string) string {
+	switch transactionStatus {
+	case "ON_PROCESS":
+		return MarketItemStatusReserved
+	case "ON_DELIVER":
+		return MarketItemStatusInDelivery
+	case "FINISHED":
+		return MarketItemStatusSold
+	default:
+		return MarketItemStatusReady
+	}
+}
@@ -15,8 +15,12 @@ type MarketItemPickupInformations struct {
 	Description               string         `json:"description" gorm:"type:varchar(255)"`
 	PickupLocationAddress     string         `json:"pickup_location_address" gorm:"type:varchar(255);not null"`
 	PickupLocationDescription string         `json:"pickup_location_description" gorm:"type:varchar(255)"`
-	ServicePrice              float64        `json:"service_price" gorm:"type:decimal(15,2);not null"`
-	DeliveryPrice             float64        `json:"delivery_price" gorm:"type:decimal(15,2);not null"`
+	PickupLat                 *float64       `json:"pickup_lat" gorm:"type:float"`
+	PickupLon                 *float64       `json:"pickup_lon" gorm:"type:float"`
+	DistanceKm                *float64       `json:"distance_km" gorm:"type:decimal(10,2)"`
+	ServicePrice              Money          `json:"service_price" gorm:"type:decimal(15,2);not null"`
+	DeliveryPrice             Money          `json:"delivery_price" gorm:"type:decimal(15,2);not null"`
+	TariffID                  *uuid.UUID     `json:"tariff_id" gorm:"type:varchar(255)"`
 	CreatedAt                 time.Time      `json:"created_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
 	UpdatedAt                 time.Time      `json:"updated_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
 	DeletedAt                 gorm.DeletedAt `json:"deleted_at" gorm:"type:datetime"`
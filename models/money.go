@@ -0,0 +1,197 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is the only currency the marketplace trades in today.
+const DefaultCurrency = "IDR"
+
+var ErrInvalidMoney = errors.New("invalid money amount")
+
+// Money is an exact amount in minor units (hundredths) of the currency. It is
+// stored in decimal(15,2) columns and serialized as a JSON number with two
+// decimals, so it never passes through float64.
+//
+// Rounding rules: amounts entered by users must have at most two decimals and
+// are rejected otherwise; intermediate products are rounded half away from
+// zero to the minor unit; fees are then rounded half away from zero to a whole
+// currency unit with RoundToUnit.
+type Money int64
+
+const minorUnitsPerUnit = 100
+
+// NewMoney returns an amount of whole currency units.
+func NewMoney(units int64) Money {
+	return Money(units * minorUnitsPerUnit)
+}
+
+// ParseMoney parses a decimal string such as "1500", "1500.5" or "1500.50".
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, ErrInvalidMoney
+	}
+
+	negative := false
+	if s[0] == '-' || s[0] == '+' {
+		negative = s[0] == '-'
+		s = s[1:]
+	}
+
+	units, fraction, hasFraction := strings.Cut(s, ".")
+	if units == "" || (hasFraction && (fraction == "" || len(fraction) > 2)) {
+		return 0, ErrInvalidMoney
+	}
+	if !isDigits(units) || !isDigits(fraction) {
+		return 0, ErrInvalidMoney
+	}
+	for len(fraction) < 2 {
+		fraction += "0"
+	}
+
+	whole, err := strconv.ParseInt(units, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidMoney
+	}
+	minor, err := strconv.ParseInt(fraction, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidMoney
+	}
+	if whole > (math.MaxInt64-minor)/minorUnitsPerUnit {
+		return 0, ErrInvalidMoney
+	}
+
+	amount := Money(whole*minorUnitsPerUnit + minor)
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+// isDigits reports whether s consists of ASCII digits only. strconv.ParseInt
+// alone would also accept a sign.
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// MulRatio returns m * num / den rounded half away from zero to the minor unit.
+func (m Money) MulRatio(num, den int64) Money {
+	product := int64(m) * num
+	quotient, remainder := product/den, product%den
+	if remainder != 0 && 2*abs(remainder) >= abs(den) {
+		if (product < 0) != (den < 0) {
+			quotient--
+		} else {
+			quotient++
+		}
+	}
+	return Money(quotient)
+}
+
+// MulQuantity multiplies a per-unit rate by a quantity with two decimals of
+// precision, such as a weight in kg or a distance in km.
+func (m Money) MulQuantity(quantity float64) Money {
+	return m.MulRatio(int64(math.Round(quantity*minorUnitsPerUnit)), minorUnitsPerUnit)
+}
+
+// RoundToUnit rounds half away from zero to a whole currency unit.
+func (m Money) RoundToUnit() Money {
+	return m.MulRatio(1, minorUnitsPerUnit) * minorUnitsPerUnit
+}
+
+func (m Money) String() string {
+	sign := ""
+	amount := int64(m)
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, amount/minorUnitsPerUnit, amount%minorUnitsPerUnit)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	parsed, err := ParseMoney(strings.Trim(string(data), `"`))
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// UnmarshalParam lets gin bind Money from form and query parameters.
+func (m *Money) UnmarshalParam(param string) error {
+	if param == "" {
+		*m = 0
+		return nil
+	}
+	parsed, err := ParseMoney(param)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+func (m *Money) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*m = 0
+		return nil
+	case []byte:
+		return m.scanString(string(v))
+	case string:
+		return m.scanString(v)
+	case int64:
+		*m = NewMoney(v)
+		return nil
+	case float64:
+		return m.scanString(strconv.FormatFloat(v, 'f', 2, 64))
+	}
+	return fmt.Errorf("cannot scan %T into Money", value)
+}
+
+func (m *Money) scanString(s string) error {
+	// decimal columns may come back with more trailing zeros than two.
+	if units, fraction, ok := strings.Cut(s, "."); ok && len(fraction) > 2 {
+		fraction = strings.TrimRight(fraction, "0")
+		if len(fraction) > 2 {
+			return ErrInvalidMoney
+		}
+		s = units + "." + fraction
+		if fraction == "" {
+			s = units
+		}
+	}
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
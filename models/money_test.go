@@ -0,0 +1,107 @@
+package models
+
+import "testing"
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Money
+		err   bool
+	}{
+		{input: "1500", want: 150000},
+		{input: "1500.5", want: 150050},
+		{input: "1500.50", want: 150050},
+		{input: " 0.01 ", want: 1},
+		{input: "-12.30", want: -1230},
+		{input: "+7", want: 700},
+		{input: "", err: true},
+		{input: "-", err: true},
+		{input: "1.", err: true},
+		{input: ".5", err: true},
+		{input: "1.234", err: true},
+		{input: "1,5", err: true},
+		{input: "abc", err: true},
+		{input: "+-5", err: true},
+		{input: "--5", err: true},
+		{input: "1.+5", err: true},
+		{input: "1.-5", err: true},
+		{input: "1e3", err: true},
+		{input: "92233720368547758.08", err: true},
+	}
+
+	for _, test := range tests {
+		got, err := ParseMoney(test.input)
+		if test.err {
+			if err == nil {
+				t.Errorf("ParseMoney(%q) = %v, want an error", test.input, got)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("ParseMoney(%q) = %v, %v, want %v", test.input, got, err, test.want)
+		}
+	}
+}
+
+func TestMoneyMulRatio(t *testing.T) {
+	tests := []struct {
+		amount   Money
+		num, den int64
+		want     Money
+	}{
+		{amount: 1000, num: 1, den: 3, want: 333},
+		{amount: 1000, num: 2, den: 3, want: 667},
+		{amount: 5, num: 1, den: 2, want: 3},
+		{amount: -5, num: 1, den: 2, want: -3},
+		{amount: 5, num: -1, den: 2, want: -3},
+		{amount: 15, num: 1, den: 10, want: 2},
+		{amount: 14, num: 1, den: 10, want: 1},
+		{amount: 100, num: 3, den: 1, want: 300},
+	}
+
+	for _, test := range tests {
+		if got := test.amount.MulRatio(test.num, test.den); got != test.want {
+			t.Errorf("%v.MulRatio(%d, %d) = %v, want %v", test.amount, test.num, test.den, got, test.want)
+		}
+	}
+}
+
+func TestMoneyMulQuantity(t *testing.T) {
+	tests := []struct {
+		rate     Money
+		quantity float64
+		want     Money
+	}{
+		{rate: NewMoney(1500), quantity: 2, want: NewMoney(3000)},
+		{rate: NewMoney(1500), quantity: 0.5, want: NewMoney(750)},
+		{rate: NewMoney(1500), quantity: 1.234, want: NewMoney(1845)},
+		{rate: NewMoney(1500), quantity: 1.235, want: NewMoney(1860)},
+		{rate: 333, quantity: 0.5, want: 167},
+		{rate: NewMoney(1), quantity: 0.1 + 0.2, want: 30},
+	}
+
+	for _, test := range tests {
+		if got := test.rate.MulQuantity(test.quantity); got != test.want {
+			t.Errorf("%v.MulQuantity(%v) = %v, want %v", test.rate, test.quantity, got, test.want)
+		}
+	}
+}
+
+func TestMoneyRoundToUnit(t *testing.T) {
+	tests := []struct {
+		amount Money
+		want   Money
+	}{
+		{amount: 150049, want: 150000},
+		{amount: 150050, want: 150100},
+		{amount: 150099, want: 150100},
+		{amount: -150050, want: -150100},
+		{amount: 0, want: 0},
+	}
+
+	for _, test := range tests {
+		if got := test.amount.RoundToUnit(); got != test.want {
+			t.Errorf("%v.RoundToUnit() = %v, want %v", test.amount, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	NotificationReservationExpired  = "RESERVATION_EXPIRED"
+	NotificationRescheduleRequested = "RESCHEDULE_REQUESTED"
+	NotificationRescheduleAccepted  = "RESCHEDULE_ACCEPTED"
+	NotificationRescheduleDeclined  = "RESCHEDULE_DECLINED"
+)
+
+// Notification is a message for one user about something that happened
+// without them acting, such as the system cancelling their order.
+type Notification struct {
+	ID        uuid.UUID      `json:"id" gorm:"type:varchar(255);primary_key"`
+	UserID    uuid.UUID      `json:"user_id" gorm:"type:varchar(255);not null;index"`
+	Type      string         `json:"type" gorm:"type:varchar(64);not null"`
+	Title     string         `json:"title" gorm:"type:varchar(255);not null"`
+	Message   string         `json:"message" gorm:"type:text"`
+	OrderID   *uuid.UUID     `json:"order_id" gorm:"type:varchar(255)"`
+	ReadAt    *time.Time     `json:"read_at" gorm:"type:datetime"`
+	CreatedAt time.Time      `json:"created_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"type:datetime"`
+}
+
+func (model *Notification) BeforeCreate(tx *gorm.DB) error {
+	model.ID = uuid.New()
+	model.CreatedAt = time.Now()
+	model.UpdatedAt = time.Now()
+	return nil
+}
+
+func (model *Notification) BeforeUpdate(tx *gorm.DB) error {
+	model.UpdatedAt = time.Now()
+	return nil
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	OfferStatusPending   = "PENDING"
+	OfferStatusCountered = "COUNTERED"
+	OfferStatusAccepted  = "ACCEPTED"
+	OfferStatusRejected  = "REJECTED"
+	OfferStatusWithdrawn = "WITHDRAWN"
+	OfferStatusExpired   = "EXPIRED"
+)
+
+// Offer is a price proposal on a market item. A buyer opens the negotiation
+// and each counter-offer is a new Offer pointing at the one it answers, so the
+// chain keeps the full history. The buyer's handover details travel with the
+// offer so that accepting it can place the order straight away.
+type Offer struct {
+	ID                        uuid.UUID      `json:"id" gorm:"type:varchar(255);primary_key"`
+	ItemID                    uuid.UUID      `json:"item_id" gorm:"type:varchar(255);not null;index"`
+	BuyerID                   uuid.UUID      `json:"buyer_id" gorm:"type:varchar(255);not null;index"`
+	SellerID                  uuid.UUID      `json:"seller_id" gorm:"type:varchar(255);not null;index"`
+	ParentOfferID             *uuid.UUID     `json:"parent_offer_id" gorm:"type:varchar(255)"`
+	ProposedBy                string         `json:"proposed_by" gorm:"type:enum('BUYER', 'SELLER');not null"`
+	Price                     Money          `json:"price" gorm:"type:decimal(15,2);not null"`
+	Currency                  string         `json:"currency" gorm:"type:varchar(3);not null;default:'IDR'"`
+	Message                   string         `json:"message" gorm:"type:text"`
+	Status                    string         `json:"status" gorm:"type:enum('PENDING', 'COUNTERED', 'ACCEPTED', 'REJECTED', 'WITHDRAWN', 'EXPIRED');not null;default:'PENDING';index"`
+	ExpiresAt                 time.Time      `json:"expires_at" gorm:"type:datetime;not null"`
+	Fulfillment               string         `json:"fulfillment" gorm:"type:enum('DELIVERY', 'SELF_PICKUP', 'DROP_OFF');not null;default:'DELIVERY'"`
+	RecipientName             string         `json:"recipient_name" gorm:"type:varchar(255);not null"`
+	RecipientPhone            string         `json:"recipient_phone" gorm:"type:varchar(32);not null"`
+	PickupLocationAddress     string         `json:"pickup_location_address" gorm:"type:varchar(255)"`
+	PickupLocationDescription string         `json:"pickup_location_description" gorm:"type:varchar(255)"`
+	PickupLat                 *float64       `json:"pickup_lat" gorm:"type:float"`
+	PickupLon                 *float64       `json:"pickup_lon" gorm:"type:float"`
+	OrderID                   *uuid.UUID     `json:"order_id" gorm:"type:varchar(255)"`
+	CreatedAt                 time.Time      `json:"created_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt                 time.Time      `json:"updated_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	DeletedAt                 gorm.DeletedAt `json:"deleted_at" gorm:"type:datetime"`
+
+	MarketItem MarketItems `json:"market_item" gorm:"foreignKey:ItemID;references:ID"`
+	Buyer      User        `json:"buyer" gorm:"foreignKey:BuyerID;references:ID"`
+	Seller     User        `json:"seller" gorm:"foreignKey:SellerID;references:ID"`
+}
+
+func (model *Offer) BeforeCreate(tx *gorm.DB) error {
+	model.ID = uuid.New()
+	if model.Currency == "" {
+		model.Currency = DefaultCurrency
+	}
+	model.CreatedAt = time.Now()
+	model.UpdatedAt = time.Now()
+	return nil
+}
+
+func (model *Offer) BeforeUpdate(tx *gorm.DB) error {
+	model.UpdatedAt = time.Now()
+	return nil
+}
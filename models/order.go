@@ -0,0 +1,85 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Fulfillment methods. DELIVERY is the courier flow of large-scale orders;
+// small-scale orders are handed over directly.
+const (
+	FulfillmentDelivery   = "DELIVERY"
+	FulfillmentSelfPickup = "SELF_PICKUP"
+	FulfillmentDropOff    = "DROP_OFF"
+)
+
+// Order is one purchase of a market item. An item can be ordered again after
+// a cancellation, so an item has many orders over its lifetime and each order
+// keeps its own pickup information and activity timeline. Delivery orders
+// can be assigned to a DRIVER, who then moves them through pickup and
+// delivery alongside the seller.
+//
+// The Declared* fields keep the weight and prices the order was placed at.
+// When the load is weighed at handover, ItemPrice (for orders PricedPerKg)
+// and the pickup information's fees are settled on MeasuredWeight, and
+// WeightDiscrepancy holds the relative difference to the declared weight.
+type Order struct {
+	ID                       uuid.UUID      `json:"id" gorm:"type:varchar(255);primary_key"`
+	ItemID                   uuid.UUID      `json:"item_id" gorm:"type:varchar(255);not null;index"`
+	BuyerID                  uuid.UUID      `json:"buyer_id" gorm:"type:varchar(255);not null;index"`
+	SellerID                 uuid.UUID      `json:"seller_id" gorm:"type:varchar(255);not null;index"`
+	PickupInformationID      uuid.UUID      `json:"pickup_information_id" gorm:"type:varchar(255);not null"`
+	ItemPrice                Money          `json:"item_price" gorm:"type:decimal(15,2);not null"`
+	PricedPerKg              bool           `json:"priced_per_kg" gorm:"not null;default:false"`
+	DeclaredWeight           float64        `json:"declared_weight" gorm:"type:decimal(12,3);not null;default:0"`
+	DeclaredItemPrice        Money          `json:"declared_item_price" gorm:"type:decimal(15,2);not null;default:0"`
+	DeclaredServicePrice     Money          `json:"declared_service_price" gorm:"type:decimal(15,2);not null;default:0"`
+	DeclaredDeliveryPrice    Money          `json:"declared_delivery_price" gorm:"type:decimal(15,2);not null;default:0"`
+	MeasuredWeight           *float64       `json:"measured_weight" gorm:"type:decimal(12,3)"`
+	WeightDiscrepancy        *float64       `json:"weight_discrepancy" gorm:"type:decimal(8,4)"`
+	WeightDiscrepancyFlagged bool           `json:"weight_discrepancy_flagged" gorm:"not null;default:false;index"`
+	Currency                 string         `json:"currency" gorm:"type:varchar(3);not null;default:'IDR'"`
+	Fulfillment              string         `json:"fulfillment" gorm:"type:enum('DELIVERY', 'SELF_PICKUP', 'DROP_OFF');not null;default:'DELIVERY'"`
+	Status                   string         `json:"status" gorm:"type:enum('ON_PROCESS', 'ON_DELIVER', 'FINISHED', 'CANCELLED');not null"`
+	ReservedUntil            *time.Time     `json:"reserved_until" gorm:"type:datetime;index"`
+	ContractID               *uuid.UUID     `json:"contract_id" gorm:"type:varchar(255);index"`
+	SlotID                   *uuid.UUID     `json:"slot_id" gorm:"type:varchar(255);index"`
+	DriverID                 *uuid.UUID     `json:"driver_id" gorm:"type:varchar(255);index"`
+	DriverAssignedAt         *time.Time     `json:"driver_assigned_at" gorm:"type:datetime"`
+	RefundedAmount           Money          `json:"refunded_amount" gorm:"type:decimal(15,2);not null;default:0"`
+	HandoverPIN              string         `json:"-" gorm:"type:varchar(6)"`
+	HandoverPINAttempts      int            `json:"-" gorm:"not null;default:0"`
+	CreatedAt                time.Time      `json:"created_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt                time.Time      `json:"updated_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	DeletedAt                gorm.DeletedAt `json:"deleted_at" gorm:"type:datetime"`
+
+	MarketItem        MarketItems                       `json:"market_item" gorm:"foreignKey:ItemID;references:ID"`
+	Buyer             User                              `json:"buyer" gorm:"foreignKey:BuyerID;references:ID"`
+	Seller            User                              `json:"seller" gorm:"foreignKey:SellerID;references:ID"`
+	Driver            *User                             `json:"driver" gorm:"foreignKey:DriverID;references:ID"`
+	PickupInformation MarketItemPickupInformations      `json:"pickup_information" gorm:"foreignKey:PickupInformationID;references:ID"`
+	PickupSlot        *PickupSlot                       `json:"pickup_slot" gorm:"foreignKey:SlotID;references:ID"`
+	Activities        []MarketItemTransactionActivities `json:"activities" gorm:"foreignKey:OrderID;references:ID"`
+	Disputes          []Dispute                         `json:"disputes" gorm:"foreignKey:OrderID;references:ID"`
+	Invoice           *Invoice                          `json:"invoice" gorm:"foreignKey:OrderID;references:ID"`
+}
+
+func (model *Order) BeforeCreate(tx *gorm.DB) error {
+	model.ID = uuid.New()
+	if model.Currency == "" {
+		model.Currency = DefaultCurrency
+	}
+	if model.Fulfillment == "" {
+		model.Fulfillment = FulfillmentDelivery
+	}
+	model.CreatedAt = time.Now()
+	model.UpdatedAt = time.Now()
+	return nil
+}
+
+func (model *Order) BeforeUpdate(tx *gorm.DB) error {
+	model.UpdatedAt = time.Now()
+	return nil
+}
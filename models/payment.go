@@ -0,0 +1,186 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	PaymentStatusPending  = "PENDING"
+	PaymentStatusPaid     = "PAID"
+	PaymentStatusFailed   = "FAILED"
+	PaymentStatusRefunded = "REFUNDED"
+)
+
+// Ledger accounts. PROVIDER_CASH is the money held at the payment provider,
+// ESCROW what buyers paid for orders that are not finished yet,
+// BUYER_RECEIVABLE what each buyer still owes after the handover weighing
+// raised their total, SELLER_PAYABLE what the platform owes each seller and
+// REFUNDS_PAYABLE what it still has to send back to each buyer (the last
+// three per OwnerID), and PLATFORM_FEES and DELIVERY_FEES the platform's
+// revenue.
+const (
+	LedgerAccountProviderCash    = "PROVIDER_CASH"
+	LedgerAccountEscrow          = "ESCROW"
+	LedgerAccountBuyerReceivable = "BUYER_RECEIVABLE"
+	LedgerAccountSellerPayable   = "SELLER_PAYABLE"
+	LedgerAccountRefundsPayable  = "REFUNDS_PAYABLE"
+	LedgerAccountPlatformFees    = "PLATFORM_FEES"
+	LedgerAccountDeliveryFees    = "DELIVERY_FEES"
+)
+
+const (
+	RefundStatusPending = "PENDING"
+	RefundStatusSent    = "SENT"
+	RefundStatusFailed  = "FAILED"
+)
+
+const (
+	PayoutStatusPending = "PENDING"
+	PayoutStatusPaid    = "PAID"
+)
+
+// Payment is a buyer's charge for an order at the payment provider. It is
+// stored before the provider is asked for the charge, so ChargeID stays empty
+// until the provider answered. Once PAID the money sits in escrow until the
+// order is finished, when it is released to the seller and the platform
+// (ReleasedAt), or cancelled, when it goes back to the buyer.
+type Payment struct {
+	ID             uuid.UUID      `json:"id" gorm:"type:varchar(255);primary_key"`
+	OrderID        uuid.UUID      `json:"order_id" gorm:"type:varchar(255);not null;index"`
+	BuyerID        uuid.UUID      `json:"buyer_id" gorm:"type:varchar(255);not null;index"`
+	Provider       string         `json:"provider" gorm:"type:varchar(32);not null"`
+	ChargeID       *string        `json:"charge_id" gorm:"type:varchar(255);uniqueIndex"`
+	CheckoutURL    string         `json:"checkout_url" gorm:"type:varchar(2048)"`
+	Amount         Money          `json:"amount" gorm:"type:decimal(15,2);not null"`
+	RefundedAmount Money          `json:"refunded_amount" gorm:"type:decimal(15,2);not null;default:0"`
+	Currency       string         `json:"currency" gorm:"type:varchar(3);not null;default:'IDR'"`
+	Status         string         `json:"status" gorm:"type:enum('PENDING', 'PAID', 'FAILED', 'REFUNDED');not null;default:'PENDING';index"`
+	PaidAt         *time.Time     `json:"paid_at" gorm:"type:datetime"`
+	ReleasedAt     *time.Time     `json:"released_at" gorm:"type:datetime"`
+	CreatedAt      time.Time      `json:"created_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt      time.Time      `json:"updated_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	DeletedAt      gorm.DeletedAt `json:"deleted_at" gorm:"type:datetime"`
+}
+
+func (model *Payment) BeforeCreate(tx *gorm.DB) error {
+	model.ID = uuid.New()
+	if model.Status == "" {
+		model.Status = PaymentStatusPending
+	}
+	if model.Currency == "" {
+		model.Currency = DefaultCurrency
+	}
+	model.CreatedAt = time.Now()
+	model.UpdatedAt = time.Now()
+	return nil
+}
+
+func (model *Payment) BeforeUpdate(tx *gorm.DB) error {
+	model.UpdatedAt = time.Now()
+	return nil
+}
+
+// PaymentRefund is money to be sent back to the buyer of a payment. It is
+// recorded as PENDING together with the change that caused it and only sent
+// to the provider once that change is committed, with its ID as the
+// idempotency key so that a retry never refunds twice. A refund the provider
+// rejects is FAILED and stays owed in REFUNDS_PAYABLE until it is settled by
+// hand.
+type PaymentRefund struct {
+	ID               uuid.UUID      `json:"id" gorm:"type:varchar(255);primary_key"`
+	PaymentID        uuid.UUID      `json:"payment_id" gorm:"type:varchar(255);not null;index"`
+	OrderID          uuid.UUID      `json:"order_id" gorm:"type:varchar(255);not null;index"`
+	BuyerID          uuid.UUID      `json:"buyer_id" gorm:"type:varchar(255);not null"`
+	Amount           Money          `json:"amount" gorm:"type:decimal(15,2);not null"`
+	Currency         string         `json:"currency" gorm:"type:varchar(3);not null;default:'IDR'"`
+	Description      string         `json:"description" gorm:"type:varchar(255);not null"`
+	Status           string         `json:"status" gorm:"type:enum('PENDING', 'SENT', 'FAILED');not null;default:'PENDING';index"`
+	ProviderRefundID *string        `json:"provider_refund_id" gorm:"type:varchar(255)"`
+	SentAt           *time.Time     `json:"sent_at" gorm:"type:datetime"`
+	CreatedAt        time.Time      `json:"created_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt        time.Time      `json:"updated_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	DeletedAt        gorm.DeletedAt `json:"deleted_at" gorm:"type:datetime"`
+}
+
+func (model *PaymentRefund) BeforeCreate(tx *gorm.DB) error {
+	model.ID = uuid.New()
+	if model.Status == "" {
+		model.Status = RefundStatusPending
+	}
+	if model.Currency == "" {
+		model.Currency = DefaultCurrency
+	}
+	model.CreatedAt = time.Now()
+	model.UpdatedAt = time.Now()
+	return nil
+}
+
+func (model *PaymentRefund) BeforeUpdate(tx *gorm.DB) error {
+	model.UpdatedAt = time.Now()
+	return nil
+}
+
+// LedgerEntry is one side of a double-entry journal. The entries sharing a
+// JournalID always balance: their debits sum to their credits. Entries are
+// only ever appended.
+type LedgerEntry struct {
+	ID          uuid.UUID  `json:"id" gorm:"type:varchar(255);primary_key"`
+	JournalID   uuid.UUID  `json:"journal_id" gorm:"type:varchar(255);not null;index"`
+	OrderID     *uuid.UUID `json:"order_id" gorm:"type:varchar(255);index"`
+	PaymentID   *uuid.UUID `json:"payment_id" gorm:"type:varchar(255);index"`
+	PayoutID    *uuid.UUID `json:"payout_id" gorm:"type:varchar(255);index"`
+	Account     string     `json:"account" gorm:"type:varchar(32);not null;index:idx_ledger_account_owner"`
+	OwnerID     *uuid.UUID `json:"owner_id" gorm:"type:varchar(255);index:idx_ledger_account_owner"`
+	Debit       Money      `json:"debit" gorm:"type:decimal(15,2);not null;default:0"`
+	Credit      Money      `json:"credit" gorm:"type:decimal(15,2);not null;default:0"`
+	Currency    string     `json:"currency" gorm:"type:varchar(3);not null;default:'IDR'"`
+	Description string     `json:"description" gorm:"type:varchar(255);not null"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+}
+
+func (model *LedgerEntry) BeforeCreate(tx *gorm.DB) error {
+	model.ID = uuid.New()
+	if model.Currency == "" {
+		model.Currency = DefaultCurrency
+	}
+	model.CreatedAt = time.Now()
+	return nil
+}
+
+// Payout is what an order earned its seller once its escrow was released.
+// An admin marks it PAID after transferring the money, with the transfer's
+// reference.
+type Payout struct {
+	ID        uuid.UUID      `json:"id" gorm:"type:varchar(255);primary_key"`
+	OrderID   uuid.UUID      `json:"order_id" gorm:"type:varchar(255);not null;uniqueIndex"`
+	SellerID  uuid.UUID      `json:"seller_id" gorm:"type:varchar(255);not null;index"`
+	Amount    Money          `json:"amount" gorm:"type:decimal(15,2);not null"`
+	Currency  string         `json:"currency" gorm:"type:varchar(3);not null;default:'IDR'"`
+	Status    string         `json:"status" gorm:"type:enum('PENDING', 'PAID');not null;default:'PENDING';index"`
+	Reference string         `json:"reference" gorm:"type:varchar(255)"`
+	PaidAt    *time.Time     `json:"paid_at" gorm:"type:datetime"`
+	CreatedAt time.Time      `json:"created_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"type:datetime"`
+}
+
+func (model *Payout) BeforeCreate(tx *gorm.DB) error {
+	model.ID = uuid.New()
+	if model.Status == "" {
+		model.Status = PayoutStatusPending
+	}
+	if model.Currency == "" {
+		model.Currency = DefaultCurrency
+	}
+	model.CreatedAt = time.Now()
+	model.UpdatedAt = time.Now()
+	return nil
+}
+
+func (model *Payout) BeforeUpdate(tx *gorm.DB) error {
+	model.UpdatedAt = time.Now()
+	return nil
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PickupSlot is a time window in which a seller can hand over orders. Up to
+// Capacity orders can be booked into it; Booked is only changed through
+// conditional updates so concurrent bookings cannot exceed it.
+type PickupSlot struct {
+	ID        uuid.UUID      `json:"id" gorm:"type:varchar(255);primary_key"`
+	SellerID  uuid.UUID      `json:"seller_id" gorm:"type:varchar(255);not null;index"`
+	StartsAt  time.Time      `json:"starts_at" gorm:"type:datetime;not null;index"`
+	EndsAt    time.Time      `json:"ends_at" gorm:"type:datetime;not null"`
+	Capacity  int            `json:"capacity" gorm:"not null;default:1"`
+	Booked    int            `json:"booked" gorm:"not null;default:0"`
+	Note      string         `json:"note" gorm:"type:varchar(255)"`
+	CreatedAt time.Time      `json:"created_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"type:datetime"`
+}
+
+func (model *PickupSlot) BeforeCreate(tx *gorm.DB) error {
+	model.ID = uuid.New()
+	model.CreatedAt = time.Now()
+	model.UpdatedAt = time.Now()
+	return nil
+}
+
+func (model *PickupSlot) BeforeUpdate(tx *gorm.DB) error {
+	model.UpdatedAt = time.Now()
+	return nil
+}
+
+const (
+	RescheduleStatusPending   = "PENDING"
+	RescheduleStatusAccepted  = "ACCEPTED"
+	RescheduleStatusDeclined  = "DECLINED"
+	RescheduleStatusCancelled = "CANCELLED"
+)
+
+// RescheduleRequest asks to move an order to another pickup slot of its
+// seller. It only takes effect once the other party accepts it.
+type RescheduleRequest struct {
+	ID             uuid.UUID      `json:"id" gorm:"type:varchar(255);primary_key"`
+	OrderID        uuid.UUID      `json:"order_id" gorm:"type:varchar(255);not null;index"`
+	RequestedBy    uuid.UUID      `json:"requested_by" gorm:"type:varchar(255);not null"`
+	RequestedParty string         `json:"requested_party" gorm:"type:enum('BUYER', 'SELLER');not null"`
+	FromSlotID     *uuid.UUID     `json:"from_slot_id" gorm:"type:varchar(255)"`
+	ToSlotID       uuid.UUID      `json:"to_slot_id" gorm:"type:varchar(255);not null"`
+	Reason         string         `json:"reason" gorm:"type:text"`
+	Status         string         `json:"status" gorm:"type:enum('PENDING', 'ACCEPTED', 'DECLINED', 'CANCELLED');not null;default:'PENDING';index"`
+	CreatedAt      time.Time      `json:"created_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt      time.Time      `json:"updated_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	DeletedAt      gorm.DeletedAt `json:"deleted_at" gorm:"type:datetime"`
+
+	ToSlot PickupSlot `json:"to_slot" gorm:"foreignKey:ToSlotID;references:ID"`
+}
+
+func (model *RescheduleRequest) BeforeCreate(tx *gorm.DB) error {
+	model.ID = uuid.New()
+	if model.Status == "" {
+		model.Status = RescheduleStatusPending
+	}
+	model.CreatedAt = time.Now()
+	model.UpdatedAt = time.Now()
+	return nil
+}
+
+func (model *RescheduleRequest) BeforeUpdate(tx *gorm.DB) error {
+	model.UpdatedAt = time.Now()
+	return nil
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Review is one party's rating of the other after a finished order. Each
+// party can review an order once, which the unique index enforces.
+type Review struct {
+	ID            uuid.UUID      `json:"id" gorm:"type:varchar(255);primary_key"`
+	OrderID       uuid.UUID      `json:"order_id" gorm:"type:varchar(255);not null;uniqueIndex:idx_review_order_reviewer"`
+	ReviewerID    uuid.UUID      `json:"reviewer_id" gorm:"type:varchar(255);not null;uniqueIndex:idx_review_order_reviewer"`
+	RevieweeID    uuid.UUID      `json:"reviewee_id" gorm:"type:varchar(255);not null;index"`
+	ReviewerParty string         `json:"reviewer_party" gorm:"type:enum('BUYER', 'SELLER');not null"`
+	Rating        int            `json:"rating" gorm:"type:tinyint;not null"`
+	Comment       string         `json:"comment" gorm:"type:text"`
+	CreatedAt     time.Time      `json:"created_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt     time.Time      `json:"updated_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	DeletedAt     gorm.DeletedAt `json:"deleted_at" gorm:"type:datetime"`
+
+	Reviewer User `json:"reviewer" gorm:"foreignKey:ReviewerID;references:ID"`
+}
+
+func (model *Review) BeforeCreate(tx *gorm.DB) error {
+	model.ID = uuid.New()
+	model.CreatedAt = time.Now()
+	model.UpdatedAt = time.Now()
+	return nil
+}
+
+func (model *Review) BeforeUpdate(tx *gorm.DB) error {
+	model.UpdatedAt = time.Now()
+	return nil
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Tariff is one version of the fee schedule applied when an item is ordered.
+// Tariffs are never edited in place: a new tariff with a later EffectiveFrom
+// supersedes older ones, so pickup records can keep pointing at the version
+// that priced them. Empty ItemScale or Category match any value, and a zero
+// MaxWeight means the weight bracket is unbounded. MinimumFee is the lowest
+// delivery fee charged, whatever the weight and distance.
+type Tariff struct {
+	ID                 uuid.UUID      `json:"id" gorm:"type:varchar(255);primary_key"`
+	Name               string         `json:"name" gorm:"type:varchar(255);not null"`
+	ItemScale          string         `json:"item_scale" gorm:"type:varchar(16)"`
+	Category           string         `json:"category" gorm:"type:varchar(64)"`
+	MinWeight          float64        `json:"min_weight" gorm:"type:decimal(10,2);not null;default:0"`
+	MaxWeight          float64        `json:"max_weight" gorm:"type:decimal(10,2);not null;default:0"`
+	ServiceBlockWeight float64        `json:"service_block_weight" gorm:"type:decimal(10,2);not null"`
+	ServiceFeePerBlock Money          `json:"service_fee_per_block" gorm:"type:decimal(15,2);not null"`
+	DeliveryFeePerKg   Money          `json:"delivery_fee_per_kg" gorm:"type:decimal(15,2);not null"`
+	DeliveryFeePerKm   Money          `json:"delivery_fee_per_km" gorm:"type:decimal(15,2);not null;default:0"`
+	MinimumFee         Money          `json:"minimum_fee" gorm:"type:decimal(15,2);not null;default:0"`
+	EffectiveFrom      time.Time      `json:"effective_from" gorm:"type:datetime;not null"`
+	EffectiveUntil     *time.Time     `json:"effective_until" gorm:"type:datetime"`
+	CreatedBy          uuid.UUID      `json:"created_by" gorm:"type:varchar(255);not null"`
+	CreatedAt          time.Time      `json:"created_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt          time.Time      `json:"updated_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
+	DeletedAt          gorm.DeletedAt `json:"deleted_at" gorm:"type:datetime"`
+}
+
+func (model *Tariff) BeforeCreate(tx *gorm.DB) error {
+	model.ID = uuid.New()
+	model.CreatedAt = time.Now()
+	model.UpdatedAt = time.Now()
+	return nil
+}
+
+func (model *Tariff) BeforeUpdate(tx *gorm.DB) error {
+	model.UpdatedAt = time.Now()
+	return nil
+}
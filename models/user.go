@@ -12,7 +12,7 @@ type User struct {
 	PhoneNumber        string           `json:"phone_number" gorm:"type:varchar(255);not null;unique"`
 	Password           string           `json:"password" gorm:"type:varchar(255);not null"`
 	Name               string           `json:"name" gorm:"type:varchar(255);not null"`
-	Role               string           `json:"role" gorm:"type:enum('ADMIN', 'P_SMALL', 'P_LARGE', 'C_SMALL', 'C_LARGE')"`
+	Role               string           `json:"role" gorm:"type:enum('ADMIN', 'P_SMALL', 'P_LARGE', 'C_SMALL', 'C_LARGE', 'DRIVER')"`
 	CreatedAt          time.Time        `json:"created_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
 	UpdatedAt          time.Time        `json:"updated_at" gorm:"type:datetime;not null;default:CURRENT_TIMESTAMP"`
 	DeletedAt          gorm.DeletedAt   `json:"deleted_at" gorm:"type:datetime"`
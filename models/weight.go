@@ -0,0 +1,46 @@
+package models
+
+import (
+	"errors"
+	"math"
+)
+
+// Weights are stored in kilograms; the unit a seller listed in is kept only
+// for display.
+const (
+	WeightUnitGram     = "g"
+	WeightUnitKilogram = "kg"
+	WeightUnitTon      = "ton"
+)
+
+var ErrInvalidWeightUnit = errors.New("invalid weight unit")
+
+var kilogramsPerUnit = map[string]float64{
+	WeightUnitGram:     0.001,
+	WeightUnitKilogram: 1,
+	WeightUnitTon:      1000,
+}
+
+func IsValidWeightUnit(unit string) bool {
+	_, ok := kilogramsPerUnit[unit]
+	return ok
+}
+
+// NormalizeWeight converts value in unit to kilograms, rounded to the gram.
+func NormalizeWeight(value float64, unit string) (float64, error) {
+	factor, ok := kilogramsPerUnit[unit]
+	if !ok {
+		return 0, ErrInvalidWeightUnit
+	}
+	return math.Round(value*factor*1000) / 1000, nil
+}
+
+// WeightInUnit converts kilograms to unit for display. Unknown units fall back
+// to kilograms.
+func WeightInUnit(kilograms float64, unit string) float64 {
+	factor, ok := kilogramsPerUnit[unit]
+	if !ok {
+		return kilograms
+	}
+	return math.Round(kilograms/factor*1000) / 1000
+}
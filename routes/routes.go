@@ -3,6 +3,7 @@ package routes
 import (
 	"recyco/controllers"
 	"recyco/middlewares"
+	"recyco/services"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,6 +17,8 @@ func SetupRouter() *gin.Engine {
 		imageRoutes.Static("/markets", "uploads/markets")
 		imageRoutes.Static("/articles", "uploads/articles")
 		imageRoutes.Static("/community", "uploads/community")
+		imageRoutes.Static("/proofs", "uploads/proofs")
+		imageRoutes.Static("/disputes", "uploads/disputes")
 	}
 
 	authRoutes := r.Group("/auth")
@@ -48,15 +51,22 @@ func SetupRouter() *gin.Engine {
 		userRoutes.GET("/profile", controllers.GetUserProfile)
 	}
 
+	users := r.Group("/users")
+	users.Use(middlewares.JWTAuthMiddleware(), middlewares.JWTBlacklistMiddleware())
+	{
+		users.GET("/:id/profile", controllers.GetPublicProfile)
+	}
+
 	marketItems := r.Group("/markets")
 	marketItems.Use(middlewares.JWTAuthMiddleware(), middlewares.JWTBlacklistMiddleware())
 	{
-		marketItems.POST("/", middlewares.RoleMiddleware("ADMIN", "P_SMALL", "P_LARGE"), controllers.CreateMarketItem)
+		marketItems.POST("/", controllers.CreateMarketItem)
 		marketItems.GET("/", controllers.GetMarketItems)
 		marketItems.GET("/:id", controllers.GetMarketItemByID)
+		marketItems.GET("/:id/orders", middlewares.RoleMiddleware("ADMIN", "P_SMALL", "P_LARGE"), controllers.GetMarketItemOrders)
 		marketItems.GET("/markets_self", controllers.GetUserMarketItems)
-		marketItems.PUT("/:id", middlewares.RoleMiddleware("P_SMALL", "P_LARGE"), controllers.UpdateMarketItem)
-		marketItems.DELETE("/:id", middlewares.RoleMiddleware("P_SMALL", "P_LARGE"), controllers.DeleteMarketItem)
+		marketItems.PUT("/:id", controllers.UpdateMarketItem)
+		marketItems.DELETE("/:id", controllers.DeleteMarketItem)
 	}
 	marketItemsSelf := r.Group("/markets_self")
 	marketItemsSelf.Use(middlewares.JWTAuthMiddleware(), middlewares.JWTBlacklistMiddleware())
@@ -67,10 +77,170 @@ func SetupRouter() *gin.Engine {
 	marketTransactions := r.Group("/market_transactions")
 	marketTransactions.Use(middlewares.JWTAuthMiddleware(), middlewares.JWTBlacklistMiddleware())
 	{
-		marketTransactions.GET("/", middlewares.RoleMiddleware("P_LARGE", "C_LARGE"), controllers.GetMarketItemTransactions)
-		marketTransactions.POST("/", middlewares.RoleMiddleware("C_LARGE"), controllers.CreateMarketItemPickupInformation)
-		marketTransactions.GET("/:id", middlewares.RoleMiddleware("P_LARGE", "C_LARGE"), controllers.GetMarketItemPickupInformationByID)
-		marketTransactions.PUT("/:id", middlewares.RoleMiddleware("P_LARGE"), controllers.UpdateMarketItemTransactionStatus)
+		marketTransactions.GET("/", middlewares.RoleMiddleware("P_SMALL", "P_LARGE", "C_SMALL", "C_LARGE"), controllers.GetMarketItemTransactions)
+		marketTransactions.POST("/", controllers.CreateMarketItemPickupInformation)
+		marketTransactions.POST("/quote", controllers.QuoteMarketItemPickup)
+		marketTransactions.POST("/small", controllers.CreateSmallScaleOrder)
+		marketTransactions.GET("/export", middlewares.RoleMiddleware("P_LARGE", "C_LARGE"), controllers.ExportMarketTransactions)
+		marketTransactions.GET("/:id", middlewares.RoleMiddleware("P_SMALL", "P_LARGE", "C_SMALL", "C_LARGE"), controllers.GetMarketItemPickupInformationByID)
+		marketTransactions.GET("/:id/invoice", middlewares.RoleMiddleware("ADMIN", "P_SMALL", "P_LARGE", "C_SMALL", "C_LARGE"), controllers.GetMarketTransactionInvoice)
+		marketTransactions.PUT("/:id", middlewares.RoleMiddleware("P_SMALL", "P_LARGE", "C_SMALL", "C_LARGE"), controllers.UpdateMarketItemTransactionStatus)
+	}
+
+	orders := r.Group("/orders")
+	orders.Use(middlewares.JWTAuthMiddleware(), middlewares.JWTBlacklistMiddleware())
+	{
+		orders.GET("/", controllers.GetOrders)
+		orders.GET("/:id", controllers.GetOrderByID)
+		orders.PUT("/:id/status", middlewares.RoleMiddleware("P_SMALL", "P_LARGE", "C_SMALL", "C_LARGE"), controllers.UpdateOrderStatus)
+		orders.POST("/:id/cancel", controllers.CancelOrder)
+		orders.PUT("/:id/confirm", controllers.ConfirmSmallScaleOrder)
+		orders.POST("/:id/reviews", controllers.CreateOrderReview)
+		orders.POST("/:id/locations", middlewares.RoleMiddleware("DRIVER", "P_SMALL", "P_LARGE"), controllers.CreateLocationPing)
+		orders.GET("/:id/locations", controllers.GetOrderLocations)
+		orders.GET("/:id/tracking", controllers.StreamOrderTracking)
+		orders.GET("/:id/pin", controllers.GetOrderHandoverPIN)
+		orders.POST("/:id/pin/reset", controllers.ResetOrderHandoverPIN)
+		orders.PUT("/:id/driver", middlewares.RoleMiddleware("ADMIN", "P_SMALL", "P_LARGE"), controllers.AssignOrderDriver)
+		orders.GET("/:id/reschedules", controllers.GetOrderReschedules)
+		orders.POST("/:id/reschedules", controllers.RequestOrderReschedule)
+		orders.POST("/:id/reschedules/:requestId/accept", controllers.AcceptOrderReschedule)
+		orders.POST("/:id/reschedules/:requestId/decline", controllers.DeclineOrderReschedule)
+		orders.POST("/:id/reschedules/:requestId/cancel", controllers.CancelOrderReschedule)
+		orders.GET("/:id/reviews", controllers.GetOrderReviews)
+		orders.POST("/:id/disputes", controllers.OpenOrderDispute)
+		orders.GET("/:id/disputes", controllers.GetOrderDisputes)
+		orders.POST("/:id/payments", middlewares.RoleMiddleware("P_SMALL", "P_LARGE", "C_SMALL", "C_LARGE"), controllers.CreateOrderPayment)
+		orders.GET("/:id/payments", controllers.GetOrderPayments)
+	}
+
+	// Payment providers call the webhook without a JWT; it is authenticated
+	// by its signature.
+	r.POST("/payments/webhooks/:provider", controllers.PaymentWebhook)
+
+	// Buyers can only simulate checkout against the development provider.
+	if _, ok := services.Payments.(*services.FakePaymentProvider); ok {
+		payments := r.Group("/payments")
+		payments.Use(middlewares.JWTAuthMiddleware(), middlewares.JWTBlacklistMiddleware())
+		{
+			payments.POST("/:id/simulate", controllers.SimulatePayment)
+		}
+	}
+
+	payouts := r.Group("/payouts")
+	payouts.Use(middlewares.JWTAuthMiddleware(), middlewares.JWTBlacklistMiddleware())
+	{
+		payouts.GET("/", controllers.GetPayouts)
+		payouts.POST("/:id/paid", middlewares.RoleMiddleware("ADMIN"), controllers.MarkPayoutPaid)
+	}
+
+	ledger := r.Group("/ledger")
+	ledger.Use(middlewares.JWTAuthMiddleware(), middlewares.JWTBlacklistMiddleware())
+	{
+		ledger.GET("/balances", middlewares.RoleMiddleware("ADMIN"), controllers.GetLedgerBalances)
+	}
+
+	disputes := r.Group("/disputes")
+	disputes.Use(middlewares.JWTAuthMiddleware(), middlewares.JWTBlacklistMiddleware())
+	{
+		disputes.GET("/", controllers.GetDisputes)
+		disputes.GET("/:id", controllers.GetDisputeByID)
+		disputes.POST("/:id/messages", controllers.CreateDisputeMessage)
+		disputes.POST("/:id/resolve", middlewares.RoleMiddleware("ADMIN"), controllers.ResolveOrderDispute)
+	}
+
+	offers := r.Group("/offers")
+	offers.Use(middlewares.JWTAuthMiddleware(), middlewares.JWTBlacklistMiddleware())
+	{
+		offers.POST("/", controllers.CreateOffer)
+		offers.GET("/", controllers.GetOffers)
+		offers.GET("/:id", controllers.GetOfferByID)
+		offers.POST("/:id/counter", controllers.CounterOffer)
+		offers.POST("/:id/accept", controllers.AcceptOffer)
+		offers.POST("/:id/reject", controllers.RejectOffer)
+		offers.POST("/:id/withdraw", controllers.WithdrawOffer)
+	}
+
+	driver := r.Group("/driver")
+	driver.Use(middlewares.JWTAuthMiddleware(), middlewares.JWTBlacklistMiddleware(), middlewares.RoleMiddleware("DRIVER"))
+	{
+		driver.GET("/jobs", controllers.GetDriverJobs)
+		driver.GET("/jobs/:id", controllers.GetDriverJobByID)
+		driver.PUT("/jobs/:id/picked_up", controllers.MarkDriverJobPickedUp)
+		driver.PUT("/jobs/:id/delivered", controllers.MarkDriverJobDelivered)
+	}
+
+	pickupSlots := r.Group("/pickup_slots")
+	pickupSlots.Use(middlewares.JWTAuthMiddleware(), middlewares.JWTBlacklistMiddleware())
+	{
+		pickupSlots.POST("/", middlewares.RoleMiddleware("P_SMALL", "P_LARGE"), controllers.CreatePickupSlot)
+		pickupSlots.GET("/", controllers.GetPickupSlots)
+		pickupSlots.GET("/mine", middlewares.RoleMiddleware("P_SMALL", "P_LARGE"), controllers.GetUserPickupSlots)
+		pickupSlots.DELETE("/:id", middlewares.RoleMiddleware("P_SMALL", "P_LARGE"), controllers.DeletePickupSlot)
+	}
+
+	auctions := r.Group("/auctions")
+	auctions.Use(middlewares.JWTAuthMiddleware(), middlewares.JWTBlacklistMiddleware())
+	{
+		auctions.POST("/", middlewares.RoleMiddleware("P_LARGE"), controllers.CreateAuction)
+		auctions.GET("/", controllers.GetAuctions)
+		auctions.GET("/:id", controllers.GetAuctionByID)
+		auctions.GET("/:id/bids", controllers.GetAuctionBids)
+		auctions.POST("/:id/bids", controllers.PlaceAuctionBid)
+		auctions.POST("/:id/cancel", middlewares.RoleMiddleware("P_LARGE"), controllers.CancelAuction)
+	}
+
+	contracts := r.Group("/contracts")
+	contracts.Use(middlewares.JWTAuthMiddleware(), middlewares.JWTBlacklistMiddleware())
+	{
+		contracts.POST("/", middlewares.RoleMiddleware("P_LARGE"), controllers.CreateContract)
+		contracts.GET("/", controllers.GetContracts)
+		contracts.GET("/:id", controllers.GetContractByID)
+		contracts.GET("/:id/orders", controllers.GetContractOrders)
+		contracts.POST("/:id/accept", middlewares.RoleMiddleware("C_LARGE"), controllers.AcceptContract)
+		contracts.POST("/:id/pause", controllers.PauseContract)
+		contracts.POST("/:id/resume", controllers.ResumeContract)
+		contracts.POST("/:id/terminate", controllers.TerminateContract)
+	}
+
+	demands := r.Group("/demands")
+	demands.Use(middlewares.JWTAuthMiddleware(), middlewares.JWTBlacklistMiddleware())
+	{
+		demands.POST("/", controllers.CreateDemandListing)
+		demands.GET("/", controllers.GetDemandListings)
+		demands.GET("/mine", controllers.GetUserDemandListings)
+		demands.GET("/:id", controllers.GetDemandListingByID)
+		demands.PUT("/:id/close", controllers.CloseDemandListing)
+		demands.POST("/:id/responses", controllers.CreateDemandResponse)
+		demands.GET("/:id/responses", controllers.GetDemandResponses)
+		demands.POST("/:id/responses/:responseId/accept", controllers.AcceptDemandResponse)
+		demands.POST("/:id/responses/:responseId/reject", controllers.RejectDemandResponse)
+		demands.POST("/:id/responses/:responseId/withdraw", controllers.WithdrawDemandResponse)
+	}
+
+	notifications := r.Group("/notifications")
+	notifications.Use(middlewares.JWTAuthMiddleware(), middlewares.JWTBlacklistMiddleware())
+	{
+		notifications.GET("/", controllers.GetNotifications)
+		notifications.PUT("/:id/read", controllers.MarkNotificationRead)
+	}
+
+	tariffs := r.Group("/tariffs")
+	tariffs.Use(middlewares.JWTAuthMiddleware(), middlewares.JWTBlacklistMiddleware(), middlewares.RoleMiddleware("ADMIN"))
+	{
+		tariffs.POST("/", controllers.CreateTariff)
+		tariffs.GET("/", controllers.GetTariffs)
+		tariffs.GET("/:id", controllers.GetTariffByID)
+		tariffs.DELETE("/:id", controllers.RetireTariff)
+	}
+
+	marketRules := r.Group("/market_rules")
+	marketRules.Use(middlewares.JWTAuthMiddleware(), middlewares.JWTBlacklistMiddleware(), middlewares.RoleMiddleware("ADMIN"))
+	{
+		marketRules.GET("/", controllers.GetMarketRules)
+		marketRules.PUT("/scales", controllers.UpsertMarketScaleRule)
+		marketRules.DELETE("/scales/:id", controllers.DeleteMarketScaleRule)
+		marketRules.PUT("/roles", controllers.UpsertMarketRoleRule)
 	}
 
 	communityRoutes := r.Group("/communities")
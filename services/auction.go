@@ -0,0 +1,252 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"recyco/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrAuctionClosed = errors.New("auction is not open for bids")
+	ErrBidTooLow     = errors.New("bid is below the minimum accepted amount")
+	ErrBidOutpaced   = errors.New("another bid was placed first")
+)
+
+// Notification types sent while running auctions.
+const (
+	NotificationOutbid        = "AUCTION_OUTBID"
+	NotificationAuctionWon    = "AUCTION_WON"
+	NotificationAuctionSold   = "AUCTION_SOLD"
+	NotificationAuctionUnsold = "AUCTION_UNSOLD"
+)
+
+// MinimumBid is the lowest amount the next bid on auction must reach.
+func MinimumBid(auction models.Auction) models.Money {
+	if auction.BidCount == 0 {
+		return auction.StartingPrice
+	}
+	return auction.CurrentPrice + auction.MinIncrement
+}
+
+// PlaceBid records bid as the new leading bid of auction within tx. The
+// auction row is updated on the bid count it was read with, so bids are
+// strictly ordered: of two concurrent bids on the same state only one
+// succeeds and the other gets ErrBidOutpaced and must be placed again
+// against the new leading price. A bid landing in the last ExtensionSeconds
+// of the auction extends it. The previous leader is notified.
+func PlaceBid(tx *gorm.DB, auction *models.Auction, bid *models.Bid, now time.Time) error {
+	if auction.Status != models.AuctionStatusOpen || !auction.EndsAt.After(now) {
+		return ErrAuctionClosed
+	}
+	if bid.Amount < MinimumBid(*auction) {
+		return ErrBidTooLow
+	}
+
+	bid.AuctionID = auction.ID
+	bid.Sequence = auction.BidCount + 1
+	if err := tx.Create(bid).Error; err != nil {
+		// A concurrent bid already took this sequence number.
+		if IsDuplicateKey(err) {
+			return ErrBidOutpaced
+		}
+		return err
+	}
+
+	endsAt := auction.EndsAt
+	extension := time.Duration(auction.ExtensionSeconds) * time.Second
+	if endsAt.Sub(now) < extension {
+		endsAt = now.Add(extension)
+	}
+
+	result := tx.Model(&models.Auction{}).
+		Where("id = ? AND status = ? AND bid_count = ? AND ends_at > ?", auction.ID, models.AuctionStatusOpen, auction.BidCount, now).
+		Updates(map[string]interface{}{
+			"CurrentPrice":    bid.Amount,
+			"HighestBidID":    bid.ID,
+			"HighestBidderID": bid.BidderID,
+			"BidCount":        bid.Sequence,
+			"EndsAt":          endsAt,
+			"UpdatedAt":       now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrBidOutpaced
+	}
+
+	previous := auction.HighestBidderID
+	auction.CurrentPrice = bid.Amount
+	auction.HighestBidID = &bid.ID
+	auction.HighestBidderID = &bid.BidderID
+	auction.BidCount = bid.Sequence
+	auction.EndsAt = endsAt
+
+	if previous != nil && *previous != bid.BidderID {
+		notification := models.Notification{
+			UserID:  *previous,
+			Type:    NotificationOutbid,
+			Title:   "You have been outbid",
+			Message: "A higher bid of " + bid.Amount.String() + " " + auction.Currency + " was placed.",
+		}
+		if err := tx.Create(&notification).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CloseAuction settles an auction whose end time has passed. If the leading
+// bid meets the reserve price the item is ordered for the winning bidder at
+// that price with the handover details of the bid; otherwise the auction is
+// UNSOLD and the item goes back to a fixed-price listing. Either way the item
+// stops being an auction item.
+func CloseAuction(tx *gorm.DB, auction models.Auction, now time.Time) error {
+	sold := auction.HighestBidID != nil && auction.CurrentPrice >= auction.ReservePrice
+	status := models.AuctionStatusUnsold
+	if sold {
+		status = models.AuctionStatusSold
+	}
+
+	result := tx.Model(&models.Auction{}).
+		Where("id = ? AND status = ? AND bid_count = ? AND ends_at <= ?", auction.ID, models.AuctionStatusOpen, auction.BidCount, now).
+		Updates(map[string]interface{}{"Status": status, "UpdatedAt": now})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTransactionConflict
+	}
+
+	if !sold {
+		if err := listItemAtFixedPrice(tx, auction, now); err != nil {
+			return err
+		}
+		notification := models.Notification{
+			UserID:  auction.SellerID,
+			Type:    NotificationAuctionUnsold,
+			Title:   "Auction ended without a sale",
+			Message: "No bid reached the reserve price. The item is listed at its fixed price again.",
+		}
+		return tx.Create(&notification).Error
+	}
+
+	var item models.MarketItems
+	if err := tx.Where("id = ?", auction.ItemID).First(&item).Error; err != nil {
+		return err
+	}
+
+	var bid models.Bid
+	if err := tx.Where("id = ?", *auction.HighestBidID).First(&bid).Error; err != nil {
+		return err
+	}
+
+	pickup := models.MarketItemPickupInformations{
+		RecipientName:             bid.RecipientName,
+		RecipientPhone:            bid.RecipientPhone,
+		Description:               "Won at auction",
+		PickupLocationAddress:     bid.PickupLocationAddress,
+		PickupLocationDescription: bid.PickupLocationDescription,
+		PickupLat:                 bid.PickupLat,
+		PickupLon:                 bid.PickupLon,
+	}
+
+	// The item is reserved while it is still an auction item, so a
+	// fixed-price order cannot take it in between.
+	order, _, err := placeOrder(tx, item, bid.BidderID, &pickup, bid.Amount, models.FulfillmentDelivery, models.ListingTypeAuction)
+	if err != nil {
+		return err
+	}
+	if err := listItemAtFixedPrice(tx, auction, now); err != nil {
+		return err
+	}
+
+	if err := tx.Model(&models.Auction{}).Where("id = ?", auction.ID).Update("order_id", order.ID).Error; err != nil {
+		return err
+	}
+
+	for _, n := range []models.Notification{
+		{UserID: bid.BidderID, Type: NotificationAuctionWon, Title: "You won the auction",
+			Message: "Your bid of " + bid.Amount.String() + " " + auction.Currency + " won. The order has been placed."},
+		{UserID: auction.SellerID, Type: NotificationAuctionSold, Title: "Your auction has a winner",
+			Message: "The item sold for " + bid.Amount.String() + " " + auction.Currency + ". The order has been placed."},
+	} {
+		n.OrderID = &order.ID
+		if err := tx.Create(&n).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func listItemAtFixedPrice(tx *gorm.DB, auction models.Auction, now time.Time) error {
+	return tx.Model(&models.MarketItems{}).Where("id = ?", auction.ItemID).
+		Updates(map[string]interface{}{"ListingType": models.ListingTypeFixed, "UpdatedAt": now}).Error
+}
+
+// CloseDueAuctions closes every open auction whose end time has passed, each
+// in its own transaction. An auction that received a last-moment bid is
+// skipped and picked up again on a later run. It returns how many auctions
+// were closed.
+func CloseDueAuctions(db *gorm.DB, now time.Time) (int, error) {
+	var auctions []models.Auction
+	if err := db.Where("status = ? AND ends_at <= ?", models.AuctionStatusOpen, now).Find(&auctions).Error; err != nil {
+		return 0, err
+	}
+
+	closed := 0
+	for _, auction := range auctions {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return CloseAuction(tx, auction, now)
+		})
+		if errors.Is(err, ErrTransactionConflict) {
+			continue
+		}
+		if err != nil {
+			log.Printf("auction %s: %v", auction.ID, err)
+			continue
+		}
+		closed++
+	}
+	return closed, nil
+}
+
+// CancelAuction withdraws an auction nobody has bid on yet and returns the
+// item to a fixed-price listing.
+func CancelAuction(tx *gorm.DB, auction models.Auction) error {
+	result := tx.Model(&models.Auction{}).
+		Where("id = ? AND status = ? AND bid_count = 0", auction.ID, models.AuctionStatusOpen).
+		Updates(map[string]interface{}{"Status": models.AuctionStatusCancelled, "UpdatedAt": time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTransactionConflict
+	}
+
+	return tx.Model(&models.MarketItems{}).Where("id = ?", auction.ItemID).
+		Updates(map[string]interface{}{"ListingType": models.ListingTypeFixed, "UpdatedAt": time.Now()}).Error
+}
+
+// StartAuction turns a READY fixed-price item into an auction item and stores
+// auction. The item is switched conditionally, so an item that was ordered or
+// put up for auction concurrently makes this fail with ErrTransactionConflict.
+func StartAuction(tx *gorm.DB, auction *models.Auction) error {
+	result := tx.Model(&models.MarketItems{}).
+		Where("id = ? AND status = ? AND listing_type = ?", auction.ItemID, models.MarketItemStatusReady, models.ListingTypeFixed).
+		Updates(map[string]interface{}{"ListingType": models.ListingTypeAuction, "UpdatedAt": time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTransactionConflict
+	}
+
+	auction.Status = models.AuctionStatusOpen
+	auction.CurrentPrice = 0
+	auction.BidCount = 0
+	return tx.Create(auction).Error
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"recyco/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Cancellation reason codes. Every cancellation carries one; OTHER needs a
+// free-text reason as well.
+const (
+	CancelReasonChangedMind        = "CHANGED_MIND"
+	CancelReasonFoundAnotherSeller = "FOUND_ANOTHER_SELLER"
+	CancelReasonPriceTooHigh       = "PRICE_TOO_HIGH"
+	CancelReasonSellerUnresponsive = "SELLER_UNRESPONSIVE"
+	CancelReasonItemUnavailable    = "ITEM_UNAVAILABLE"
+	CancelReasonItemDamaged        = "ITEM_DAMAGED"
+	CancelReasonBuyerUnresponsive  = "BUYER_UNRESPONSIVE"
+	CancelReasonScheduleConflict   = "SCHEDULE_CONFLICT"
+	CancelReasonReservationExpired = "RESERVATION_EXPIRED"
+	CancelReasonDisputeResolution  = "DISPUTE_RESOLUTION"
+	CancelReasonOther              = "OTHER"
+)
+
+// CancellationReasons lists the reason codes each party may give.
+var CancellationReasons = map[string][]string{
+	PartyBuyer: {
+		CancelReasonChangedMind,
+		CancelReasonFoundAnotherSeller,
+		CancelReasonPriceTooHigh,
+		CancelReasonSellerUnresponsive,
+		CancelReasonScheduleConflict,
+		CancelReasonOther,
+	},
+	PartySeller: {
+		CancelReasonItemUnavailable,
+		CancelReasonItemDamaged,
+		CancelReasonBuyerUnresponsive,
+		CancelReasonScheduleConflict,
+		CancelReasonOther,
+	},
+	PartySystem: {CancelReasonReservationExpired},
+	PartyAdmin:  {CancelReasonDisputeResolution, CancelReasonOther},
+}
+
+const NotificationOrderCancelled = "ORDER_CANCELLED"
+
+var ErrCancellationReason = errors.New("invalid cancellation reason code")
+
+// IsValidCancellationReason reports whether party may cancel with code.
+func IsValidCancellationReason(party, code string) bool {
+	for _, c := range CancellationReasons[party] {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// CancelOrder cancels order within tx as party with a reason code from
+// CancellationReasons and an optional free-text reason, which OTHER requires.
+// Who may cancel at which stage is governed by TransactionTransitions: the
+// buyer only before the order is on delivery. Whatever the buyer paid is
+// refunded. The item of a contract run goes off the market with the order,
+// and an order made from a demand response gives its weight back to the
+// demand listing.
+func CancelOrder(tx *gorm.DB, order *models.Order, party string, actorID uuid.UUID, code, reason string) (models.MarketItemTransactionActivities, error) {
+	if !IsValidCancellationReason(party, code) || (code == CancelReasonOther && reason == "") {
+		return models.MarketItemTransactionActivities{}, ErrCancellationReason
+	}
+	if reason == "" {
+		reason = code
+	}
+
+	activity, err := recordTransactionStatus(tx, order, TransactionStatusCancelled, party, actorID, reason)
+	if err != nil {
+		return activity, err
+	}
+
+	activity.ReasonCode = code
+	if err := tx.Model(&models.MarketItemTransactionActivities{}).Where("id = ?", activity.ID).
+		Update("reason_code", code).Error; err != nil {
+		return activity, err
+	}
+
+	if order.ContractID != nil {
+		if err := withdrawItem(tx, order.ItemID); err != nil {
+			return activity, err
+		}
+	}
+	if err := releaseDemandResponse(tx, *order); err != nil {
+		return activity, err
+	}
+
+	err = RefundCancelledOrder(tx, *order)
+	return activity, err
+}
+
+// releaseDemandResponse undoes the acceptance of the demand response order
+// was made from, if any, within tx. Its weight goes back to the demand
+// listing, which is OPEN again if the response had fulfilled it, and the
+// item made for the buyer is withdrawn rather than put on the market.
+func releaseDemandResponse(tx *gorm.DB, order models.Order) error {
+	var response models.DemandResponse
+	err := tx.Where("order_id = ?", order.ID).First(&response).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Model(&models.DemandListing{}).Where("id = ?", response.DemandID).
+		Updates(map[string]interface{}{
+			"FulfilledWeight": gorm.Expr("GREATEST(fulfilled_weight - ?, 0)", response.Weight),
+			"Status":          gorm.Expr("CASE WHEN status = ? THEN ? ELSE status END", models.DemandStatusFulfilled, models.DemandStatusOpen),
+			"UpdatedAt":       time.Now(),
+		}).Error; err != nil {
+		return err
+	}
+	return withdrawItem(tx, order.ItemID)
+}
+
+// withdrawItem takes an item that was only ever meant for one buyer off the
+// market within tx, the way its seller deleting it would.
+func withdrawItem(tx *gorm.DB, itemID uuid.UUID) error {
+	return tx.Model(&models.MarketItems{}).Where("id = ?", itemID).
+		Updates(map[string]interface{}{
+			"Status":    models.MarketItemStatusWithdrawn,
+			"UpdatedAt": time.Now(),
+			"DeletedAt": time.Now(),
+		}).Error
+}
+
+// PartyCancellationStats counts the orders a user took part in on one side
+// and how many of them they cancelled themselves, by reason code.
+type PartyCancellationStats struct {
+	Orders    int64            `json:"orders"`
+	Cancelled int64            `json:"cancelled"`
+	Rate      float64          `json:"rate"`
+	ByReason  map[string]int64 `json:"by_reason"`
+}
+
+// CancellationStats is a user's cancellation record as buyer and as seller.
+type CancellationStats struct {
+	AsBuyer  PartyCancellationStats `json:"as_buyer"`
+	AsSeller PartyCancellationStats `json:"as_seller"`
+}
+
+// LoadCancellationStats computes the cancellation record of userID. Rate is
+// the share of orders the user cancelled, rounded to four decimals;
+// cancellations by the other party or the system do not count against them.
+func LoadCancellationStats(db *gorm.DB, userID uuid.UUID) (CancellationStats, error) {
+	var stats CancellationStats
+
+	sides := []struct {
+		party  string
+		column string
+		target *PartyCancellationStats
+	}{
+		{PartyBuyer, "buyer_id", &stats.AsBuyer},
+		{PartySeller, "seller_id", &stats.AsSeller},
+	}
+	for _, side := range sides {
+		side.target.ByReason = map[string]int64{}
+
+		if err := db.Model(&models.Order{}).Where(side.column+" = ?", userID).Count(&side.target.Orders).Error; err != nil {
+			return stats, err
+		}
+
+		var rows []struct {
+			ReasonCode string
+			Count      int64
+		}
+		if err := db.Model(&models.MarketItemTransactionActivities{}).
+			Select("reason_code, COUNT(*) AS count").
+			Where("status = ? AND actor_party = ? AND actor_id = ?", TransactionStatusCancelled, side.party, userID).
+			Group("reason_code").
+			Scan(&rows).Error; err != nil {
+			return stats, err
+		}
+
+		for _, row := range rows {
+			code := row.ReasonCode
+			if code == "" {
+				code = CancelReasonOther
+			}
+			side.target.ByReason[code] += row.Count
+			side.target.Cancelled += row.Count
+		}
+		if side.target.Orders > 0 {
+			side.target.Rate = math.Round(float64(side.target.Cancelled)/float64(side.target.Orders)*10000) / 10000
+		}
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"recyco/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// NotificationContractOrder is sent to both parties when a contract run
+// places an order.
+const NotificationContractOrder = "CONTRACT_ORDER"
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// NextContractRun returns the first scheduled run of contract strictly after
+// after, or nil when the schedule has ended. Runs fall at the start of the
+// scheduled day and never before StartsOn. Biweekly runs count their weeks
+// from the first run on or after StartsOn.
+func NextContractRun(contract models.Contract, after time.Time) *time.Time {
+	start := startOfDay(contract.StartsOn)
+
+	var next time.Time
+	switch contract.Frequency {
+	case models.ContractFrequencyWeekly, models.ContractFrequencyBiweekly:
+		days := 7
+		if contract.Frequency == models.ContractFrequencyBiweekly {
+			days = 14
+		}
+
+		next = start.AddDate(0, 0, (contract.Weekday-int(start.Weekday())+7)%7)
+		if gap := int(after.Sub(next).Hours() / 24); gap > days {
+			next = next.AddDate(0, 0, gap/days*days)
+		}
+		for !next.After(after) {
+			next = next.AddDate(0, 0, days)
+		}
+	case models.ContractFrequencyMonthly:
+		next = time.Date(start.Year(), start.Month(), contract.DayOfMonth, 0, 0, 0, 0, start.Location())
+		if next.Before(start) {
+			next = next.AddDate(0, 1, 0)
+		}
+		for !next.After(after) {
+			next = next.AddDate(0, 1, 0)
+		}
+	default:
+		return nil
+	}
+
+	if contract.EndsOn != nil && next.After(*contract.EndsOn) {
+		return nil
+	}
+	return &next
+}
+
+// RunContract places the order for the contract run due at runAt within tx:
+// a market item for the estimated weight, of the scale the rules give that
+// weight, is posted on the seller's behalf and ordered for the buyer at the
+// contract price. The contract's NextRunAt
+// is advanced conditionally first, so two schedulers cannot generate the
+// same run; the loser gets ErrTransactionConflict. Runs missed while the
+// scheduler was down are not replayed, the schedule resumes after now.
+func RunContract(tx *gorm.DB, contract models.Contract, now time.Time) (models.Order, error) {
+	var order models.Order
+
+	next := NextContractRun(contract, now)
+	updates := map[string]interface{}{"NextRunAt": next, "UpdatedAt": now}
+	if next == nil {
+		updates["Status"] = models.ContractStatusEnded
+	}
+
+	result := tx.Model(&models.Contract{}).
+		Where("id = ? AND status = ? AND next_run_at = ?", contract.ID, models.ContractStatusActive, contract.NextRunAt).
+		Updates(updates)
+	if result.Error != nil {
+		return order, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return order, ErrTransactionConflict
+	}
+
+	rules, err := LoadMarketRules(tx)
+	if err != nil {
+		return order, err
+	}
+
+	item := models.MarketItems{
+		Name:             contract.Title,
+		Price:            contract.PricePerKg.MulQuantity(contract.EstimatedWeight),
+		PricePerKg:       contract.PricePerKg,
+		Currency:         contract.Currency,
+		Weight:           contract.EstimatedWeight,
+		WeightUnit:       contract.WeightUnit,
+		WeightIsEstimate: true,
+		ItemScale:        rules.ScaleForWeight(contract.Category, contract.EstimatedWeight),
+		Status:           models.MarketItemStatusReady,
+		Category:         contract.Category,
+		Description:      contract.Description,
+		Lat:              contract.Lat,
+		Lon:              contract.Lon,
+		PostedBy:         contract.SellerID,
+	}
+	if err := tx.Create(&item).Error; err != nil {
+		return order, err
+	}
+
+	pickup := models.MarketItemPickupInformations{
+		RecipientName:             contract.RecipientName,
+		RecipientPhone:            contract.RecipientPhone,
+		Description:               "Contract: " + contract.Title,
+		PickupLocationAddress:     contract.PickupLocationAddress,
+		PickupLocationDescription: contract.PickupLocationDescription,
+		PickupLat:                 contract.PickupLat,
+		PickupLon:                 contract.PickupLon,
+	}
+
+	order, _, err = PlaceOrder(tx, item, contract.BuyerID, &pickup, item.Price, models.FulfillmentDelivery)
+	if err != nil {
+		return order, err
+	}
+
+	order.ContractID = &contract.ID
+	if err := tx.Model(&models.Order{}).Where("id = ?", order.ID).Update("contract_id", contract.ID).Error; err != nil {
+		return order, err
+	}
+
+	err = NotifyOrderParties(tx, order, NotificationContractOrder,
+		"Contract order placed",
+		"The scheduled order for contract \""+contract.Title+"\" has been placed.")
+	return order, err
+}
+
+// RunDueContracts runs every active contract whose next run is due, each in
+// its own transaction, and returns how many orders were placed.
+func RunDueContracts(db *gorm.DB, now time.Time) (int, error) {
+	var contracts []models.Contract
+	if err := db.Where("status = ? AND next_run_at <= ?", models.ContractStatusActive, now).Find(&contracts).Error; err != nil {
+		return 0, err
+	}
+
+	placed := 0
+	for _, contract := range contracts {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			_, err := RunContract(tx, contract, now)
+			return err
+		})
+		if errors.Is(err, ErrTransactionConflict) {
+			continue
+		}
+		if err != nil {
+			log.Printf("contract %s: %v", contract.ID, err)
+			continue
+		}
+		placed++
+	}
+	return placed, nil
+}
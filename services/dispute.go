@@ -0,0 +1,204 @@
+package services
+
+import (
+	"errors"
+	"recyco/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DisputeWindow is how long after an order was finished its parties can still
+// open a dispute about it.
+const DisputeWindow = 14 * 24 * time.Hour
+
+const (
+	NotificationDisputeOpened   = "DISPUTE_OPENED"
+	NotificationDisputeMessage  = "DISPUTE_MESSAGE"
+	NotificationDisputeResolved = "DISPUTE_RESOLVED"
+)
+
+var (
+	ErrDisputeNotAllowed     = errors.New("order cannot be disputed")
+	ErrDisputeAlreadyOpen    = errors.New("order already has an open dispute")
+	ErrDisputeClosed         = errors.New("dispute is already resolved")
+	ErrDisputeRefund         = errors.New("refund must be positive and within the order total")
+	ErrDisputeResolution     = errors.New("invalid dispute resolution")
+	ErrDisputeNoCounterparty = errors.New("the other party of this order is unknown")
+)
+
+// OrderTotal is what the buyer pays for order: the item price and its fees.
+func OrderTotal(order models.Order, pickup models.MarketItemPickupInformations) models.Money {
+	return order.ItemPrice + pickup.ServicePrice + pickup.DeliveryPrice
+}
+
+// OpenDispute files dispute about order within tx and attaches the evidence
+// photos. Orders can be disputed while they are on delivery and for
+// DisputeWindow after they were finished, one open dispute at a time.
+func OpenDispute(tx *gorm.DB, order models.Order, dispute *models.Dispute, photoURLs []string, now time.Time) error {
+	switch order.Status {
+	case TransactionStatusOnDeliver:
+	case TransactionStatusFinished:
+		var finished models.MarketItemTransactionActivities
+		if err := tx.Where("order_id = ? AND status = ?", order.ID, TransactionStatusFinished).
+			Order("created_at desc").First(&finished).Error; err != nil {
+			return err
+		}
+		if now.After(finished.CreatedAt.Add(DisputeWindow)) {
+			return ErrDisputeNotAllowed
+		}
+	default:
+		return ErrDisputeNotAllowed
+	}
+
+	// Disputes about one order are opened one at a time, so two cannot both
+	// find no open dispute and be created side by side.
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", order.ID).First(&models.Order{}).Error; err != nil {
+		return err
+	}
+	var open int64
+	if err := tx.Model(&models.Dispute{}).
+		Where("order_id = ? AND status = ?", order.ID, models.DisputeStatusOpen).
+		Count(&open).Error; err != nil {
+		return err
+	}
+	if open > 0 {
+		return ErrDisputeAlreadyOpen
+	}
+
+	dispute.OrderID = order.ID
+	dispute.Status = models.DisputeStatusOpen
+	if err := tx.Create(dispute).Error; err != nil {
+		return err
+	}
+
+	for _, url := range photoURLs {
+		photo := models.DisputeEvidencePhoto{DisputeID: dispute.ID, UploadedByID: dispute.OpenedByID, Url: url}
+		if err := tx.Create(&photo).Error; err != nil {
+			return err
+		}
+		dispute.Photos = append(dispute.Photos, photo)
+	}
+
+	return NotifyOrderParties(tx, order, NotificationDisputeOpened,
+		"Dispute opened",
+		"A dispute has been opened on your order and is waiting for an admin.")
+}
+
+// AddDisputeMessage appends a message with its photos to an open dispute
+// within tx and lets the order parties know.
+func AddDisputeMessage(tx *gorm.DB, dispute models.Dispute, order models.Order, message *models.DisputeMessage, photoURLs []string) error {
+	if dispute.Status != models.DisputeStatusOpen {
+		return ErrDisputeClosed
+	}
+
+	message.DisputeID = dispute.ID
+	if err := tx.Create(message).Error; err != nil {
+		return err
+	}
+
+	for _, url := range photoURLs {
+		photo := models.DisputeEvidencePhoto{DisputeID: dispute.ID, MessageID: &message.ID, UploadedByID: message.AuthorID, Url: url}
+		if err := tx.Create(&photo).Error; err != nil {
+			return err
+		}
+		message.Photos = append(message.Photos, photo)
+	}
+
+	return NotifyOrderParties(tx, order, NotificationDisputeMessage,
+		"New message on your dispute",
+		"There is a new message on the dispute about your order.")
+}
+
+// ResolveDispute settles an open dispute within tx as decided by adminID.
+// PARTIAL_REFUND adds refund to what the buyer gets back and holds the seller
+// at fault, CANCELLED cancels the order and holds the party the dispute was
+// opened against at fault, and UPHELD leaves the order as it is. The dispute
+// is closed conditionally on still being open, so of two admins deciding at
+// once one gets ErrTransactionConflict.
+func ResolveDispute(tx *gorm.DB, dispute *models.Dispute, order *models.Order, resolution string, refund models.Money, note string, adminID uuid.UUID, now time.Time) error {
+	if dispute.Status != models.DisputeStatusOpen {
+		return ErrDisputeClosed
+	}
+
+	var atFault *uuid.UUID
+	switch resolution {
+	case models.DisputeResolutionPartialRefund:
+		var pickup models.MarketItemPickupInformations
+		if err := tx.Where("id = ?", order.PickupInformationID).First(&pickup).Error; err != nil {
+			return err
+		}
+		if refund <= 0 || order.RefundedAmount+refund > OrderTotal(*order, pickup) {
+			return ErrDisputeRefund
+		}
+		atFault = &order.SellerID
+	case models.DisputeResolutionCancelled:
+		against := order.SellerID
+		if dispute.OpenedByParty == PartySeller {
+			against = order.BuyerID
+		}
+		if against == uuid.Nil {
+			return ErrDisputeNoCounterparty
+		}
+		atFault = &against
+		refund = 0
+	case models.DisputeResolutionUpheld:
+		refund = 0
+	default:
+		return ErrDisputeResolution
+	}
+
+	result := tx.Model(&models.Dispute{}).
+		Where("id = ? AND status = ?", dispute.ID, models.DisputeStatusOpen).
+		Updates(map[string]interface{}{
+			"Status":         models.DisputeStatusResolved,
+			"Resolution":     resolution,
+			"RefundAmount":   refund,
+			"ResolutionNote": note,
+			"AtFaultID":      atFault,
+			"ResolvedByID":   adminID,
+			"ResolvedAt":     now,
+			"UpdatedAt":      now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTransactionConflict
+	}
+
+	switch resolution {
+	case models.DisputeResolutionPartialRefund:
+		if err := tx.Model(&models.Order{}).Where("id = ?", order.ID).
+			UpdateColumn("refunded_amount", gorm.Expr("refunded_amount + ?", refund)).Error; err != nil {
+			return err
+		}
+		order.RefundedAmount += refund
+		if err := RefundOrderPayment(tx, *order, refund, "Dispute refund"); err != nil {
+			return err
+		}
+	case models.DisputeResolutionCancelled:
+		reason := "Cancelled after dispute"
+		if note != "" {
+			reason += ": " + note
+		}
+		if _, err := CancelOrder(tx, order, PartyAdmin, adminID, CancelReasonDisputeResolution, reason); err != nil {
+			return err
+		}
+	}
+
+	dispute.Status = models.DisputeStatusResolved
+	dispute.Resolution = &resolution
+	dispute.RefundAmount = refund
+	dispute.ResolutionNote = note
+	dispute.AtFaultID = atFault
+	dispute.ResolvedByID = &adminID
+	dispute.ResolvedAt = &now
+	dispute.UpdatedAt = now
+
+	return NotifyOrderParties(tx, *order, NotificationDisputeResolved,
+		"Dispute resolved",
+		"The dispute about your order was resolved: "+resolution+".")
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"recyco/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// NotificationDriverAssigned is sent to a driver who gets a new job.
+const NotificationDriverAssigned = "DRIVER_ASSIGNED"
+
+// AssignDriver hands a delivery order that has not been picked up yet to
+// driverID within tx, replacing any earlier assignment, and notifies the
+// driver. The update is conditional on the order still waiting for pickup,
+// otherwise it fails with ErrTransactionConflict.
+func AssignDriver(tx *gorm.DB, order *models.Order, driverID uuid.UUID) error {
+	now := time.Now()
+	result := tx.Model(&models.Order{}).
+		Where("id = ? AND status = ? AND fulfillment = ?", order.ID, TransactionStatusOnProcess, models.FulfillmentDelivery).
+		Updates(map[string]interface{}{"DriverID": driverID, "DriverAssignedAt": now, "UpdatedAt": now})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTransactionConflict
+	}
+
+	order.DriverID = &driverID
+	order.DriverAssignedAt = &now
+
+	notification := models.Notification{
+		UserID:  driverID,
+		Type:    NotificationDriverAssigned,
+		Title:   "New delivery job",
+		Message: "You have been assigned an order to pick up.",
+		OrderID: &order.ID,
+	}
+	return tx.Create(&notification).Error
+}
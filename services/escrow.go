@@ -0,0 +1,556 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"recyco/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const NotificationPaymentReceived = "PAYMENT_RECEIVED"
+
+var (
+	ErrPaymentNotAllowed = errors.New("order cannot be paid in its current status")
+	ErrAlreadyPaid       = errors.New("order is already paid")
+	ErrPaymentProvider   = errors.New("payment belongs to another provider")
+	ErrPaymentAmount     = errors.New("webhook amount does not match the payment")
+	ErrUnbalancedJournal = errors.New("journal debits and credits do not balance")
+	ErrPayoutPaid        = errors.New("payout is already paid")
+)
+
+// LedgerLine is one account movement of a journal posted with postJournal.
+type LedgerLine struct {
+	Account string
+	OwnerID *uuid.UUID
+	Debit   models.Money
+	Credit  models.Money
+}
+
+// postJournal appends lines as one balanced journal within tx. Lines that
+// move nothing are left out.
+func postJournal(tx *gorm.DB, orderID, paymentID, payoutID *uuid.UUID, currency, description string, lines []LedgerLine) error {
+	var debits, credits models.Money
+	for _, line := range lines {
+		debits += line.Debit
+		credits += line.Credit
+	}
+	if debits != credits {
+		return ErrUnbalancedJournal
+	}
+
+	journalID := uuid.New()
+	for _, line := range lines {
+		if line.Debit == 0 && line.Credit == 0 {
+			continue
+		}
+		entry := models.LedgerEntry{
+			JournalID:   journalID,
+			OrderID:     orderID,
+			PaymentID:   paymentID,
+			PayoutID:    payoutID,
+			Account:     line.Account,
+			OwnerID:     line.OwnerID,
+			Debit:       line.Debit,
+			Credit:      line.Credit,
+			Currency:    currency,
+			Description: description,
+		}
+		if err := tx.Create(&entry).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// orderAccountBalance is what account holds for orderID: its credits less its
+// debits. ESCROW, SELLER_PAYABLE and the fee accounts all grow on the credit
+// side; what a buyer owes in BUYER_RECEIVABLE shows up negative.
+func orderAccountBalance(tx *gorm.DB, orderID uuid.UUID, account string) (models.Money, error) {
+	var sums struct {
+		Debit  models.Money
+		Credit models.Money
+	}
+	err := tx.Model(&models.LedgerEntry{}).
+		Select("SUM(debit) AS debit, SUM(credit) AS credit").
+		Where("order_id = ? AND account = ?", orderID, account).
+		Scan(&sums).Error
+	return sums.Credit - sums.Debit, err
+}
+
+// LedgerBalance is the balance of one ledger account, per owner for
+// BUYER_RECEIVABLE, SELLER_PAYABLE and REFUNDS_PAYABLE, in the account's
+// normal direction: PROVIDER_CASH and BUYER_RECEIVABLE grow with debits,
+// every other account with credits.
+type LedgerBalance struct {
+	Account string       `json:"account"`
+	OwnerID *uuid.UUID   `json:"owner_id"`
+	Debit   models.Money `json:"debit"`
+	Credit  models.Money `json:"credit"`
+	Balance models.Money `json:"balance"`
+}
+
+// LedgerBalances sums the whole ledger by account and owner. Across all
+// accounts the debits always equal the credits.
+func LedgerBalances(db *gorm.DB) ([]LedgerBalance, error) {
+	var balances []LedgerBalance
+	err := db.Model(&models.LedgerEntry{}).
+		Select("account, owner_id, SUM(debit) AS debit, SUM(credit) AS credit").
+		Group("account, owner_id").
+		Order("account, owner_id").
+		Scan(&balances).Error
+	for i := range balances {
+		balances[i].Balance = balances[i].Credit - balances[i].Debit
+		if balances[i].Account == models.LedgerAccountProviderCash || balances[i].Account == models.LedgerAccountBuyerReceivable {
+			balances[i].Balance = -balances[i].Balance
+		}
+	}
+	return balances, err
+}
+
+// findOrderPayment returns the payment of order that collected money, if any.
+// The payment row stays locked for the rest of tx, so concurrent refunds and
+// releases see each other's refunded amount.
+func findOrderPayment(tx *gorm.DB, orderID uuid.UUID) (models.Payment, bool, error) {
+	var payment models.Payment
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("order_id = ? AND status IN ?", orderID, []string{models.PaymentStatusPaid, models.PaymentStatusRefunded}).
+		First(&payment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return payment, false, nil
+	}
+	return payment, err == nil, err
+}
+
+// StartPayment charges the buyer of order through provider for what is still
+// owed on it. Orders are paid while they are in progress; a payment that is
+// still pending is handed back instead of charging twice. The payment is
+// stored in its own transaction with the order row locked, so concurrent
+// calls agree on one payment, and the provider is only asked for the charge
+// once that is committed. If the provider cannot be reached, the next call
+// asks again under the same reference.
+func StartPayment(db *gorm.DB, provider PaymentProvider, order models.Order) (models.Payment, error) {
+	var payment models.Payment
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", order.ID).First(&order).Error; err != nil {
+			return err
+		}
+		if order.Status != TransactionStatusOnProcess && order.Status != TransactionStatusOnDeliver {
+			return ErrPaymentNotAllowed
+		}
+
+		var existing []models.Payment
+		if err := tx.Where("order_id = ? AND status IN ?", order.ID,
+			[]string{models.PaymentStatusPending, models.PaymentStatusPaid, models.PaymentStatusRefunded}).
+			Order("created_at desc").Find(&existing).Error; err != nil {
+			return err
+		}
+		for _, previous := range existing {
+			if previous.Status != models.PaymentStatusPending {
+				return ErrAlreadyPaid
+			}
+		}
+		if len(existing) > 0 && existing[0].Provider == provider.Name() {
+			payment = existing[0]
+			return nil
+		}
+
+		var pickup models.MarketItemPickupInformations
+		if err := tx.Where("id = ?", order.PickupInformationID).First(&pickup).Error; err != nil {
+			return err
+		}
+
+		payment = models.Payment{
+			OrderID:  order.ID,
+			BuyerID:  order.BuyerID,
+			Provider: provider.Name(),
+			Amount:   OrderTotal(order, pickup) - order.RefundedAmount,
+			Currency: order.Currency,
+		}
+		return tx.Create(&payment).Error
+	})
+	if err != nil || payment.ChargeID != nil {
+		return payment, err
+	}
+
+	charge, err := provider.CreateCharge(ChargeRequest{
+		Reference:   payment.ID.String(),
+		Amount:      payment.Amount,
+		Currency:    payment.Currency,
+		Description: "Order " + order.ID.String(),
+	})
+	if err != nil {
+		return payment, err
+	}
+	if err := db.Model(&models.Payment{}).Where("id = ? AND charge_id IS NULL", payment.ID).
+		Updates(map[string]interface{}{"ChargeID": charge.ID, "CheckoutURL": charge.CheckoutURL}).Error; err != nil {
+		return payment, err
+	}
+	payment.ChargeID = &charge.ID
+	payment.CheckoutURL = charge.CheckoutURL
+	return payment, nil
+}
+
+// HandlePaymentWebhook applies a verified provider event within tx. A
+// successful charge moves the money into escrow; if the order was finished
+// or cancelled while the buyer was paying, it is released or its refund
+// recorded right away. Events are applied once: a redelivered event finds the payment no
+// longer pending and changes nothing.
+func HandlePaymentWebhook(tx *gorm.DB, provider PaymentProvider, event WebhookEvent, now time.Time) (models.Payment, error) {
+	var payment models.Payment
+	if err := tx.Where("charge_id = ?", event.ChargeID).First(&payment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return payment, ErrUnknownCharge
+		}
+		return payment, err
+	}
+	if payment.Provider != provider.Name() {
+		return payment, ErrPaymentProvider
+	}
+
+	switch event.Type {
+	case PaymentEventChargeFailed:
+		result := tx.Model(&models.Payment{}).Where("id = ? AND status = ?", payment.ID, models.PaymentStatusPending).
+			Update("status", models.PaymentStatusFailed)
+		if result.Error == nil && result.RowsAffected > 0 {
+			payment.Status = models.PaymentStatusFailed
+		}
+		return payment, result.Error
+	case PaymentEventChargeSucceeded:
+	default:
+		return payment, nil
+	}
+
+	if event.Amount != payment.Amount {
+		return payment, ErrPaymentAmount
+	}
+	result := tx.Model(&models.Payment{}).Where("id = ? AND status = ?", payment.ID, models.PaymentStatusPending).
+		Updates(map[string]interface{}{"Status": models.PaymentStatusPaid, "PaidAt": now})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return payment, result.Error
+	}
+	payment.Status = models.PaymentStatusPaid
+	payment.PaidAt = &now
+
+	if err := postJournal(tx, &payment.OrderID, &payment.ID, nil, payment.Currency, "Payment received", []LedgerLine{
+		{Account: models.LedgerAccountProviderCash, Debit: payment.Amount},
+		{Account: models.LedgerAccountEscrow, Credit: payment.Amount},
+	}); err != nil {
+		return payment, err
+	}
+
+	var order models.Order
+	if err := tx.Where("id = ?", payment.OrderID).First(&order).Error; err != nil {
+		return payment, err
+	}
+	if err := NotifyOrderParties(tx, order, NotificationPaymentReceived, "Payment received",
+		"The buyer paid "+payment.Currency+" "+payment.Amount.String()+"; it is held until the order is finished"); err != nil {
+		return payment, err
+	}
+	switch order.Status {
+	case TransactionStatusFinished:
+		err := ReleaseEscrow(tx, order, now)
+		return payment, err
+	case TransactionStatusCancelled:
+		err := RefundCancelledOrder(tx, order)
+		return payment, err
+	}
+	return payment, nil
+}
+
+// ReleaseEscrow pays out what the buyer paid for a finished order within tx.
+// The order's final total, after the handover weighing and any refunds,
+// decides the split: the service fee goes to PLATFORM_FEES, the delivery fee
+// to DELIVERY_FEES and the rest to the seller, who gets a pending Payout for
+// it. Anything held beyond the final total is refunded to the buyer. When
+// the weighing raised the total above what was paid, the whole total is
+// still split and the difference is booked as owed by the buyer in
+// BUYER_RECEIVABLE. Orders that were not paid through a provider have
+// nothing to release.
+func ReleaseEscrow(tx *gorm.DB, order models.Order, now time.Time) error {
+	payment, paid, err := findOrderPayment(tx, order.ID)
+	if err != nil || !paid {
+		return err
+	}
+	result := tx.Model(&models.Payment{}).Where("id = ? AND released_at IS NULL", payment.ID).Update("released_at", now)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+
+	var pickup models.MarketItemPickupInformations
+	if err := tx.Where("id = ?", order.PickupInformationID).First(&pickup).Error; err != nil {
+		return err
+	}
+
+	held, err := orderAccountBalance(tx, order.ID, models.LedgerAccountEscrow)
+	if err != nil {
+		return err
+	}
+	final := max(OrderTotal(order, pickup)-order.RefundedAmount, 0)
+	if held > final {
+		if err := refundPayment(tx, order, payment, held-final, "Refund of overpayment after weighing",
+			[]string{models.LedgerAccountEscrow}); err != nil {
+			return err
+		}
+		held = final
+	}
+
+	serviceFee := min(pickup.ServicePrice, final)
+	deliveryFee := min(pickup.DeliveryPrice, final-serviceFee)
+	sellerShare := final - serviceFee - deliveryFee
+
+	if err := postJournal(tx, &order.ID, &payment.ID, nil, payment.Currency, "Escrow released", []LedgerLine{
+		{Account: models.LedgerAccountEscrow, Debit: held},
+		{Account: models.LedgerAccountBuyerReceivable, OwnerID: &order.BuyerID, Debit: final - held},
+		{Account: models.LedgerAccountPlatformFees, Credit: serviceFee},
+		{Account: models.LedgerAccountDeliveryFees, Credit: deliveryFee},
+		{Account: models.LedgerAccountSellerPayable, OwnerID: &order.SellerID, Credit: sellerShare},
+	}); err != nil {
+		return err
+	}
+
+	if sellerShare <= 0 {
+		return nil
+	}
+	payout := models.Payout{OrderID: order.ID, SellerID: order.SellerID, Amount: sellerShare, Currency: payment.Currency}
+	return tx.Create(&payout).Error
+}
+
+// RefundOrderPayment records within tx that amount of what the buyer paid for
+// order goes back to them. It comes out of escrow while the order is unfinished and
+// out of the seller's share afterwards, lowering their payout if it has not
+// been paid yet. Orders that were not paid through a provider have nothing
+// to refund.
+func RefundOrderPayment(tx *gorm.DB, order models.Order, amount models.Money, description string) error {
+	payment, paid, err := findOrderPayment(tx, order.ID)
+	if err != nil || !paid {
+		return err
+	}
+	return refundPayment(tx, order, payment, amount, description,
+		[]string{models.LedgerAccountEscrow, models.LedgerAccountSellerPayable})
+}
+
+// RefundCancelledOrder records within tx that everything still kept of the
+// buyer's payment for a cancelled order goes back to them, taking back the
+// fees and the seller's share if the escrow was already released. Whatever
+// the buyer still owed for the order is written off the same way.
+func RefundCancelledOrder(tx *gorm.DB, order models.Order) error {
+	payment, paid, err := findOrderPayment(tx, order.ID)
+	if err != nil || !paid {
+		return err
+	}
+
+	sources := []string{models.LedgerAccountEscrow, models.LedgerAccountPlatformFees, models.LedgerAccountDeliveryFees, models.LedgerAccountSellerPayable}
+	if err := refundPayment(tx, order, payment, payment.Amount-payment.RefundedAmount, "Refund of cancelled order", sources); err != nil {
+		return err
+	}
+
+	balance, err := orderAccountBalance(tx, order.ID, models.LedgerAccountBuyerReceivable)
+	if err != nil || balance >= 0 {
+		return err
+	}
+	owed := -balance
+	lines, fromSeller, err := debitSources(tx, order, owed, sources)
+	if err != nil {
+		return err
+	}
+	lines = append(lines, LedgerLine{Account: models.LedgerAccountBuyerReceivable, OwnerID: &order.BuyerID, Credit: owed})
+	if err := postJournal(tx, &order.ID, &payment.ID, nil, payment.Currency, "Write-off of cancelled order", lines); err != nil {
+		return err
+	}
+	return reducePayout(tx, order.ID, fromSeller)
+}
+
+// refundPayment records a PENDING refund of amount of payment within tx and
+// books it against sources in turn, each up to what it holds for the order,
+// into what is owed to the buyer. The last source covers whatever the others
+// could not, even if that takes it below zero, as when a seller was already
+// paid out. No money moves until SendRefunds passes the refund on to the
+// provider after tx is committed.
+func refundPayment(tx *gorm.DB, order models.Order, payment models.Payment, amount models.Money, description string, sources []string) error {
+	amount = min(amount, payment.Amount-payment.RefundedAmount)
+	if amount <= 0 {
+		return nil
+	}
+
+	lines, fromSeller, err := debitSources(tx, order, amount, sources)
+	if err != nil {
+		return err
+	}
+	lines = append(lines, LedgerLine{Account: models.LedgerAccountRefundsPayable, OwnerID: &payment.BuyerID, Credit: amount})
+
+	refund := models.PaymentRefund{
+		PaymentID:   payment.ID,
+		OrderID:     order.ID,
+		BuyerID:     payment.BuyerID,
+		Amount:      amount,
+		Currency:    payment.Currency,
+		Description: description,
+	}
+	if err := tx.Create(&refund).Error; err != nil {
+		return err
+	}
+	if err := postJournal(tx, &order.ID, &payment.ID, nil, payment.Currency, description, lines); err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{"refunded_amount": gorm.Expr("refunded_amount + ?", amount)}
+	if payment.RefundedAmount+amount == payment.Amount {
+		updates["status"] = models.PaymentStatusRefunded
+	}
+	if err := tx.Model(&models.Payment{}).Where("id = ?", payment.ID).UpdateColumns(updates).Error; err != nil {
+		return err
+	}
+
+	return reducePayout(tx, order.ID, fromSeller)
+}
+
+// debitSources returns the ledger lines that take amount out of sources in
+// turn, each up to what it holds for order. The last source covers whatever
+// the others could not, even if that takes it below zero. It also returns
+// how much was taken from the seller's share.
+func debitSources(tx *gorm.DB, order models.Order, amount models.Money, sources []string) ([]LedgerLine, models.Money, error) {
+	var lines []LedgerLine
+	remaining := amount
+	var fromSeller models.Money
+	for i, account := range sources {
+		take := remaining
+		if i < len(sources)-1 {
+			balance, err := orderAccountBalance(tx, order.ID, account)
+			if err != nil {
+				return nil, 0, err
+			}
+			take = min(remaining, max(balance, 0))
+		}
+		if take == 0 {
+			continue
+		}
+
+		line := LedgerLine{Account: account, Debit: take}
+		if account == models.LedgerAccountSellerPayable {
+			line.OwnerID = &order.SellerID
+			fromSeller = take
+		}
+		lines = append(lines, line)
+		remaining -= take
+	}
+	return lines, fromSeller, nil
+}
+
+// reducePayout lowers the pending payout of orderID by what was taken back
+// from the seller's share.
+func reducePayout(tx *gorm.DB, orderID uuid.UUID, fromSeller models.Money) error {
+	if fromSeller <= 0 {
+		return nil
+	}
+	return tx.Model(&models.Payout{}).
+		Where("order_id = ? AND status = ?", orderID, models.PayoutStatusPending).
+		UpdateColumn("amount", gorm.Expr("GREATEST(amount - ?, 0)", fromSeller)).Error
+}
+
+// SendRefunds passes the pending refunds of orderID on to provider and
+// returns how many were sent. It is called once the transaction that recorded
+// them is committed; a refund that cannot be sent now is logged and left to
+// SendPendingRefunds.
+func SendRefunds(db *gorm.DB, provider PaymentProvider, orderID uuid.UUID) (int, error) {
+	var refunds []models.PaymentRefund
+	if err := db.Where("order_id = ? AND status = ?", orderID, models.RefundStatusPending).
+		Order("created_at asc").Find(&refunds).Error; err != nil {
+		return 0, err
+	}
+	return sendRefunds(db, provider, refunds)
+}
+
+// SendPendingRefunds passes every pending refund on to provider, such as
+// those recorded by background jobs or left over when the provider could not
+// be reached, and returns how many were sent.
+func SendPendingRefunds(db *gorm.DB, provider PaymentProvider) (int, error) {
+	var refunds []models.PaymentRefund
+	if err := db.Where("status = ?", models.RefundStatusPending).
+		Order("created_at asc").Find(&refunds).Error; err != nil {
+		return 0, err
+	}
+	return sendRefunds(db, provider, refunds)
+}
+
+func sendRefunds(db *gorm.DB, provider PaymentProvider, refunds []models.PaymentRefund) (int, error) {
+	if len(refunds) > 0 && provider == nil {
+		return 0, ErrPaymentsDisabled
+	}
+
+	sent := 0
+	for _, refund := range refunds {
+		if err := sendRefund(db, provider, refund); err != nil {
+			log.Printf("refund %s: %v", refund.ID, err)
+			continue
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// sendRefund asks provider for refund under the refund's ID as idempotency
+// key, so sending it again after a crash returns the first refund, and then
+// settles what is owed to the buyer. A refund the provider rejects is marked
+// FAILED and stays owed.
+func sendRefund(db *gorm.DB, provider PaymentProvider, refund models.PaymentRefund) error {
+	var payment models.Payment
+	if err := db.Where("id = ?", refund.PaymentID).First(&payment).Error; err != nil {
+		return err
+	}
+	if payment.Provider != provider.Name() || payment.ChargeID == nil {
+		return ErrPaymentProvider
+	}
+
+	confirmation, err := provider.Refund(*payment.ChargeID, refund.Amount, refund.ID.String())
+	if errors.Is(err, ErrRefundRejected) {
+		if failErr := db.Model(&models.PaymentRefund{}).Where("id = ? AND status = ?", refund.ID, models.RefundStatusPending).
+			Update("status", models.RefundStatusFailed).Error; failErr != nil {
+			return failErr
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.PaymentRefund{}).Where("id = ? AND status = ?", refund.ID, models.RefundStatusPending).
+			Updates(map[string]interface{}{"Status": models.RefundStatusSent, "ProviderRefundID": confirmation.ID, "SentAt": now})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return postJournal(tx, &refund.OrderID, &refund.PaymentID, nil, refund.Currency, refund.Description, []LedgerLine{
+			{Account: models.LedgerAccountRefundsPayable, OwnerID: &refund.BuyerID, Debit: refund.Amount},
+			{Account: models.LedgerAccountProviderCash, Credit: refund.Amount},
+		})
+	})
+}
+
+// MarkPayoutPaid records within tx that an admin transferred payout to its
+// seller under reference, settling the seller's payable. The payout is
+// updated conditionally on still being pending, so it is never paid twice.
+func MarkPayoutPaid(tx *gorm.DB, payout *models.Payout, reference string, now time.Time) error {
+	if payout.Status != models.PayoutStatusPending {
+		return ErrPayoutPaid
+	}
+	result := tx.Model(&models.Payout{}).Where("id = ? AND status = ?", payout.ID, models.PayoutStatusPending).
+		Updates(map[string]interface{}{"Status": models.PayoutStatusPaid, "Reference": reference, "PaidAt": now})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTransactionConflict
+	}
+	if err := tx.Where("id = ?", payout.ID).First(payout).Error; err != nil {
+		return err
+	}
+
+	return postJournal(tx, &payout.OrderID, nil, &payout.ID, payout.Currency, "Payout "+reference, []LedgerLine{
+		{Account: models.LedgerAccountSellerPayable, OwnerID: &payout.SellerID, Debit: payout.Amount},
+		{Account: models.LedgerAccountProviderCash, Credit: payout.Amount},
+	})
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/big"
+	"recyco/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// HandoverPINMaxAttempts is how many wrong PINs an order accepts before the
+// buyer has to issue a new one.
+const HandoverPINMaxAttempts = 5
+
+var (
+	ErrHandoverRequired  = errors.New("orders are completed through CompleteOrder")
+	ErrHandoverPINWrong  = errors.New("handover PIN does not match")
+	ErrHandoverPINLocked = errors.New("too many wrong handover PINs")
+)
+
+// Handover is the evidence given when an order is handed to the buyer.
+// Weight is the measured weight in kg, if it was weighed.
+type Handover struct {
+	PIN       string
+	Weight    *float64
+	PhotoURLs []string
+}
+
+// GenerateHandoverPIN returns a random six-digit PIN.
+func GenerateHandoverPIN() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// VerifyHandoverPIN checks pin against order. Every attempt is claimed on db
+// outside of any transaction before the PIN is compared, so it sticks even
+// though the caller rolls back and concurrent guesses cannot slip past the
+// limit: after HandoverPINMaxAttempts wrong PINs the order is locked until the
+// buyer issues a new PIN. A correct PIN clears the count again.
+func VerifyHandoverPIN(db *gorm.DB, order models.Order, pin string) error {
+	result := db.Model(&models.Order{}).
+		Where("id = ? AND handover_pin_attempts < ?", order.ID, HandoverPINMaxAttempts).
+		UpdateColumn("handover_pin_attempts", gorm.Expr("handover_pin_attempts + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrHandoverPINLocked
+	}
+
+	if order.HandoverPIN == "" || subtle.ConstantTimeCompare([]byte(order.HandoverPIN), []byte(pin)) != 1 {
+		return ErrHandoverPINWrong
+	}
+
+	return db.Model(&models.Order{}).Where("id = ?", order.ID).
+		UpdateColumn("handover_pin_attempts", 0).Error
+}
+
+// ResetHandoverPIN issues a new PIN for order and clears its wrong attempts.
+func ResetHandoverPIN(db *gorm.DB, order *models.Order) error {
+	pin, err := GenerateHandoverPIN()
+	if err != nil {
+		return err
+	}
+
+	if err := db.Model(&models.Order{}).Where("id = ?", order.ID).
+		Updates(map[string]interface{}{"HandoverPIN": pin, "HandoverPINAttempts": 0, "UpdatedAt": time.Now()}).Error; err != nil {
+		return err
+	}
+
+	order.HandoverPIN = pin
+	order.HandoverPINAttempts = 0
+	return nil
+}
+
+// CompleteOrder moves order to FINISHED within tx and attaches the handover
+// evidence to the FINISHED activity. When the load was weighed, the order is
+// settled on the measured weight with SettleMeasuredWeight; the escrowed
+// payment is then released and the invoice issued on the final figures. The
+// PIN must have been checked with VerifyHandoverPIN beforehand.
+func CompleteOrder(tx *gorm.DB, order *models.Order, party string, actorID uuid.UUID, handover Handover) (models.MarketItemTransactionActivities, error) {
+	activity, err := recordTransactionStatus(tx, order, TransactionStatusFinished, party, actorID, "")
+	if err != nil {
+		return activity, err
+	}
+
+	activity.PinVerified = true
+	activity.HandoverWeight = handover.Weight
+	if err := tx.Model(&models.MarketItemTransactionActivities{}).Where("id = ?", activity.ID).
+		Updates(map[string]interface{}{"PinVerified": true, "HandoverWeight": handover.Weight}).Error; err != nil {
+		return activity, err
+	}
+
+	for _, url := range handover.PhotoURLs {
+		photo := models.DeliveryProofPhoto{ActivityID: activity.ID, Url: url}
+		if err := tx.Create(&photo).Error; err != nil {
+			return activity, err
+		}
+		activity.ProofPhotos = append(activity.ProofPhotos, photo)
+	}
+
+	if handover.Weight != nil {
+		if err := SettleMeasuredWeight(tx, order, *handover.Weight); err != nil {
+			return activity, err
+		}
+	}
+
+	if err := ReleaseEscrow(tx, *order, time.Now()); err != nil {
+		return activity, err
+	}
+
+	_, err = IssueInvoice(tx, order.ID, time.Now())
+	return activity, err
+}
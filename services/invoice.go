@@ -0,0 +1,121 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"recyco/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrInvoiceNotFinished = errors.New("only finished orders are invoiced")
+
+// InvoiceNumber formats the number of the sequence-th invoice of year.
+func InvoiceNumber(year, sequence int) string {
+	return fmt.Sprintf("INV-%d-%06d", year, sequence)
+}
+
+// nextInvoiceSequence hands out the next invoice number of year within tx.
+// The increment locks the year's counter row until tx ends, so concurrent
+// issuers get consecutive numbers without gaps or duplicates.
+func nextInvoiceSequence(tx *gorm.DB, year int) (int, error) {
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.InvoiceSequence{Year: year}).Error; err != nil {
+		return 0, err
+	}
+
+	if err := tx.Model(&models.InvoiceSequence{}).Where("year = ?", year).
+		UpdateColumn("last_number", gorm.Expr("last_number + 1")).Error; err != nil {
+		return 0, err
+	}
+
+	var sequence models.InvoiceSequence
+	if err := tx.Where("year = ?", year).First(&sequence).Error; err != nil {
+		return 0, err
+	}
+	return sequence.LastNumber, nil
+}
+
+// IssueInvoice returns the invoice of a finished order, issuing it within tx
+// if it has none yet. The order row is locked first, so an order is never
+// invoiced twice. The invoice copies the parties, the item, the weight
+// (measured at handover when it was weighed) and the prices as they are now.
+func IssueInvoice(tx *gorm.DB, orderID interface{}, now time.Time) (models.Invoice, error) {
+	var invoice models.Invoice
+
+	var order models.Order
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", orderID).First(&order).Error; err != nil {
+		return invoice, err
+	}
+
+	err := tx.Where("order_id = ?", order.ID).First(&invoice).Error
+	if err == nil {
+		return invoice, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return invoice, err
+	}
+
+	if order.Status != TransactionStatusFinished {
+		return invoice, ErrInvoiceNotFinished
+	}
+
+	var seller, buyer models.User
+	var item models.MarketItems
+	var pickup models.MarketItemPickupInformations
+	if err := tx.Unscoped().Where("id = ?", order.SellerID).First(&seller).Error; err != nil {
+		return invoice, err
+	}
+	if err := tx.Unscoped().Where("id = ?", order.BuyerID).First(&buyer).Error; err != nil {
+		return invoice, err
+	}
+	if err := tx.Unscoped().Where("id = ?", order.ItemID).First(&item).Error; err != nil {
+		return invoice, err
+	}
+	if err := tx.Unscoped().Where("id = ?", order.PickupInformationID).First(&pickup).Error; err != nil {
+		return invoice, err
+	}
+
+	year := now.Year()
+	sequence, err := nextInvoiceSequence(tx, year)
+	if err != nil {
+		return invoice, err
+	}
+
+	weight := order.DeclaredWeight
+	if weight == 0 {
+		weight = item.Weight
+	}
+	if order.MeasuredWeight != nil {
+		weight = *order.MeasuredWeight
+	}
+
+	invoice = models.Invoice{
+		OrderID:        order.ID,
+		Number:         InvoiceNumber(year, sequence),
+		Year:           year,
+		Sequence:       sequence,
+		IssuedAt:       now,
+		SellerID:       seller.ID,
+		SellerName:     seller.Name,
+		SellerPhone:    seller.PhoneNumber,
+		BuyerID:        buyer.ID,
+		BuyerName:      buyer.Name,
+		BuyerPhone:     buyer.PhoneNumber,
+		ItemName:       item.Name,
+		Category:       item.Category,
+		Weight:         weight,
+		WeightUnit:     item.WeightUnit,
+		WeightMeasured: order.MeasuredWeight != nil,
+		Fulfillment:    order.Fulfillment,
+		ItemPrice:      order.ItemPrice,
+		ServicePrice:   pickup.ServicePrice,
+		DeliveryPrice:  pickup.DeliveryPrice,
+		Total:          OrderTotal(order, pickup),
+		Currency:       order.Currency,
+	}
+	err = tx.Create(&invoice).Error
+	return invoice, err
+}
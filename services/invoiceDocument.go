@@ -0,0 +1,202 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"recyco/models"
+	"strconv"
+	"strings"
+)
+
+// invoiceLine is one labelled figure of a rendered invoice.
+type invoiceLine struct {
+	Label string
+	Value string
+}
+
+// invoiceView is what both renderings of an invoice show.
+type invoiceView struct {
+	Number   string
+	IssuedAt string
+	Seller   []string
+	Buyer    []string
+	Item     []invoiceLine
+	Charges  []invoiceLine
+	Total    string
+}
+
+func formatInvoiceAmount(amount models.Money, currency string) string {
+	return currency + " " + amount.String()
+}
+
+func newInvoiceView(invoice models.Invoice) invoiceView {
+	weight := strconv.FormatFloat(models.WeightInUnit(invoice.Weight, invoice.WeightUnit), 'f', -1, 64) + " " + invoice.WeightUnit
+	if invoice.WeightMeasured {
+		weight += " (measured at handover)"
+	}
+
+	item := []invoiceLine{{"Item", invoice.ItemName}}
+	if invoice.Category != "" {
+		item = append(item, invoiceLine{"Category", invoice.Category})
+	}
+	item = append(item,
+		invoiceLine{"Weight", weight},
+		invoiceLine{"Fulfillment", invoice.Fulfillment},
+	)
+
+	return invoiceView{
+		Number:   invoice.Number,
+		IssuedAt: invoice.IssuedAt.Format("2 January 2006"),
+		Seller:   []string{invoice.SellerName, invoice.SellerPhone},
+		Buyer:    []string{invoice.BuyerName, invoice.BuyerPhone},
+		Item:     item,
+		Charges: []invoiceLine{
+			{"Item price", formatInvoiceAmount(invoice.ItemPrice, invoice.Currency)},
+			{"Service fee", formatInvoiceAmount(invoice.ServicePrice, invoice.Currency)},
+			{"Delivery fee", formatInvoiceAmount(invoice.DeliveryPrice, invoice.Currency)},
+		},
+		Total: formatInvoiceAmount(invoice.Total, invoice.Currency),
+	}
+}
+
+var invoiceTemplate = template.Must(template.New("invoice").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Invoice {{.Number}}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; margin: 40px; color: #222; }
+h1 { margin-bottom: 0; }
+table { border-collapse: collapse; width: 100%; margin-top: 24px; }
+td { padding: 6px 0; border-bottom: 1px solid #ddd; }
+td.amount { text-align: right; }
+.parties { display: flex; gap: 80px; margin-top: 24px; }
+.total td { font-weight: bold; border-bottom: none; }
+</style>
+</head>
+<body>
+<h1>Invoice</h1>
+<p>No. {{.Number}}<br>Issued {{.IssuedAt}}</p>
+<div class="parties">
+<div><strong>Seller</strong>{{range .Seller}}<br>{{.}}{{end}}</div>
+<div><strong>Buyer</strong>{{range .Buyer}}<br>{{.}}{{end}}</div>
+</div>
+<table>
+{{range .Item}}<tr><td>{{.Label}}</td><td class="amount">{{.Value}}</td></tr>
+{{end}}</table>
+<table>
+{{range .Charges}}<tr><td>{{.Label}}</td><td class="amount">{{.Value}}</td></tr>
+{{end}}<tr class="total"><td>Total</td><td class="amount">{{.Total}}</td></tr>
+</table>
+</body>
+</html>
+`))
+
+// RenderInvoiceHTML writes invoice as a standalone HTML page.
+func RenderInvoiceHTML(w io.Writer, invoice models.Invoice) error {
+	return invoiceTemplate.Execute(w, newInvoiceView(invoice))
+}
+
+// pdfText escapes s for a PDF string literal. The built-in Helvetica font
+// only covers Latin-1, so other characters are replaced.
+func pdfText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 32 || r > 126:
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// RenderInvoicePDF writes invoice as a single A4 page PDF using the standard
+// Helvetica fonts, so no font files need to be embedded.
+func RenderInvoicePDF(w io.Writer, invoice models.Invoice) error {
+	view := newInvoiceView(invoice)
+
+	var content bytes.Buffer
+	text := func(font string, size, x, y float64, s string) {
+		fmt.Fprintf(&content, "BT /%s %g Tf %g %g Td (%s) Tj ET\n", font, size, x, y, pdfText(s))
+	}
+	rightText := func(font string, size, right, y float64, s string) {
+		// Helvetica averages about half an em per character, which is close
+		// enough to right-align short figures.
+		text(font, size, right-float64(len(s))*size*0.5, y, s)
+	}
+	rule := func(y float64) {
+		fmt.Fprintf(&content, "0.8 G 50 %g m 545 %g l S 0 G\n", y, y)
+	}
+
+	y := 780.0
+	text("F2", 22, 50, y, "Invoice")
+	y -= 22
+	text("F1", 10, 50, y, "No. "+view.Number)
+	y -= 14
+	text("F1", 10, 50, y, "Issued "+view.IssuedAt)
+
+	y -= 36
+	text("F2", 11, 50, y, "Seller")
+	text("F2", 11, 300, y, "Buyer")
+	for i := 0; i < len(view.Seller) || i < len(view.Buyer); i++ {
+		y -= 14
+		if i < len(view.Seller) {
+			text("F1", 10, 50, y, view.Seller[i])
+		}
+		if i < len(view.Buyer) {
+			text("F1", 10, 300, y, view.Buyer[i])
+		}
+	}
+
+	y -= 20
+	for _, sections := range [][]invoiceLine{view.Item, view.Charges} {
+		y -= 10
+		for _, line := range sections {
+			y -= 18
+			text("F1", 10, 50, y, line.Label)
+			rightText("F1", 10, 545, y, line.Value)
+			rule(y - 6)
+		}
+	}
+	y -= 24
+	text("F2", 12, 50, y, "Total")
+	rightText("F2", 12, 545, y, view.Total)
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Contents 4 0 R " +
+			"/Resources << /Font << /F1 5 0 R /F2 6 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Title (%s) /CreationDate (D:%s) >>",
+			pdfText("Invoice "+invoice.Number), invoice.IssuedAt.UTC().Format("20060102150405")+"Z"),
+	}
+
+	var doc bytes.Buffer
+	doc.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = doc.Len()
+		fmt.Fprintf(&doc, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := doc.Len()
+	fmt.Fprintf(&doc, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&doc, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&doc, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(objects)+1, len(objects), xref)
+
+	_, err := w.Write(doc.Bytes())
+	return err
+}
@@ -0,0 +1,30 @@
+package services
+
+import (
+	"recyco/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// NotifyOrderParties stores the same notification about order for its buyer
+// and its seller. Legacy orders without a known buyer only notify the seller.
+func NotifyOrderParties(tx *gorm.DB, order models.Order, kind, title, message string) error {
+	for _, userID := range []uuid.UUID{order.BuyerID, order.SellerID} {
+		if userID == uuid.Nil {
+			continue
+		}
+
+		notification := models.Notification{
+			UserID:  userID,
+			Type:    kind,
+			Title:   title,
+			Message: message,
+			OrderID: &order.ID,
+		}
+		if err := tx.Create(&notification).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
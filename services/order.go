@@ -0,0 +1,63 @@
+package services
+
+import (
+	"recyco/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PlaceOrder creates an order of item for buyerID at price within tx. pickup
+// carries the buyer's handover details; its fees are priced here from the
+// tariff in effect, and it is saved before the order that references it.
+// DELIVERY orders pay the full tariff, self-pickup and drop-off only the
+// service fee. The declared weight and prices are kept on the order so they
+// can be compared with what is measured at handover. Only fixed-price items
+// can be ordered this way; auctions place the order of their winner when they
+// close.
+func PlaceOrder(tx *gorm.DB, item models.MarketItems, buyerID uuid.UUID, pickup *models.MarketItemPickupInformations, price models.Money, fulfillment string) (models.Order, models.MarketItemTransactionActivities, error) {
+	return placeOrder(tx, item, buyerID, pickup, price, fulfillment, models.ListingTypeFixed)
+}
+
+func placeOrder(tx *gorm.DB, item models.MarketItems, buyerID uuid.UUID, pickup *models.MarketItemPickupInformations, price models.Money, fulfillment, listingType string) (models.Order, models.MarketItemTransactionActivities, error) {
+	var order models.Order
+	var activity models.MarketItemTransactionActivities
+
+	var quote FeeQuote
+	var err error
+	if fulfillment == models.FulfillmentDelivery {
+		quote, err = QuoteFees(tx, item, pickup.PickupLat, pickup.PickupLon)
+	} else {
+		quote, err = QuoteSmallScaleFees(tx, item)
+	}
+	if err != nil {
+		return order, activity, err
+	}
+
+	pickup.ItemID = item.ID
+	pickup.DistanceKm = quote.DistanceKm
+	pickup.ServicePrice = quote.ServicePrice
+	pickup.DeliveryPrice = quote.DeliveryPrice
+	pickup.TariffID = quote.TariffID
+	if err := tx.Create(pickup).Error; err != nil {
+		return order, activity, err
+	}
+
+	order = models.Order{
+		ItemID:                item.ID,
+		BuyerID:               buyerID,
+		SellerID:              item.PostedBy,
+		PickupInformationID:   pickup.ID,
+		ItemPrice:             price,
+		PricedPerKg:           item.PricePerKg > 0,
+		DeclaredWeight:        item.Weight,
+		DeclaredItemPrice:     price,
+		DeclaredServicePrice:  quote.ServicePrice,
+		DeclaredDeliveryPrice: quote.DeliveryPrice,
+		Currency:              item.Currency,
+		Fulfillment:           fulfillment,
+	}
+
+	activity, err = openOrder(tx, &order, listingType)
+	return order, activity, err
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"recyco/models"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Webhook event types a PaymentProvider reports.
+const (
+	PaymentEventChargeSucceeded = "charge.succeeded"
+	PaymentEventChargeFailed    = "charge.failed"
+)
+
+var (
+	ErrPaymentsDisabled = errors.New("no payment provider is configured")
+	ErrWebhookSignature = errors.New("webhook signature does not match")
+	ErrUnknownCharge    = errors.New("unknown charge")
+	ErrRefundRejected   = errors.New("refund rejected by the payment provider")
+)
+
+// ChargeRequest asks a provider to collect Amount from the buyer. Reference
+// is our payment ID, which the provider echoes back and uses as the
+// idempotency key of the request.
+type ChargeRequest struct {
+	Reference   string
+	Amount      models.Money
+	Currency    string
+	Description string
+}
+
+// Charge is a provider's answer to a ChargeRequest. The buyer completes it at
+// CheckoutURL and the outcome arrives later as a webhook.
+type Charge struct {
+	ID          string
+	CheckoutURL string
+}
+
+// WebhookEvent is a verified notification from a provider about a charge.
+type WebhookEvent struct {
+	Type     string       `json:"type"`
+	ChargeID string       `json:"charge_id"`
+	Amount   models.Money `json:"amount"`
+}
+
+// Refund is a provider's confirmation of money sent back to the buyer.
+type Refund struct {
+	ID     string
+	Amount models.Money
+}
+
+// PaymentProvider is a payment gateway. Implementations must be safe for
+// concurrent use.
+type PaymentProvider interface {
+	// Name identifies the provider on stored payments and in webhook URLs.
+	Name() string
+	// CreateCharge returns the charge created first when it is asked again
+	// with the same Reference.
+	CreateCharge(request ChargeRequest) (Charge, error)
+	// VerifyWebhook authenticates a webhook body with the signature the
+	// provider sent along and decodes it.
+	VerifyWebhook(payload []byte, signature string) (WebhookEvent, error)
+	// Refund sends amount of chargeID back to the buyer. It returns the
+	// refund made first when it is asked again with the same idempotencyKey.
+	Refund(chargeID string, amount models.Money, idempotencyKey string) (Refund, error)
+}
+
+// FakePaymentProvider is a local PaymentProvider for development and tests.
+// It keeps its charges in memory, signs webhooks with HMAC-SHA256 like real
+// gateways do, and lets the caller decide each charge's outcome with
+// SimulateWebhook.
+type FakePaymentProvider struct {
+	secret []byte
+
+	mu         sync.Mutex
+	charges    map[string]models.Money
+	references map[string]Charge
+	refunds    map[string]Refund
+}
+
+func NewFakePaymentProvider(secret string) *FakePaymentProvider {
+	return &FakePaymentProvider{
+		secret:     []byte(secret),
+		charges:    map[string]models.Money{},
+		references: map[string]Charge{},
+		refunds:    map[string]Refund{},
+	}
+}
+
+func (provider *FakePaymentProvider) Name() string {
+	return "fake"
+}
+
+func (provider *FakePaymentProvider) CreateCharge(request ChargeRequest) (Charge, error) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	if charge, ok := provider.references[request.Reference]; ok {
+		return charge, nil
+	}
+	charge := Charge{ID: "fake_ch_" + uuid.NewString(), CheckoutURL: "/payments/" + request.Reference + "/simulate"}
+	provider.charges[charge.ID] = request.Amount
+	provider.references[request.Reference] = charge
+	return charge, nil
+}
+
+func (provider *FakePaymentProvider) sign(payload []byte) string {
+	mac := hmac.New(sha256.New, provider.secret)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (provider *FakePaymentProvider) VerifyWebhook(payload []byte, signature string) (WebhookEvent, error) {
+	var event WebhookEvent
+	if !hmac.Equal([]byte(provider.sign(payload)), []byte(signature)) {
+		return event, ErrWebhookSignature
+	}
+	err := json.Unmarshal(payload, &event)
+	return event, err
+}
+
+func (provider *FakePaymentProvider) Refund(chargeID string, amount models.Money, idempotencyKey string) (Refund, error) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	if refund, ok := provider.refunds[idempotencyKey]; ok {
+		return refund, nil
+	}
+	// Charges do not survive a restart, so unknown ones are refunded as
+	// long as the amount is sensible.
+	if charged, ok := provider.charges[chargeID]; (ok && amount > charged) || amount <= 0 {
+		return Refund{}, ErrRefundRejected
+	}
+	refund := Refund{ID: "fake_re_" + uuid.NewString(), Amount: amount}
+	provider.refunds[idempotencyKey] = refund
+	return refund, nil
+}
+
+// SimulateWebhook builds the signed webhook the fake gateway would send for
+// the outcome of chargeID.
+func (provider *FakePaymentProvider) SimulateWebhook(eventType, chargeID string, amount models.Money) ([]byte, string, error) {
+	payload, err := json.Marshal(WebhookEvent{Type: eventType, ChargeID: chargeID, Amount: amount})
+	if err != nil {
+		return nil, "", err
+	}
+	return payload, provider.sign(payload), nil
+}
+
+// Payments is the provider orders are charged through. It is set up from
+// the configuration at start and stays nil while payments are disabled.
+var Payments PaymentProvider
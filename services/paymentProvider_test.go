@@ -0,0 +1,67 @@
+package services
+
+import (
+	"errors"
+	"recyco/models"
+	"testing"
+)
+
+func TestFakeProviderVerifiesWebhooks(t *testing.T) {
+	provider := NewFakePaymentProvider("secret")
+	payload, signature, err := provider.SimulateWebhook(PaymentEventChargeSucceeded, "fake_ch_1", models.NewMoney(5000))
+	if err != nil {
+		t.Fatalf("simulate webhook: %v", err)
+	}
+
+	event, err := provider.VerifyWebhook(payload, signature)
+	if err != nil {
+		t.Fatalf("verify signed webhook: %v", err)
+	}
+	if event.Type != PaymentEventChargeSucceeded || event.ChargeID != "fake_ch_1" || event.Amount != models.NewMoney(5000) {
+		t.Fatalf("event = %+v, want the simulated one", event)
+	}
+
+	tampered := append([]byte{}, payload...)
+	tampered[len(tampered)-2]++
+	if _, err := provider.VerifyWebhook(tampered, signature); !errors.Is(err, ErrWebhookSignature) {
+		t.Fatalf("verify tampered webhook: %v, want %v", err, ErrWebhookSignature)
+	}
+	if _, err := NewFakePaymentProvider("other").VerifyWebhook(payload, signature); !errors.Is(err, ErrWebhookSignature) {
+		t.Fatalf("verify with another secret: %v, want %v", err, ErrWebhookSignature)
+	}
+}
+
+func TestFakeProviderIsIdempotent(t *testing.T) {
+	provider := NewFakePaymentProvider("secret")
+	request := ChargeRequest{Reference: "payment-1", Amount: models.NewMoney(5000), Currency: "IDR"}
+
+	first, err := provider.CreateCharge(request)
+	if err != nil {
+		t.Fatalf("create charge: %v", err)
+	}
+	again, err := provider.CreateCharge(request)
+	if err != nil || again != first {
+		t.Fatalf("charge again = %+v, %v, want %+v", again, err, first)
+	}
+
+	refund, err := provider.Refund(first.ID, models.NewMoney(2000), "refund-1")
+	if err != nil {
+		t.Fatalf("refund: %v", err)
+	}
+	if again, err := provider.Refund(first.ID, models.NewMoney(2000), "refund-1"); err != nil || again != refund {
+		t.Fatalf("refund again = %+v, %v, want %+v", again, err, refund)
+	}
+	if _, err := provider.Refund(first.ID, models.NewMoney(6000), "refund-2"); !errors.Is(err, ErrRefundRejected) {
+		t.Fatalf("refund more than charged: %v, want %v", err, ErrRefundRejected)
+	}
+}
+
+func TestPostJournalRejectsUnbalancedLines(t *testing.T) {
+	err := postJournal(nil, nil, nil, nil, "IDR", "Unbalanced", []LedgerLine{
+		{Account: models.LedgerAccountProviderCash, Debit: models.NewMoney(5000)},
+		{Account: models.LedgerAccountEscrow, Credit: models.NewMoney(4000)},
+	})
+	if !errors.Is(err, ErrUnbalancedJournal) {
+		t.Fatalf("post unbalanced journal: %v, want %v", err, ErrUnbalancedJournal)
+	}
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"math"
+	"recyco/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DefaultTariff prices orders when no tariff in the database matches. It is
+// the schedule the marketplace launched with: 1000 per started 50 kg block of
+// service fee and 1500 per kg of delivery fee.
+var DefaultTariff = models.Tariff{
+	Name:               "Default",
+	ServiceBlockWeight: 50,
+	ServiceFeePerBlock: models.NewMoney(1000),
+	DeliveryFeePerKg:   models.NewMoney(1500),
+}
+
+// FeeQuote is the result of pricing an order. TariffID is nil when the
+// built-in DefaultTariff was applied, and DistanceKm is nil when either end of
+// the trip has no coordinates.
+type FeeQuote struct {
+	TariffID      *uuid.UUID   `json:"tariff_id"`
+	TariffName    string       `json:"tariff_name"`
+	ServicePrice  models.Money `json:"service_price"`
+	DeliveryPrice models.Money `json:"delivery_price"`
+	DistanceKm    *float64     `json:"distance_km"`
+}
+
+// FindTariff returns the tariff in effect at for an item. The most specific
+// match wins (category before scale before catch-all) and among equally
+// specific tariffs the one that became effective last.
+func FindTariff(db *gorm.DB, item models.MarketItems, at time.Time) (models.Tariff, error) {
+	var tariffs []models.Tariff
+	err := db.
+		Where("effective_from <= ? AND (effective_until IS NULL OR effective_until > ?)", at, at).
+		Where("item_scale = ? OR item_scale = ''", item.ItemScale).
+		Where("category = ? OR category = ''", item.Category).
+		Where("min_weight <= ? AND (max_weight = 0 OR max_weight > ?)", item.Weight, item.Weight).
+		Order("category = '' asc, item_scale = '' asc, effective_from desc").
+		Limit(1).
+		Find(&tariffs).Error
+	if err != nil {
+		return models.Tariff{}, err
+	}
+	if len(tariffs) == 0 {
+		return DefaultTariff, nil
+	}
+	return tariffs[0], nil
+}
+
+// CalculateFees applies tariff to a weight in kg and an optional distance.
+// Weight and distance are taken to two decimals, and each fee is rounded half
+// away from zero to a whole currency unit after all components are summed.
+func CalculateFees(tariff models.Tariff, weight float64, distanceKm *float64) (servicePrice, deliveryPrice models.Money) {
+	blocks := int64(1)
+	if tariff.ServiceBlockWeight > 0 && weight > tariff.ServiceBlockWeight {
+		blocks = int64(math.Floor(weight/tariff.ServiceBlockWeight)) + 1
+	}
+	servicePrice = tariff.ServiceFeePerBlock.MulRatio(blocks, 1).RoundToUnit()
+
+	deliveryPrice = tariff.DeliveryFeePerKg.MulQuantity(weight)
+	if distanceKm != nil {
+		deliveryPrice += tariff.DeliveryFeePerKm.MulQuantity(*distanceKm)
+	}
+	deliveryPrice = deliveryPrice.RoundToUnit()
+	if deliveryPrice < tariff.MinimumFee {
+		deliveryPrice = tariff.MinimumFee
+	}
+	return servicePrice, deliveryPrice
+}
+
+// QuoteFees prices an order of item picked up at the given coordinates using
+// the tariff in effect now.
+func QuoteFees(db *gorm.DB, item models.MarketItems, pickupLat, pickupLon *float64) (FeeQuote, error) {
+	tariff, err := FindTariff(db, item, time.Now())
+	if err != nil {
+		return FeeQuote{}, err
+	}
+
+	quote := FeeQuote{TariffName: tariff.Name}
+	if tariff.ID != uuid.Nil {
+		id := tariff.ID
+		quote.TariffID = &id
+	}
+
+	if item.Lat != nil && item.Lon != nil && pickupLat != nil && pickupLon != nil {
+		distance := math.Round(DistanceKm(*item.Lat, *item.Lon, *pickupLat, *pickupLon)*100) / 100
+		quote.DistanceKm = &distance
+	}
+
+	quote.ServicePrice, quote.DeliveryPrice = CalculateFees(tariff, item.Weight, quote.DistanceKm)
+	return quote, nil
+}
+
+// QuoteSmallScaleFees prices a small-scale order. No courier is involved in
+// self-pickup or drop-off, so only the service fee of the applicable tariff is
+// charged.
+func QuoteSmallScaleFees(db *gorm.DB, item models.MarketItems) (FeeQuote, error) {
+	tariff, err := FindTariff(db, item, time.Now())
+	if err != nil {
+		return FeeQuote{}, err
+	}
+
+	quote := FeeQuote{TariffName: tariff.Name}
+	if tariff.ID != uuid.Nil {
+		id := tariff.ID
+		quote.TariffID = &id
+	}
+
+	quote.ServicePrice, _ = CalculateFees(tariff, item.Weight, nil)
+	return quote, nil
+}
+
+// DistanceKm is the great-circle distance between two coordinates.
+func DistanceKm(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadiusKm = 6371.0
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return earthRadiusKm * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"recyco/models"
+	"testing"
+)
+
+func TestCalculateFees(t *testing.T) {
+	tariff := models.Tariff{
+		ServiceBlockWeight: 50,
+		ServiceFeePerBlock: models.NewMoney(1000),
+		DeliveryFeePerKg:   models.NewMoney(1500),
+		DeliveryFeePerKm:   models.NewMoney(2000),
+		MinimumFee:         models.NewMoney(10000),
+	}
+	distance := func(km float64) *float64 { return &km }
+
+	tests := []struct {
+		name         string
+		tariff       models.Tariff
+		weight       float64
+		distanceKm   *float64
+		wantService  models.Money
+		wantDelivery models.Money
+	}{
+		{
+			name:         "first block",
+			tariff:       DefaultTariff,
+			weight:       10,
+			wantService:  models.NewMoney(1000),
+			wantDelivery: models.NewMoney(15000),
+		},
+		{
+			name:         "weight equal to the block stays in the first block",
+			tariff:       DefaultTariff,
+			weight:       50,
+			wantService:  models.NewMoney(1000),
+			wantDelivery: models.NewMoney(75000),
+		},
+		{
+			name:         "weight over the block starts a second block",
+			tariff:       DefaultTariff,
+			weight:       50.01,
+			wantService:  models.NewMoney(2000),
+			wantDelivery: models.NewMoney(75015),
+		},
+		{
+			name:         "whole multiples above the first block start the next block",
+			tariff:       DefaultTariff,
+			weight:       100,
+			wantService:  models.NewMoney(3000),
+			wantDelivery: models.NewMoney(150000),
+		},
+		{
+			name:         "zero block weight charges a single block",
+			tariff:       models.Tariff{ServiceFeePerBlock: models.NewMoney(2500)},
+			weight:       500,
+			wantService:  models.NewMoney(2500),
+			wantDelivery: 0,
+		},
+		{
+			name:         "minimum fee applies to light loads",
+			tariff:       tariff,
+			weight:       2,
+			wantService:  models.NewMoney(1000),
+			wantDelivery: models.NewMoney(10000),
+		},
+		{
+			name:         "distance is added to the weight component",
+			tariff:       tariff,
+			weight:       20,
+			distanceKm:   distance(3.5),
+			wantService:  models.NewMoney(1000),
+			wantDelivery: models.NewMoney(37000),
+		},
+		{
+			name:         "distance alone can lift the fee above the minimum",
+			tariff:       tariff,
+			weight:       1,
+			distanceKm:   distance(10),
+			wantService:  models.NewMoney(1000),
+			wantDelivery: models.NewMoney(21500),
+		},
+		{
+			name:         "delivery fee is rounded to a whole unit after summing",
+			tariff:       models.Tariff{ServiceBlockWeight: 50, ServiceFeePerBlock: models.NewMoney(1000), DeliveryFeePerKg: 25, DeliveryFeePerKm: 25},
+			weight:       1,
+			distanceKm:   distance(1),
+			wantService:  models.NewMoney(1000),
+			wantDelivery: models.NewMoney(1),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service, delivery := CalculateFees(test.tariff, test.weight, test.distanceKm)
+			if service != test.wantService || delivery != test.wantDelivery {
+				t.Errorf("CalculateFees() = %v, %v, want %v, %v", service, delivery, test.wantService, test.wantDelivery)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"math"
+	"recyco/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Reputation aggregates the reviews a user has received. Score is the mean
+// rating rounded to two decimals, or 0 when there are no reviews yet.
+// WeightDiscrepancies counts the finished sales whose measured weight was
+// flagged as too far from the weight the user declared. Disputes counts the
+// resolved disputes on the user's orders and DisputesLost those that were
+// resolved against them.
+type Reputation struct {
+	Score               float64 `json:"score"`
+	ReviewCount         int64   `json:"review_count"`
+	WeightDiscrepancies int64   `json:"weight_discrepancies"`
+	Disputes            int64   `json:"disputes"`
+	DisputesLost        int64   `json:"disputes_lost"`
+}
+
+// LoadReputations returns the reputation of each of userIDs with one grouped
+// query per figure. Users without reviews are present with a zero Reputation.
+func LoadReputations(db *gorm.DB, userIDs []uuid.UUID) (map[uuid.UUID]Reputation, error) {
+	reputations := make(map[uuid.UUID]Reputation, len(userIDs))
+	for _, id := range userIDs {
+		reputations[id] = Reputation{}
+	}
+	if len(userIDs) == 0 {
+		return reputations, nil
+	}
+
+	var rows []struct {
+		RevieweeID  uuid.UUID
+		RatingSum   int64
+		ReviewCount int64
+	}
+	if err := db.Model(&models.Review{}).
+		Select("reviewee_id, SUM(rating) AS rating_sum, COUNT(*) AS review_count").
+		Where("reviewee_id IN ?", userIDs).
+		Group("reviewee_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		reputations[row.RevieweeID] = Reputation{
+			Score:       math.Round(float64(row.RatingSum)/float64(row.ReviewCount)*100) / 100,
+			ReviewCount: row.ReviewCount,
+		}
+	}
+
+	var discrepancies []struct {
+		SellerID uuid.UUID
+		Count    int64
+	}
+	if err := db.Model(&models.Order{}).
+		Select("seller_id, COUNT(*) AS count").
+		Where("seller_id IN ? AND status = ? AND weight_discrepancy_flagged = ?", userIDs, TransactionStatusFinished, true).
+		Group("seller_id").
+		Scan(&discrepancies).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range discrepancies {
+		reputation := reputations[row.SellerID]
+		reputation.WeightDiscrepancies = row.Count
+		reputations[row.SellerID] = reputation
+	}
+
+	for _, column := range []string{"orders.buyer_id", "orders.seller_id", "disputes.at_fault_id"} {
+		var disputes []struct {
+			UserID uuid.UUID
+			Count  int64
+		}
+		if err := db.Model(&models.Dispute{}).
+			Joins("JOIN orders ON orders.id = disputes.order_id").
+			Select(column+" AS user_id, COUNT(*) AS count").
+			Where(column+" IN ? AND disputes.status = ?", userIDs, models.DisputeStatusResolved).
+			Group(column).
+			Scan(&disputes).Error; err != nil {
+			return nil, err
+		}
+
+		for _, row := range disputes {
+			reputation := reputations[row.UserID]
+			if column == "disputes.at_fault_id" {
+				reputation.DisputesLost += row.Count
+			} else {
+				reputation.Disputes += row.Count
+			}
+			reputations[row.UserID] = reputation
+		}
+	}
+	return reputations, nil
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"recyco/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ReservationWindow is how long a new order holds its item for the buyer
+// while the seller has not started the handover.
+const ReservationWindow = 24 * time.Hour
+
+// ReservationExpiredReason is recorded on the CANCELLED activity of orders
+// cancelled by the expiry job.
+const ReservationExpiredReason = "Reservation expired"
+
+// ExpireReservations cancels every order still ON_PROCESS past its
+// reservation deadline, which puts its item back on the market, and notifies
+// both parties. Each order is cancelled in its own transaction; an order that
+// moved on concurrently is skipped and one that fails is logged and left for
+// the next run. Refunds of paid orders are only recorded here and sent by
+// SendPendingRefunds. It returns how many orders were cancelled.
+func ExpireReservations(db *gorm.DB, now time.Time) (int, error) {
+	var orders []models.Order
+	if err := db.Where("status = ? AND reserved_until < ?", TransactionStatusOnProcess, now).
+		Find(&orders).Error; err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, order := range orders {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if _, err := CancelOrder(tx, &order, PartySystem, uuid.Nil, CancelReasonReservationExpired, ReservationExpiredReason); err != nil {
+				return err
+			}
+			return NotifyOrderParties(tx, order, models.NotificationReservationExpired,
+				"Order cancelled",
+				"The order was cancelled because the seller did not start the handover before "+
+					order.ReservedUntil.Format(time.RFC3339)+". The item is available again.")
+		})
+		if errors.Is(err, ErrTransactionConflict) {
+			continue
+		}
+		if err != nil {
+			log.Printf("reservation of order %s: %v", order.ID, err)
+			continue
+		}
+		expired++
+	}
+	return expired, nil
+}
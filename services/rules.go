@@ -0,0 +1,141 @@
+package services
+
+import (
+	"recyco/models"
+
+	"gorm.io/gorm"
+)
+
+// DefaultScaleRules and DefaultRoleRules describe the marketplace policy the
+// platform launched with. They seed the settings tables on first start and
+// are used whenever those tables are empty.
+var DefaultScaleRules = []models.MarketScaleRule{
+	{Category: "", SmallMaxWeight: 15},
+}
+
+var DefaultRoleRules = []models.MarketRoleRule{
+	{Role: "ADMIN", ItemScale: "SMALL", CanView: true},
+	{Role: "ADMIN", ItemScale: "LARGE", CanView: true},
+	{Role: "P_SMALL", ItemScale: "SMALL", CanPost: true, CanView: true},
+	{Role: "P_LARGE", ItemScale: "LARGE", CanPost: true, CanView: true},
+	{Role: "C_SMALL", ItemScale: "SMALL", CanView: true, CanBuy: true},
+	{Role: "C_LARGE", ItemScale: "LARGE", CanView: true, CanBuy: true},
+}
+
+// MarketRules is a snapshot of the admin-editable marketplace policy.
+type MarketRules struct {
+	ScaleRules []models.MarketScaleRule
+	RoleRules  []models.MarketRoleRule
+}
+
+func LoadMarketRules(db *gorm.DB) (MarketRules, error) {
+	var rules MarketRules
+	if err := db.Find(&rules.ScaleRules).Error; err != nil {
+		return rules, err
+	}
+	if err := db.Find(&rules.RoleRules).Error; err != nil {
+		return rules, err
+	}
+
+	if len(rules.ScaleRules) == 0 {
+		rules.ScaleRules = DefaultScaleRules
+	}
+	if len(rules.RoleRules) == 0 {
+		rules.RoleRules = DefaultRoleRules
+	}
+	return rules, nil
+}
+
+// SeedMarketRules stores the default rules when the settings tables are empty
+// so that admins start from the current policy.
+func SeedMarketRules(db *gorm.DB) error {
+	var count int64
+	if err := db.Model(&models.MarketScaleRule{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		for _, rule := range DefaultScaleRules {
+			if err := db.Create(&rule).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := db.Model(&models.MarketRoleRule{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		for _, rule := range DefaultRoleRules {
+			if err := db.Create(&rule).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SmallMaxWeight returns the largest weight in kg that is still SMALL for an
+// item of category.
+func (rules MarketRules) SmallMaxWeight(category string) float64 {
+	threshold := 0.0
+	for _, rule := range rules.ScaleRules {
+		if rule.Category == category {
+			return rule.SmallMaxWeight
+		}
+		if rule.Category == "" {
+			threshold = rule.SmallMaxWeight
+		}
+	}
+	return threshold
+}
+
+// ScaleForWeight classifies an item of category weighing weight kg.
+func (rules MarketRules) ScaleForWeight(category string, weight float64) string {
+	if weight <= rules.SmallMaxWeight(category) {
+		return "SMALL"
+	}
+	return "LARGE"
+}
+
+func (rules MarketRules) scales(role string, allowed func(models.MarketRoleRule) bool) []string {
+	scales := []string{}
+	for _, rule := range rules.RoleRules {
+		if rule.Role == role && allowed(rule) {
+			scales = append(scales, rule.ItemScale)
+		}
+	}
+	return scales
+}
+
+func (rules MarketRules) PostableScales(role string) []string {
+	return rules.scales(role, func(rule models.MarketRoleRule) bool { return rule.CanPost })
+}
+
+func (rules MarketRules) ViewableScales(role string) []string {
+	return rules.scales(role, func(rule models.MarketRoleRule) bool { return rule.CanView })
+}
+
+func (rules MarketRules) BuyableScales(role string) []string {
+	return rules.scales(role, func(rule models.MarketRoleRule) bool { return rule.CanBuy })
+}
+
+func (rules MarketRules) CanPost(role, scale string) bool {
+	return containsString(rules.PostableScales(role), scale)
+}
+
+func (rules MarketRules) CanView(role, scale string) bool {
+	return containsString(rules.ViewableScales(role), scale)
+}
+
+func (rules MarketRules) CanBuy(role, scale string) bool {
+	return containsString(rules.BuyableScales(role), scale)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"errors"
+	"recyco/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrSlotUnavailable = errors.New("pickup slot is full, past or not offered by the seller")
+
+// BookOrderSlot books one place in slotID for order within tx and links the
+// order to it. The slot must belong to the order's seller, start in the
+// future and have room left; the capacity check and the increment are one
+// conditional update, so concurrent bookings cannot overbook it.
+func BookOrderSlot(tx *gorm.DB, order *models.Order, slotID uuid.UUID) error {
+	if err := reserveSlot(tx, slotID, order.SellerID); err != nil {
+		return err
+	}
+
+	if err := tx.Model(&models.Order{}).Where("id = ?", order.ID).Update("slot_id", slotID).Error; err != nil {
+		return err
+	}
+	order.SlotID = &slotID
+	return nil
+}
+
+// MoveOrderSlot moves order to slotID, freeing its previous slot. The order
+// is moved conditionally on the slot it was read with, so of two concurrent
+// moves only one frees that slot; the other gets ErrTransactionConflict.
+func MoveOrderSlot(tx *gorm.DB, order *models.Order, slotID uuid.UUID) error {
+	previous := order.SlotID
+	if err := reserveSlot(tx, slotID, order.SellerID); err != nil {
+		return err
+	}
+
+	query := tx.Model(&models.Order{}).Where("id = ?", order.ID)
+	if previous != nil {
+		query = query.Where("slot_id = ?", *previous)
+	} else {
+		query = query.Where("slot_id IS NULL")
+	}
+	result := query.Update("slot_id", slotID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTransactionConflict
+	}
+	order.SlotID = &slotID
+
+	if previous != nil {
+		return releaseSlot(tx, *previous)
+	}
+	return nil
+}
+
+func reserveSlot(tx *gorm.DB, slotID uuid.UUID, sellerID uuid.UUID) error {
+	result := tx.Model(&models.PickupSlot{}).
+		Where("id = ? AND seller_id = ? AND starts_at > ? AND booked < capacity", slotID, sellerID, time.Now()).
+		Updates(map[string]interface{}{"Booked": gorm.Expr("booked + 1"), "UpdatedAt": time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSlotUnavailable
+	}
+	return nil
+}
+
+func releaseSlot(tx *gorm.DB, slotID uuid.UUID) error {
+	return tx.Model(&models.PickupSlot{}).
+		Where("id = ? AND booked > 0", slotID).
+		Updates(map[string]interface{}{"Booked": gorm.Expr("booked - 1"), "UpdatedAt": time.Now()}).Error
+}
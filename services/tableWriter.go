@@ -0,0 +1,213 @@
+package services
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// TableColumn is a column of an exported table. Numeric columns are written
+// as numbers where the format has them, so spreadsheets can sum them.
+type TableColumn struct {
+	Header  string
+	Numeric bool
+}
+
+// TableWriter writes a table row by row without holding it in memory. Flush
+// pushes the rows written so far to the underlying writer; Close finishes the
+// document and must be called once all rows are written.
+type TableWriter interface {
+	WriteRow(values []string) error
+	Flush() error
+	Close() error
+}
+
+type csvTableWriter struct {
+	writer  *csv.Writer
+	columns []TableColumn
+}
+
+// NewCSVTableWriter writes columns as the header line of a CSV document on w.
+func NewCSVTableWriter(w io.Writer, columns []TableColumn) (TableWriter, error) {
+	table := &csvTableWriter{writer: csv.NewWriter(w)}
+
+	headers := make([]string, len(columns))
+	for i, column := range columns {
+		headers[i] = column.Header
+	}
+	if err := table.WriteRow(headers); err != nil {
+		return table, err
+	}
+	table.columns = columns
+	return table, nil
+}
+
+// csvFormulaPrefixes are the characters spreadsheets start a formula with
+// when they open a CSV file.
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// WriteRow quotes cells that a spreadsheet would run as a formula with a
+// leading ', so user text such as an item name cannot inject one. Numbers in
+// numeric columns are left as they are, negative ones included.
+func (table *csvTableWriter) WriteRow(values []string) error {
+	cells := make([]string, len(values))
+	for i, value := range values {
+		cells[i] = value
+		if value == "" || !strings.ContainsRune(csvFormulaPrefixes, rune(value[0])) {
+			continue
+		}
+		if i < len(table.columns) && table.columns[i].Numeric {
+			if _, err := strconv.ParseFloat(value, 64); err == nil {
+				continue
+			}
+		}
+		cells[i] = "'" + value
+	}
+	return table.writer.Write(cells)
+}
+
+func (table *csvTableWriter) Flush() error {
+	table.writer.Flush()
+	return table.writer.Error()
+}
+
+func (table *csvTableWriter) Close() error {
+	return table.Flush()
+}
+
+// xlsxParts are the parts of a single-sheet workbook besides the sheet itself.
+var xlsxParts = []struct {
+	name    string
+	content string
+}{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+</Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`},
+}
+
+type xlsxTableWriter struct {
+	archive *zip.Writer
+	sheet   *bufio.Writer
+	columns []TableColumn
+	row     int
+}
+
+// NewXLSXTableWriter writes an Excel workbook with a single sheet named
+// sheetName on w, with columns as its first row. The archive is streamed as
+// rows are written; strings are stored inline so no shared string table has
+// to be built up front.
+func NewXLSXTableWriter(w io.Writer, sheetName string, columns []TableColumn) (TableWriter, error) {
+	archive := zip.NewWriter(w)
+
+	workbook := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="` + xmlText(sheetName) + `" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`
+	for _, part := range xlsxParts {
+		if err := writeZipPart(archive, part.name, part.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := writeZipPart(archive, "xl/workbook.xml", workbook); err != nil {
+		return nil, err
+	}
+
+	file, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	table := &xlsxTableWriter{archive: archive, sheet: bufio.NewWriter(file), columns: columns}
+	table.sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	// The header row is text even above numeric columns.
+	headers := make([]string, len(columns))
+	for i, column := range columns {
+		headers[i] = column.Header
+	}
+	return table, table.writeRow(headers, false)
+}
+
+func writeZipPart(archive *zip.Writer, name, content string) error {
+	file, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(file, content)
+	return err
+}
+
+func xmlText(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// xlsxColumnName turns a zero-based column index into its letters: A, B, ...
+// Z, AA, AB and so on.
+func xlsxColumnName(index int) string {
+	name := ""
+	for index++; index > 0; index = (index - 1) / 26 {
+		name = string(rune('A'+(index-1)%26)) + name
+	}
+	return name
+}
+
+func (table *xlsxTableWriter) writeRow(values []string, typed bool) error {
+	table.row++
+	row := strconv.Itoa(table.row)
+
+	table.sheet.WriteString(`<row r="` + row + `">`)
+	for i, value := range values {
+		if value == "" {
+			continue
+		}
+		ref := xlsxColumnName(i) + row
+		if typed && i < len(table.columns) && table.columns[i].Numeric {
+			if _, err := strconv.ParseFloat(value, 64); err == nil {
+				table.sheet.WriteString(`<c r="` + ref + `"><v>` + value + `</v></c>`)
+				continue
+			}
+		}
+		table.sheet.WriteString(`<c r="` + ref + `" t="inlineStr"><is><t xml:space="preserve">` + xmlText(value) + `</t></is></c>`)
+	}
+	_, err := table.sheet.WriteString(`</row>`)
+	return err
+}
+
+func (table *xlsxTableWriter) WriteRow(values []string) error {
+	return table.writeRow(values, true)
+}
+
+func (table *xlsxTableWriter) Flush() error {
+	if err := table.sheet.Flush(); err != nil {
+		return err
+	}
+	return table.archive.Flush()
+}
+
+func (table *xlsxTableWriter) Close() error {
+	if _, err := table.sheet.WriteString(`</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	if err := table.sheet.Flush(); err != nil {
+		return err
+	}
+	return table.archive.Close()
+}
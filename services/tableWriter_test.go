@@ -0,0 +1,44 @@
+package services
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCSVTableWriterEscapesFormulas(t *testing.T) {
+	var out bytes.Buffer
+	table, err := NewCSVTableWriter(&out, []TableColumn{{Header: "Item"}, {Header: "Amount", Numeric: true}})
+	if err != nil {
+		t.Fatalf("new writer: %v", err)
+	}
+
+	rows := [][]string{
+		{"=HYPERLINK(\"http://evil\")", "-5000"},
+		{"+1", "=1+1"},
+		{"-2", "12.5"},
+		{"@SUM(A1)", ""},
+		{"\tTab", "1"},
+		{"\rReturn", "2"},
+		{"Cardboard", "3"},
+	}
+	for _, row := range rows {
+		if err := table.WriteRow(row); err != nil {
+			t.Fatalf("write row: %v", err)
+		}
+	}
+	if err := table.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	want := "Item,Amount\n" +
+		"\"'=HYPERLINK(\"\"http://evil\"\")\",-5000\n" +
+		"'+1,'=1+1\n" +
+		"'-2,12.5\n" +
+		"'@SUM(A1),\n" +
+		"'\tTab,1\n" +
+		"\"'\rReturn\",2\n" +
+		"Cardboard,3\n"
+	if out.String() != want {
+		t.Fatalf("csv =\n%q\nwant\n%q", out.String(), want)
+	}
+}
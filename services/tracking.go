@@ -0,0 +1,86 @@
+package services
+
+import (
+	"recyco/models"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// LocationPingRetention is how long GPS pings are kept before they are
+	// purged.
+	LocationPingRetention = 7 * 24 * time.Hour
+	// LocationPingMinInterval is the shortest accepted gap between two pings
+	// of the same order.
+	LocationPingMinInterval = 5 * time.Second
+)
+
+// TrackingEvent is one live update of an order sent to tracking streams.
+// Type is "location" or "status".
+type TrackingEvent struct {
+	Type     string               `json:"type"`
+	OrderID  uuid.UUID            `json:"order_id"`
+	Status   string               `json:"status,omitempty"`
+	Location *models.LocationPing `json:"location,omitempty"`
+	At       time.Time            `json:"at"`
+}
+
+// TrackingHub fans out tracking events of an order to the streams watching
+// it within this process. Slow subscribers miss events instead of blocking
+// the publisher; every event carries the full current state, so a missed one
+// is superseded by the next.
+type TrackingHub struct {
+	mu          sync.Mutex
+	subscribers map[uuid.UUID]map[chan TrackingEvent]struct{}
+}
+
+func NewTrackingHub() *TrackingHub {
+	return &TrackingHub{subscribers: map[uuid.UUID]map[chan TrackingEvent]struct{}{}}
+}
+
+// Tracking is the hub shared by the ping and stream endpoints.
+var Tracking = NewTrackingHub()
+
+// Subscribe returns a channel receiving the events of orderID and a function
+// that must be called to stop receiving them.
+func (hub *TrackingHub) Subscribe(orderID uuid.UUID) (<-chan TrackingEvent, func()) {
+	events := make(chan TrackingEvent, 16)
+
+	hub.mu.Lock()
+	if hub.subscribers[orderID] == nil {
+		hub.subscribers[orderID] = map[chan TrackingEvent]struct{}{}
+	}
+	hub.subscribers[orderID][events] = struct{}{}
+	hub.mu.Unlock()
+
+	return events, func() {
+		hub.mu.Lock()
+		delete(hub.subscribers[orderID], events)
+		if len(hub.subscribers[orderID]) == 0 {
+			delete(hub.subscribers, orderID)
+		}
+		hub.mu.Unlock()
+	}
+}
+
+func (hub *TrackingHub) Publish(event TrackingEvent) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	for events := range hub.subscribers[event.OrderID] {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
+
+// PurgeLocationPings permanently deletes pings recorded before the retention
+// window and returns how many were removed.
+func PurgeLocationPings(db *gorm.DB, now time.Time) (int, error) {
+	result := db.Unscoped().Where("created_at < ?", now.Add(-LocationPingRetention)).Delete(&models.LocationPing{})
+	return int(result.RowsAffected), result.Error
+}
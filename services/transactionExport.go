@@ -0,0 +1,158 @@
+package services
+
+import (
+	"recyco/models"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// transactionExportBatch is how many orders are loaded at a time while
+// exporting, which bounds the memory an export needs however long the
+// history is.
+const transactionExportBatch = 200
+
+const exportTimeLayout = "2006-01-02 15:04:05"
+
+// TransactionExportColumns are the columns of a transaction export, one row
+// per order. Weights are in kilograms.
+var TransactionExportColumns = []TableColumn{
+	{Header: "Order ID"},
+	{Header: "Ordered At"},
+	{Header: "Role"},
+	{Header: "Item ID"},
+	{Header: "Item"},
+	{Header: "Category"},
+	{Header: "Seller ID"},
+	{Header: "Seller"},
+	{Header: "Buyer ID"},
+	{Header: "Buyer"},
+	{Header: "Fulfillment"},
+	{Header: "Status"},
+	{Header: "Declared Weight (kg)", Numeric: true},
+	{Header: "Measured Weight (kg)", Numeric: true},
+	{Header: "Item Price", Numeric: true},
+	{Header: "Service Fee", Numeric: true},
+	{Header: "Delivery Fee", Numeric: true},
+	{Header: "Refunded", Numeric: true},
+	{Header: "Total", Numeric: true},
+	{Header: "Currency"},
+	{Header: "On Deliver At"},
+	{Header: "Finished At"},
+	{Header: "Cancelled At"},
+	{Header: "Cancellation Reason"},
+	{Header: "Invoice Number"},
+}
+
+func formatExportTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(exportTimeLayout)
+}
+
+func formatExportWeight(kilograms float64) string {
+	return strconv.FormatFloat(kilograms, 'f', -1, 64)
+}
+
+// transactionExportRow lays order out in the order of
+// TransactionExportColumns, as seen by userID.
+func transactionExportRow(order models.Order, userID uuid.UUID) []string {
+	role := PartyBuyer
+	if order.SellerID == userID {
+		role = PartySeller
+	}
+
+	// An order passes through each status once, so the activity of a status
+	// is its timestamp.
+	reached := map[string]*time.Time{}
+	reasonCode := ""
+	for i, activity := range order.Activities {
+		reached[activity.Status] = &order.Activities[i].CreatedAt
+		if activity.Status == TransactionStatusCancelled {
+			reasonCode = activity.ReasonCode
+		}
+	}
+
+	measured := ""
+	if order.MeasuredWeight != nil {
+		measured = formatExportWeight(*order.MeasuredWeight)
+	}
+	invoiceNumber := ""
+	if order.Invoice != nil {
+		invoiceNumber = order.Invoice.Number
+	}
+	pickup := order.PickupInformation
+
+	return []string{
+		order.ID.String(),
+		order.CreatedAt.Format(exportTimeLayout),
+		role,
+		order.ItemID.String(),
+		order.MarketItem.Name,
+		order.MarketItem.Category,
+		order.SellerID.String(),
+		order.Seller.Name,
+		order.BuyerID.String(),
+		order.Buyer.Name,
+		order.Fulfillment,
+		order.Status,
+		formatExportWeight(order.DeclaredWeight),
+		measured,
+		order.ItemPrice.String(),
+		pickup.ServicePrice.String(),
+		pickup.DeliveryPrice.String(),
+		order.RefundedAmount.String(),
+		(OrderTotal(order, pickup) - order.RefundedAmount).String(),
+		order.Currency,
+		formatExportTime(reached[TransactionStatusOnDeliver]),
+		formatExportTime(reached[TransactionStatusFinished]),
+		formatExportTime(reached[TransactionStatusCancelled]),
+		reasonCode,
+		invoiceNumber,
+	}
+}
+
+// ExportTransactions writes every order userID bought or sold that was placed
+// in [from, to) to table, oldest first. Orders are read in batches and table
+// is flushed after each one, so rows reach the client while later ones are
+// still being loaded.
+func ExportTransactions(db *gorm.DB, userID uuid.UUID, from, to time.Time, table TableWriter) error {
+	var last *models.Order
+	for {
+		query := db.Preload("MarketItem", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+			Preload("Buyer", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+			Preload("Seller", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+			Preload("PickupInformation", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+			Preload("Activities", func(db *gorm.DB) *gorm.DB { return db.Order("created_at asc") }).
+			Preload("Invoice").
+			Where("buyer_id = ? OR seller_id = ?", userID, userID).
+			Where("created_at >= ? AND created_at < ?", from, to)
+		if last != nil {
+			// Keyset pagination stays fast deep into a long history, unlike
+			// an offset.
+			query = query.Where("(created_at > ? OR (created_at = ? AND id > ?))", last.CreatedAt, last.CreatedAt, last.ID)
+		}
+
+		var orders []models.Order
+		if err := query.Order("created_at asc, id asc").Limit(transactionExportBatch).Find(&orders).Error; err != nil {
+			return err
+		}
+
+		for _, order := range orders {
+			if err := table.WriteRow(transactionExportRow(order, userID)); err != nil {
+				return err
+			}
+		}
+		if err := table.Flush(); err != nil {
+			return err
+		}
+
+		if len(orders) < transactionExportBatch {
+			return nil
+		}
+		last = &orders[len(orders)-1]
+	}
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"errors"
+	"recyco/models"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	TransactionStatusOnProcess = "ON_PROCESS"
+	TransactionStatusOnDeliver = "ON_DELIVER"
+	TransactionStatusFinished  = "FINISHED"
+	TransactionStatusCancelled = "CANCELLED"
+)
+
+// Parties that may trigger a transaction transition.
+const (
+	PartyBuyer  = "BUYER"
+	PartySeller = "SELLER"
+	PartySystem = "SYSTEM"
+	PartyDriver = "DRIVER"
+	PartyAdmin  = "ADMIN"
+)
+
+var (
+	ErrIllegalTransition        = errors.New("illegal transaction status transition")
+	ErrTransitionForbidden      = errors.New("party is not allowed to trigger this transition")
+	ErrTransitionReason         = errors.New("a reason is required for this transition")
+	ErrTransactionConflict      = errors.New("market item was changed by another request")
+	ErrInvalidTransactionStatus = errors.New("invalid transaction status")
+)
+
+// mysqlDuplicateEntry is the MySQL error number of a unique index violation.
+const mysqlDuplicateEntry = 1062
+
+// IsDuplicateKey reports whether err is MySQL rejecting a row that would
+// violate a unique index, which is how a concurrent duplicate shows up.
+func IsDuplicateKey(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry
+}
+
+// TransactionTransition declares one allowed edge of the transaction
+// lifecycle. From is empty for the transition that opens a transaction.
+type TransactionTransition struct {
+	From           string
+	To             string
+	Parties        []string
+	RequiresReason bool
+}
+
+var TransactionTransitions = []TransactionTransition{
+	{From: "", To: TransactionStatusOnProcess, Parties: []string{PartyBuyer}},
+	{From: TransactionStatusOnProcess, To: TransactionStatusOnDeliver, Parties: []string{PartySeller, PartyDriver}},
+	{From: TransactionStatusOnDeliver, To: TransactionStatusFinished, Parties: []string{PartySeller, PartyDriver}},
+	{From: TransactionStatusOnProcess, To: TransactionStatusCancelled, Parties: []string{PartyBuyer, PartySeller, PartySystem, PartyAdmin}, RequiresReason: true},
+	{From: TransactionStatusOnDeliver, To: TransactionStatusCancelled, Parties: []string{PartySeller, PartySystem, PartyAdmin}, RequiresReason: true},
+	{From: TransactionStatusFinished, To: TransactionStatusCancelled, Parties: []string{PartyAdmin}, RequiresReason: true},
+}
+
+func IsValidTransactionStatus(status string) bool {
+	switch status {
+	case TransactionStatusOnProcess, TransactionStatusOnDeliver, TransactionStatusFinished, TransactionStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// ValidateTransactionTransition checks a requested transition against the
+// declared lifecycle without touching the database.
+func ValidateTransactionTransition(from, to, party, reason string) error {
+	if !IsValidTransactionStatus(to) {
+		return ErrInvalidTransactionStatus
+	}
+
+	for _, transition := range TransactionTransitions {
+		if transition.From != from || transition.To != to {
+			continue
+		}
+
+		allowed := false
+		for _, p := range transition.Parties {
+			if p == party {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return ErrTransitionForbidden
+		}
+
+		if transition.RequiresReason && reason == "" {
+			return ErrTransitionReason
+		}
+		return nil
+	}
+
+	return ErrIllegalTransition
+}
+
+// OpenOrder places order for its market item: the item is reserved for the
+// buyer until the order's reservation deadline, the order is stored as
+// ON_PROCESS with a fresh handover PIN and the first activity of its timeline
+// is recorded. The reservation is a conditional update on the item still
+// being a READY fixed-price listing, so of several concurrent orders only one
+// succeeds and the others, like an order racing the item being put up for
+// auction, get ErrTransactionConflict.
+func OpenOrder(tx *gorm.DB, order *models.Order) (models.MarketItemTransactionActivities, error) {
+	return openOrder(tx, order, models.ListingTypeFixed)
+}
+
+// openOrder is OpenOrder for an item listed as listingType.
+func openOrder(tx *gorm.DB, order *models.Order, listingType string) (models.MarketItemTransactionActivities, error) {
+	var activity models.MarketItemTransactionActivities
+
+	if err := ValidateTransactionTransition("", TransactionStatusOnProcess, PartyBuyer, ""); err != nil {
+		return activity, err
+	}
+
+	result := tx.Model(&models.MarketItems{}).
+		Where("id = ? AND status = ? AND listing_type = ?", order.ItemID, models.MarketItemStatusReady, listingType).
+		Updates(map[string]interface{}{
+			"Status":    models.MarketItemStatusReserved,
+			"OrderedBy": order.BuyerID,
+			"UpdatedAt": time.Now(),
+		})
+	if result.Error != nil {
+		return activity, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return activity, ErrTransactionConflict
+	}
+
+	order.Status = TransactionStatusOnProcess
+	if order.HandoverPIN == "" {
+		pin, err := GenerateHandoverPIN()
+		if err != nil {
+			return activity, err
+		}
+		order.HandoverPIN = pin
+	}
+	if order.ReservedUntil == nil {
+		reservedUntil := time.Now().Add(ReservationWindow)
+		order.ReservedUntil = &reservedUntil
+	}
+	if err := tx.Create(order).Error; err != nil {
+		return activity, err
+	}
+
+	return appendActivity(tx, *order, PartyBuyer, order.BuyerID, "")
+}
+
+// RecordTransactionStatus is the write path for every later step of an
+// order. It validates the transition against the declared lifecycle, moves
+// the order and the listing status of its item, and appends the activity,
+// all within tx. A cancelled order gives its pickup slot back, and the item
+// of a finished order that is cancelled goes back to its seller as a DRAFT
+// rather than straight back on the market. Both rows are updated
+// conditionally on the status they had before, so a concurrent writer makes
+// this call fail with ErrTransactionConflict. Completing an order needs the
+// handover evidence and goes through CompleteOrder instead, cancelling one
+// needs a reason code and goes through CancelOrder.
+func RecordTransactionStatus(tx *gorm.DB, order *models.Order, to, party string, actorID uuid.UUID, reason string) (models.MarketItemTransactionActivities, error) {
+	switch to {
+	case TransactionStatusFinished:
+		return models.MarketItemTransactionActivities{}, ErrHandoverRequired
+	case TransactionStatusCancelled:
+		return models.MarketItemTransactionActivities{}, ErrCancellationReason
+	}
+	return recordTransactionStatus(tx, order, to, party, actorID, reason)
+}
+
+func recordTransactionStatus(tx *gorm.DB, order *models.Order, to, party string, actorID uuid.UUID, reason string) (models.MarketItemTransactionActivities, error) {
+	var activity models.MarketItemTransactionActivities
+
+	from := order.Status
+	if err := ValidateTransactionTransition(from, to, party, reason); err != nil {
+		return activity, err
+	}
+
+	result := tx.Model(&models.Order{}).
+		Where("id = ? AND status = ?", order.ID, from).
+		Updates(map[string]interface{}{"Status": to, "UpdatedAt": time.Now()})
+	if result.Error != nil {
+		return activity, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return activity, ErrTransactionConflict
+	}
+
+	itemUpdates := map[string]interface{}{
+		"Status":    models.MarketItemStatusForTransaction(to),
+		"UpdatedAt": time.Now(),
+	}
+	if to == TransactionStatusCancelled {
+		itemUpdates["OrderedBy"] = nil
+		if from == TransactionStatusFinished {
+			itemUpdates["Status"] = models.MarketItemStatusDraft
+		}
+	}
+
+	result = tx.Model(&models.MarketItems{}).
+		Where("id = ? AND status = ?", order.ItemID, models.MarketItemStatusForTransaction(from)).
+		Updates(itemUpdates)
+	if result.Error != nil {
+		return activity, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return activity, ErrTransactionConflict
+	}
+
+	if to == TransactionStatusCancelled && order.SlotID != nil {
+		if err := releaseSlot(tx, *order.SlotID); err != nil {
+			return activity, err
+		}
+	}
+
+	order.Status = to
+	return appendActivity(tx, *order, party, actorID, reason)
+}
+
+func appendActivity(tx *gorm.DB, order models.Order, party string, actorID uuid.UUID, reason string) (models.MarketItemTransactionActivities, error) {
+	activity := models.MarketItemTransactionActivities{
+		ID:              uuid.New(),
+		ItemID:          order.ItemID,
+		OrderID:         &order.ID,
+		Status:          order.Status,
+		TransactionByID: order.PickupInformationID,
+		ActorParty:      party,
+		Reason:          reason,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}
+	if actorID != uuid.Nil {
+		activity.ActorID = &actorID
+	}
+
+	if err := tx.Create(&activity).Error; err != nil {
+		return activity, err
+	}
+	return activity, nil
+}
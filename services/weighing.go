@@ -0,0 +1,81 @@
+package services
+
+import (
+	"math"
+	"recyco/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WeightDiscrepancyThreshold is the relative difference between declared and
+// measured weight above which an order is flagged against the seller.
+const WeightDiscrepancyThreshold = 0.10
+
+// SettleMeasuredWeight reprices order within tx on the weight in kg measured
+// at handover. Orders priced per kg scale their item price with the weight,
+// lot-priced orders keep the agreed price. Fees are recalculated with the
+// tariff the order was placed under, so a tariff change in the meantime does
+// not reprice it, and the weight discrepancy is recorded and flagged when it
+// exceeds WeightDiscrepancyThreshold.
+func SettleMeasuredWeight(tx *gorm.DB, order *models.Order, measured float64) error {
+	var pickup models.MarketItemPickupInformations
+	if err := tx.Where("id = ?", order.PickupInformationID).First(&pickup).Error; err != nil {
+		return err
+	}
+
+	tariff := DefaultTariff
+	if pickup.TariffID != nil {
+		if err := tx.Unscoped().Where("id = ?", *pickup.TariffID).First(&tariff).Error; err != nil {
+			return err
+		}
+	}
+
+	servicePrice, deliveryPrice := CalculateFees(tariff, measured, pickup.DistanceKm)
+	if order.Fulfillment != models.FulfillmentDelivery {
+		deliveryPrice = 0
+	}
+
+	itemPrice := order.ItemPrice
+	var discrepancy *float64
+	flagged := false
+	declaredGrams := int64(math.Round(order.DeclaredWeight * 1000))
+	if declaredGrams > 0 {
+		measuredGrams := int64(math.Round(measured * 1000))
+		if order.PricedPerKg {
+			itemPrice = order.DeclaredItemPrice.MulRatio(measuredGrams, declaredGrams)
+		}
+
+		ratio := math.Round(float64(measuredGrams-declaredGrams)/float64(declaredGrams)*10000) / 10000
+		discrepancy = &ratio
+		flagged = math.Abs(ratio) > WeightDiscrepancyThreshold
+	}
+
+	now := time.Now()
+	if err := tx.Model(&models.MarketItemPickupInformations{}).Where("id = ?", pickup.ID).
+		Updates(map[string]interface{}{"ServicePrice": servicePrice, "DeliveryPrice": deliveryPrice, "UpdatedAt": now}).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Model(&models.Order{}).Where("id = ?", order.ID).
+		Updates(map[string]interface{}{
+			"ItemPrice":                itemPrice,
+			"MeasuredWeight":           measured,
+			"WeightDiscrepancy":        discrepancy,
+			"WeightDiscrepancyFlagged": flagged,
+			"UpdatedAt":                now,
+		}).Error; err != nil {
+		return err
+	}
+
+	order.ItemPrice = itemPrice
+	order.MeasuredWeight = &measured
+	order.WeightDiscrepancy = discrepancy
+	order.WeightDiscrepancyFlagged = flagged
+	order.UpdatedAt = now
+	if order.PickupInformation.ID == pickup.ID {
+		order.PickupInformation.ServicePrice = servicePrice
+		order.PickupInformation.DeliveryPrice = deliveryPrice
+	}
+	return nil
+}